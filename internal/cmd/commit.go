@@ -1,18 +1,70 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
 	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/runtime"
+	"github.com/steveyegge/gastown/internal/style"
 	"github.com/steveyegge/gastown/internal/workspace"
+	"golang.org/x/term"
 )
 
 // DefaultAgentEmailDomain is the default domain for agent git emails.
 const DefaultAgentEmailDomain = "gastown.local"
 
+// Default formats for --author-from-identity. Placeholders: {identity}
+// (the agent address with "/" replaced by "-"), {rig} ("town" for town-level
+// agents), {role}, and {name} (the polecat or crew name, else the role).
+const (
+	DefaultAuthorNameFormat  = "{identity}"
+	DefaultAuthorEmailFormat = "{name}@{rig}.agents"
+)
+
+// DefaultMaxMessageBytes is the default --max-message-bytes limit.
+const DefaultMaxMessageBytes = 64 * 1024
+
+// DefaultCommitSubjectFormat is the default subject seeded by --seed-from-molecule.
+const DefaultCommitSubjectFormat = "{id}: {title}"
+
+// Defaults for --ticket-from-branch: a JIRA-style key such as ABC-123,
+// recorded as a Refs trailer.
+const (
+	DefaultTicketPattern = `[A-Z][A-Z0-9]+-[0-9]+`
+	DefaultTicketTrailer = "Refs"
+)
+
+// Trailer keys written by gt commit for agent attribution.
+const (
+	TrailerExecutedBy     = git.TrailerExecutedBy
+	TrailerRig            = git.TrailerRig
+	TrailerRole           = git.TrailerRole
+	TrailerMolecule       = git.TrailerMolecule
+	TrailerMoleculeStatus = git.TrailerMoleculeStatus
+	TrailerBranch         = git.TrailerBranch
+	TrailerGeneratedBy    = git.TrailerGeneratedBy
+	TrailerHost           = git.TrailerHost
+	TrailerPID            = git.TrailerPID
+	TrailerSessionID      = git.TrailerSessionID
+	TrailerCommittedAt    = git.TrailerCommittedAt
+	TrailerCoAuthoredBy   = git.TrailerCoAuthoredBy
+)
+
 var commitCmd = &cobra.Command{
 	Use:   "commit [flags] [-- git-commit-args...]",
 	Short: "Git commit with automatic agent identity",
@@ -21,7 +73,8 @@ var commitCmd = &cobra.Command{
 When run by an agent (GT_ROLE set), this command:
 1. Detects the agent identity from environment variables
 2. Converts it to a git-friendly name and email
-3. Runs 'git commit' with the correct identity
+3. Appends agent trailers (Executed-By, Rig, Role, Molecule) to the message
+4. Runs 'git commit' with the correct identity
 
 The email domain is configurable in town settings (agent_email_domain).
 Default: gastown.local
@@ -29,12 +82,154 @@ Default: gastown.local
 Examples:
   gt commit -m "Fix bug"              # Commit as current agent
   gt commit -am "Quick fix"           # Stage all and commit
+  gt commit -m "Fix parser" -- src/parser.go src/lexer.go
+                                      # Commit only these paths
+  gt commit --fixup HEAD~2            # Fixup for rebase --autosquash
   gt commit -- --amend                # Amend last commit
 
 Identity mapping:
   Agent: gastown/crew/jack  →  Name: gastown/crew/jack
                                 Email: gastown.crew.jack@gastown.local
 
+Trailers:
+  Executed-By: gastown/crew/jack
+  Rig: gastown
+  Role: crew
+  Molecule: gt-abc12                  # Only when work is pinned; one per molecule
+  Branch: polecat/jack                # Only with --branch-trailer
+  Generated-By: gastown/0.2.6         # Only with --version-trailer
+  Host: build-7                       # Only with --env-trailers
+  PID: 4242                           # Only with --env-trailers
+  Session-Id: 3f2c...                 # Only with --env-trailers, when known
+  Committed-At: 2026-01-02T15:04:05Z  # Only with --timestamp-trailer
+  Refs: JIRA-123                      # Only with --ticket-from-branch, when found
+  Co-authored-by: gastown/polecats/toast <gastown.polecats.toast@gastown.local>
+                                      # Only with --co-author
+
+Fixups and squashes:
+  git's --fixup REF and --squash REF record a commit for a later
+  'git rebase -i --autosquash' to fold into REF, and need no -m: git writes
+  the "fixup! <subject>" or "squash! <subject>" subject. A squash without -m
+  and without a terminal takes that message as-is (--no-edit). The folded
+  commit is gone after the rebase, and REF keeps its own trailers, so
+  neither gets agent trailers (Executed-By, Molecule, Refs, ...), just as
+  with --no-trailers; --conventional isn't checked either. --trailer and
+  --co-author are still added. --fixup=amend:REF and --fixup=reword:REF
+  replace REF's message, so they keep the agent trailers.
+
+Paths:
+  Paths given after the flags (or after --) are committed as they are in the
+  working tree, and nothing else: other staged changes stay staged for a
+  later commit (git's --only). Trailers are applied as usual, and --check or
+  --dry-run print the pathspec that would be used.
+
+Flags (all other flags are passed through to git commit):
+  --no-trailers           Do not append agent trailers (implied by --fixup and
+                          --squash)
+  --molecule-status       Also record the pinned work's status (Molecule-Status)
+  --branch-trailer        Record the current branch (Branch), which is otherwise
+                          lost once the branch is deleted after merge
+  --amend-if-mine         Amend the last commit only if this agent made it
+                          (matching Executed-By) and it isn't pushed yet;
+                          otherwise create a new commit. When amending, agent
+                          trailers replace the commit's existing ones rather
+                          than being appended again
+  --keep-date             When amending, keep the original commit's dates. Git's
+                          --amend keeps the author date (when the work was
+                          written) but sets the committer date (when the commit
+                          was last rewritten) to now; this keeps both, so a
+                          small fixup doesn't move the commit in history
+  --check                 Preflight: build the final message with trailers and run
+                          'git commit --dry-run' with it; nothing is committed.
+                          Lists the staged files unless -a or paths are given
+  --preflight-only        Diagnose instead of committing: check that git is installed
+                          and new enough, the repo isn't mid-merge/rebase, HEAD is
+                          on a branch with an upstream, and the agent identity and
+                          molecule lookup work. Each check reports pass, warn or
+                          fail; exits non-zero if any check fails
+  --author-from-identity  Set the commit author (GIT_AUTHOR_NAME/EMAIL) from the
+                          agent's rig/role/name, e.g. beads-crew-dave
+                          <dave@beads.agents>; formats from town settings
+                          commit.author_name_format/author_email_format
+  --agent-author          Make the agent both author and committer through the
+                          GIT_AUTHOR_* and GIT_COMMITTER_* environment, so
+                          neither the human's git config nor inherited GIT_*
+                          variables end up on the commit. With
+                          --author-from-identity, that sets the author
+  --env-trailers          Record where the commit was made: Host, the agent's
+                          PID, and Session-Id (GT_SESSION_ID or the runtime's
+                          session env var), to correlate with agent run logs
+  --co-author WHO         Credit another contributor with a Co-authored-by trailer,
+                          after the agent trailers. WHO is "Name <email>" or an
+                          agent identity (e.g. gastown/polecats/toast), whose
+                          email is derived as for the committing agent;
+                          repeatable, and kept with --no-trailers
+  --trailer KEY=VALUE     Add a custom trailer (e.g. --trailer Reviewed-By=alice),
+                          after the agent trailers; repeatable, and kept with
+                          --no-trailers. Shown by --check with the others
+  --ticket-from-branch    Extract a ticket (e.g. JIRA-123 from feature/JIRA-123-foo)
+                          from the branch name and record it as a Refs trailer;
+                          pattern and key from town settings commit.ticket_pattern
+                          and commit.ticket_trailer. Skipped if there's no ticket
+  --ticket-prefix         Like --ticket-from-branch, and also prefix the subject
+                          with "JIRA-123: " unless it already mentions the ticket
+  --max-message-bytes N   Reject messages (from -m) larger than N bytes, not counting
+                          trailers (default from town settings
+                          commit.max_message_bytes, else 65536)
+  --truncate-message      Truncate an oversized message instead: the subject and
+                          any trailers are kept and the body is cut, with a note;
+                          also enabled by town settings commit.truncate_message
+  --conventional          Reject the commit unless the subject (from -m) follows
+                          Conventional Commits: "type(scope): description",
+                          e.g. "fix(parser): handle empty input", with an allowed
+                          type (feat, fix, docs, style, refactor, perf, test,
+                          build, ci, chore, revert; or town settings
+                          commit.conventional_types). Checked before trailers
+                          are added; with --check or --dry-run the result is
+                          reported. Also enabled by commit.conventional
+  --sign, -S              Sign the commit (git commit -S) with the configured key
+  --no-sign               Don't sign, overriding commit.gpgsign=true
+  --ssh-sign-key PATH     Sign the commit with this SSH key (gpg.format=ssh), for
+                          environments with SSH keys but no GPG; PATH may also be
+                          a literal "key::ssh-ed25519 ..." public key
+  --no-binary             Refuse to commit if binary files are staged (by default
+                          staged binaries only produce a warning)
+  --scan-secrets          Refuse to commit if the staged diff adds likely secrets:
+                          AWS keys, private keys, GitHub/Slack/Stripe/Google
+                          tokens, or high-entropy strings. Lines containing
+                          "gitleaks:allow" are skipped, as are matches or paths
+                          matching town settings commit.secret_allowlist. Only
+                          the index is scanned, not files staged by -a
+  --version-trailer       Record the gt version that made the commit (Generated-By);
+                          also enabled by town settings commit.version_trailer
+  --timestamp-trailer     Record when gt commit ran as a Committed-At trailer (RFC 3339,
+                          UTC), for audit logs; unlike the author and committer
+                          dates it isn't changed by rebases or --date
+  --seed-from-molecule    Without -m, prefill the subject from the pinned molecule
+                          and open the editor for the body
+  --autoformat            Split a long message into a subject line and a body
+                          wrapped at 72 columns (keeps well-formed messages as-is)
+  --autoformat-width N    Subject limit and wrap width for --autoformat
+  --subject-format FMT    Subject format for --seed-from-molecule, using {id}
+                          and {title} (default from town settings commit.subject_format,
+                          else "{id}: {title}")
+
+Commit templates:
+  Without a message (and when the message generator isn't used), gt commit
+  opens the editor with the repo's template, comment lines stripped, and the
+  trailers appended below it. Leaving it unedited aborts the commit, as with
+  git's commit.template. .gastown/commit-template.txt at the repo root
+  takes precedence over git's commit.template.
+
+Identity outside a town:
+  When the town layout isn't present (e.g. a CI checkout of an agent's
+  branch), the identity comes from GASTOWN_ROLE (a role or full address such
+  as gastown/polecats/toast), GASTOWN_RIG and GASTOWN_POLECAT, else from
+  .gastown/identity.json in the repo ({"role": "polecat", "rig": "gastown",
+  "polecat": "toast"}). A committed manifest applies to anyone committing
+  outside a town, so only place it in agent or CI checkouts. Set GT_DEBUG
+  to see which source was used.
+
 When run without GT_ROLE (human), passes through to git commit with no changes.`,
 	RunE:               runCommit,
 	DisableFlagParsing: true, // We'll parse flags ourselves to pass them to git
@@ -45,33 +240,1392 @@ func init() {
 	rootCmd.AddCommand(commitCmd)
 }
 
+// commitOptions holds the gt-specific flags extracted from the commit args.
+type commitOptions struct {
+	noTrailers       bool     // Skip all agent trailers
+	moleculeStatus   bool     // Add a Molecule-Status trailer
+	branchTrailer    bool     // Add a Branch trailer
+	versionTrailer   bool     // Add a Generated-By trailer
+	timestampTrailer bool     // Add a Committed-At trailer
+	amendIfMine      bool     // Amend HEAD only if this agent made it and it's unpushed
+	keepDate         bool     // Keep the amended commit's author and committer dates
+	check            bool     // Dry-run the commit with the assembled message
+	preflightOnly    bool     // Only report whether a commit can be made here
+	authorIdentity   bool     // Derive GIT_AUTHOR_NAME/EMAIL from the agent
+	agentAuthor      bool     // Set GIT_AUTHOR_* and GIT_COMMITTER_* to the agent
+	envTrailers      bool     // Add Host, PID and Session-Id trailers
+	noBinary         bool     // Refuse to commit staged binary files
+	scanSecrets      bool     // Refuse to commit staged secrets
+	ticketTrailer    bool     // Add a trailer for the ticket in the branch name
+	ticketPrefix     bool     // Also prefix the subject with the ticket
+	maxMessageBytes  int      // Overrides the configured message size limit
+	truncateMessage  bool     // Truncate oversized messages instead of rejecting
+	conventional     bool     // Require a Conventional Commits subject
+	sign             bool     // Sign the commit (-S)
+	noSign           bool     // Don't sign, overriding commit.gpgsign
+	sshSignKey       string   // Sign with this SSH key (gpg.format=ssh)
+	coAuthors        []string // Co-authors from --co-author, as given
+	trailers         []string // Custom trailers from --trailer, as "Key: value"
+	seedFromMolecule bool     // Prefill the subject from the pinned molecule
+	subjectFormat    string   // Overrides the configured seed subject format
+	autoformat       bool     // Split the message into subject and wrapped body
+	autoformatWidth  int      // Subject limit and wrap width for autoformat
+}
+
+// MoleculeStatus is the subset of `gt mol status --json` used for trailers.
+type MoleculeStatus struct {
+	MoleculeID  string   // Attached molecule, or the pinned bead if none
+	MoleculeIDs []string // All attached molecules in order, if several; MoleculeID is the first
+	Title       string   // Title of the pinned bead
+	Status      string   // Status of the pinned bead (e.g. "in_progress")
+}
+
+// IDs returns the pinned molecules in order: MoleculeIDs, or MoleculeID
+// alone. Returns nil if nothing is pinned.
+func (m *MoleculeStatus) IDs() []string {
+	switch {
+	case m == nil:
+		return nil
+	case len(m.MoleculeIDs) > 0:
+		return m.MoleculeIDs
+	case m.MoleculeID != "":
+		return []string{m.MoleculeID}
+	}
+	return nil
+}
+
 func runCommit(cmd *cobra.Command, args []string) error {
+	opts, gitArgs, err := parseCommitArgs(args)
+	if err != nil {
+		return err
+	}
+	if opts.preflightOnly {
+		return runCommitPreflight()
+	}
+
+	// Only the first -m can hold the subject; later ones are body paragraphs
+	if indexes := messageArgIndexes(gitArgs); opts.autoformat && len(indexes) > 0 {
+		gitArgs[indexes[0]] = autoformatMessage(gitArgs[indexes[0]], opts.autoformatWidth)
+	}
+
+	// Role and molecule lookups are shared by the steps below
+	cc := &commitContext{}
+
+	// Keep paths behind "--" so flags gt adds later (e.g. --trailer) can't
+	// be taken for paths, or paths for flags
+	if flags, paths := splitPathspecs(gitArgs); len(paths) > 0 {
+		gitArgs = append(append(flags, "--"), paths...)
+	}
+
+	// A squash opens the editor on git's "squash! <subject>" unless given a
+	// message; without a terminal, take git's message as-is
+	autosquash := autosquashKind(gitArgs)
+	if autosquash == autosquashSquash && len(messageArgIndexes(gitArgs)) == 0 && !term.IsTerminal(int(os.Stdin.Fd())) {
+		gitArgs = append([]string{"--no-edit"}, gitArgs...)
+	}
+
 	// Detect agent identity
-	identity := detectSender()
+	identity := commitIdentity(cc)
+
+	if opts.amendIfMine {
+		amend, reason := shouldAmendIfMine(identity)
+		if amend {
+			gitArgs = append([]string{"--amend"}, gitArgs...)
+			fmt.Printf("%s Amending previous commit (%s)\n", style.ArrowPrefix, reason)
+		} else {
+			fmt.Printf("%s Creating a new commit (%s)\n", style.ArrowPrefix, reason)
+		}
+	}
+
+	var env []string
+	if opts.keepDate {
+		var dateArgs []string
+		if dateArgs, env, err = keepDateArgs(gitArgs, opts.amendIfMine); err != nil {
+			return err
+		}
+		gitArgs = append(dateArgs, gitArgs...)
+	}
+
+	var signConfig []string
+	if opts.sshSignKey != "" {
+		if signConfig, err = sshSignConfig(opts.sshSignKey); err != nil {
+			return err
+		}
+	}
+	if signArgs := commitSignArgs(opts); signArgs != nil {
+		gitArgs = append(signArgs, gitArgs...)
+	}
+
+	if opts.scanSecrets {
+		_, settings := cc.Settings()
+		if err := checkStagedSecrets(settings.SecretAllowlist); err != nil {
+			return err
+		}
+	}
+
+	// Checked on the message as given, before trailers or a ticket prefix.
+	// A fixup or squash has git's generated subject, so there's none to check
+	if _, settings := cc.Settings(); (opts.conventional || settings.Conventional) && !foldsAway(autosquash) {
+		checked, err := checkConventionalSubject(gitArgs, settings.ConventionalTypes)
+		if err != nil {
+			return err
+		}
+		if checked && isDryRun(opts, gitArgs) {
+			fmt.Printf("%s Subject is a conventional commit\n", style.SuccessPrefix)
+		}
+	}
 
 	// If overseer (human), just pass through to git commit
 	if identity == "overseer" {
-		return runGitCommit(args, "", "")
+		domain, _ := cc.Settings()
+		trailers := append(coAuthorTrailers(opts.coAuthors, domain), opts.trailers...)
+		if opts.check {
+			return runCommitCheck(gitArgs, trailers, "", "")
+		}
+		return runGitCommit(gitArgs, trailers, "", "", signConfig, env)
+	}
+
+	domain, commitSettings := cc.Settings()
+
+	// Convert identity to git-friendly email
+	// "gastown/crew/jack" → "gastown.crew.jack@domain"
+	email := identityToEmail(identity, domain)
+
+	// Use identity as the author name (human-readable)
+	name := identity
+
+	if opts.seedFromMolecule && !hasCommitMessageArg(gitArgs) {
+		format := opts.subjectFormat
+		if format == "" {
+			format = commitSettings.SubjectFormat
+		}
+		if mol := cc.Molecule(); mol != nil && mol.Title != "" {
+			// -e keeps the editor open so the body can be written below the
+			// seeded subject; git appends the trailers before launching it.
+			gitArgs = append([]string{"-e", "-m", formatMoleculeSubject(format, mol)}, gitArgs...)
+		}
+	}
+
+	// Without a message or a terminal for the editor, ask the generator
+	if !hasCommitMessageArg(gitArgs) && !term.IsTerminal(int(os.Stdin.Fd())) {
+		message, err := generateCommitMessage(cc)
+		if err != nil && !errors.Is(err, ErrNoMessageGenerator) {
+			return fmt.Errorf("generating commit message: %w", err)
+		}
+		if err == nil {
+			gitArgs = append([]string{"-m", message}, gitArgs...)
+		}
+	}
+
+	// Still without a message, start the editor from the repo's template.
+	// It is seeded as -m so the steps below (e.g. a ticket prefix) apply,
+	// and moved to -t before committing.
+	fromTemplate := false
+	if !hasCommitMessageArg(gitArgs) {
+		if cwd, err := os.Getwd(); err == nil {
+			template, err := loadCommitTemplate(git.NewGit(cwd))
+			if err != nil {
+				return err
+			}
+			if template != "" {
+				gitArgs = append([]string{"-e", "-m", template}, gitArgs...)
+				fromTemplate = true
+			}
+		}
+	}
+
+	if commitSettings.VersionTrailer {
+		opts.versionTrailer = true
+	}
+
+	gitArgs, err = enforceMessageLimit(gitArgs, opts, commitSettings)
+	if err != nil {
+		return err
+	}
+
+	// The commit a fixup or squash folds into keeps its own attribution
+	if foldsAway(autosquash) && (!opts.noTrailers || opts.ticketTrailer) {
+		fmt.Printf("%s Skipping agent trailers on a %s! commit; the target commit keeps its own\n", style.ArrowPrefix, autosquash)
+		opts.noTrailers, opts.ticketTrailer = true, false
+	}
+
+	var trailers []string
+	if !opts.noTrailers {
+		trailers = buildAgentTrailers(cc, identity, opts)
+	}
+
+	if opts.ticketTrailer {
+		ticketTrailer, err := applyBranchTicket(gitArgs, opts.ticketPrefix, commitSettings)
+		if err != nil {
+			return err
+		}
+		if ticketTrailer != "" {
+			trailers = append(trailers, ticketTrailer)
+		}
+	}
+	trailers = append(trailers, coAuthorTrailers(opts.coAuthors, domain)...)
+	trailers = append(trailers, opts.trailers...)
+
+	warnLFSNotInstalled()
+	warnEOLOnlyChanges()
+	warnUnstagedChanges(gitArgs)
+	if err := checkStagedBinaries(opts.noBinary); err != nil {
+		return err
+	}
+
+	if opts.agentAuthor {
+		env = append(env, git.Identity{Name: name, Email: email}.Env()...)
+	}
+	if opts.authorIdentity {
+		ctx, err := cc.Role()
+		if err != nil {
+			return fmt.Errorf("resolving agent identity: %w", err)
+		}
+		authorName, authorEmail, err := agentAuthor(ctx, commitSettings)
+		if err != nil {
+			return err
+		}
+		env = append(env, "GIT_AUTHOR_NAME="+authorName, "GIT_AUTHOR_EMAIL="+authorEmail)
+	}
+
+	if molecules := trailerValues(trailers, TrailerMolecule); len(molecules) > 0 && isDryRun(opts, gitArgs) {
+		fmt.Printf("%s Molecules: %s\n", style.ArrowPrefix, strings.Join(molecules, ", "))
+	}
+	if _, paths := splitPathspecs(gitArgs); len(paths) > 0 && isDryRun(opts, gitArgs) {
+		fmt.Printf("%s Paths: %s\n", style.ArrowPrefix, strings.Join(paths, " "))
+	}
+	if committedAt := trailerValues(trailers, TrailerCommittedAt); len(committedAt) > 0 && isDryRun(opts, gitArgs) {
+		fmt.Printf("%s Committed-At: %s\n", style.ArrowPrefix, committedAt[0])
+	}
+	if opts.check {
+		return runCommitCheck(gitArgs, trailers, name, email)
+	}
+	config := signConfig
+	if isAmend(gitArgs) {
+		config = append(config, amendTrailerConfig(trailers)...)
+	}
+	commitArgs, commitTrailers := gitArgs, trailers
+	if fromTemplate {
+		var cleanup func()
+		if commitArgs, cleanup, err = templateArgs(gitArgs, trailers); err != nil {
+			return err
+		}
+		defer cleanup()
+		commitTrailers = nil // Already in the template
+	}
+	if err := runGitCommit(commitArgs, commitTrailers, name, email, config, env); err != nil {
+		return err
+	}
+	runPostCommit(opts, gitArgs, trailers)
+	return nil
+}
+
+// commitIdentity returns the agent address to commit as, or "overseer" for
+// humans. Outside an agent's town directory (e.g. a CI replay of agent
+// work), the GASTOWN_ROLE env vars or .gastown/identity.json supply it.
+func commitIdentity(cc *commitContext) string {
+	identity := detectSender()
+	if identity != "overseer" || os.Getenv(EnvGTRole) != "" {
+		return identity
+	}
+	if info, err := cc.Role(); err == nil && (info.Source == RoleSourceFallbackEnv || info.Source == RoleSourceManifest) {
+		return info.ActorString()
 	}
+	return identity
+}
 
-	// Load agent email domain from town settings
+// loadCommitSettings returns the agent email domain and commit settings from
+// town settings, falling back to defaults outside a town.
+func loadCommitSettings() (string, config.CommitSettings) {
 	domain := DefaultAgentEmailDomain
+	var commitSettings config.CommitSettings
 	townRoot, err := workspace.FindFromCwd()
 	if err == nil && townRoot != "" {
 		settings, err := config.LoadOrCreateTownSettings(config.TownSettingsPath(townRoot))
-		if err == nil && settings.AgentEmailDomain != "" {
-			domain = settings.AgentEmailDomain
+		if err == nil {
+			if settings.AgentEmailDomain != "" {
+				domain = settings.AgentEmailDomain
+			}
+			if settings.Commit != nil {
+				commitSettings = *settings.Commit
+			}
 		}
 	}
+	return domain, commitSettings
+}
 
-	// Convert identity to git-friendly email
-	// "gastown/crew/jack" → "gastown.crew.jack@domain"
-	email := identityToEmail(identity, domain)
+// ErrNoMessageGenerator is returned by the default MessageGenerator.
+// gt commit then leaves the missing message for git to report.
+var ErrNoMessageGenerator = errors.New("no commit message generator configured")
 
-	// Use identity as the author name (human-readable)
-	name := identity
+// MessageGenerator produces a commit message for the staged files when an
+// agent runs gt commit without a message and without a terminal for the
+// editor (e.g. an LLM summarizer). Trailers are appended afterwards.
+// The default returns ErrNoMessageGenerator, leaving git's behavior unchanged.
+var MessageGenerator = func(ctx RoleContext, staged []string) (string, error) {
+	return "", ErrNoMessageGenerator
+}
+
+// CommitResult describes a commit created by gt commit.
+type CommitResult struct {
+	Hash      string   // Hash of the new commit
+	Trailers  []string // Trailers gt commit appended, as "Key: value"
+	Molecule  string   // Value of the first Molecule trailer, or "" if none
+	Molecules []string // Values of all Molecule trailers, in order
+}
+
+// PostCommit is called after gt commit creates an agent commit, e.g. to
+// record the commit against the molecule in an orchestrator. It is not
+// called for dry runs (--check or git's --dry-run), failed commits, or
+// overseer pass-through.
+// The commit is already made when it runs: an error is reported as a
+// warning and does not undo the commit or change gt commit's exit status.
+// The default does nothing.
+var PostCommit = func(result CommitResult) error {
+	return nil
+}
+
+// runPostCommit runs PostCommit for the commit just made at HEAD. A dry
+// run made no commit, so it is skipped.
+func runPostCommit(opts commitOptions, gitArgs, trailers []string) {
+	if isDryRun(opts, gitArgs) {
+		return
+	}
+	result := CommitResult{Trailers: trailers, Molecules: trailerValues(trailers, TrailerMolecule)}
+	if len(result.Molecules) > 0 {
+		result.Molecule = result.Molecules[0]
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		result.Hash, _ = git.NewGit(cwd).Rev("HEAD")
+	}
+	if err := PostCommit(result); err != nil {
+		style.PrintWarning("post-commit hook failed (the commit was kept): %v", err)
+	}
+}
+
+// generateCommitMessage runs MessageGenerator for the current agent and the
+// changes that would be committed.
+func generateCommitMessage(cc *commitContext) (string, error) {
+	ctx, _ := cc.Role() // Best-effort: generators get an empty context outside a town
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getting current directory: %w", err)
+	}
+	status, err := git.NewGit(cwd).Status()
+	if err != nil {
+		return "", fmt.Errorf("reading status: %w", err)
+	}
+	// Only the index is committed; unstaged edits don't belong in the message
+	message, err := MessageGenerator(ctx, status.Staged)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(message) == "" {
+		return "", fmt.Errorf("generator returned an empty message")
+	}
+	return message, nil
+}
+
+// warnLFSNotInstalled warns when changed files are meant to be stored in
+// LFS but git-lfs isn't installed, so the full blobs would be committed.
+// The check is advisory: any lookup failure skips the warning.
+func warnLFSNotInstalled() {
+	if git.IsLFSInstalled() {
+		return
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	g := git.NewGit(cwd)
+	if isLFS, err := g.IsLFSRepo(); err != nil || !isLFS {
+		return
+	}
+	status, err := g.Status()
+	if err != nil {
+		return
+	}
+	tracked, err := g.LFSTrackedFiles(append(status.Modified, status.Added...))
+	if err != nil || len(tracked) == 0 {
+		return
+	}
+	style.PrintWarning("git-lfs is not installed; these LFS-tracked files will be committed as full blobs:")
+	for _, path := range tracked {
+		fmt.Printf("  %s\n", path)
+	}
+}
+
+// warnUnstagedChanges warns when the commit takes only the index but the
+// working tree has further edits, which won't be included. The check is
+// advisory: any lookup failure skips the warning.
+func warnUnstagedChanges(gitArgs []string) {
+	if !commitsIndexOnly(gitArgs) {
+		return
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	status, err := git.NewGit(cwd).Status()
+	if err != nil || len(status.Unstaged) == 0 {
+		return
+	}
+	style.PrintWarning("these files have unstaged changes that won't be committed:")
+	for _, path := range status.Unstaged {
+		fmt.Printf("  %s\n", path)
+	}
+	fmt.Printf("  %s\n", style.Dim.Render("Stage them with 'git add', or commit everything with -a"))
+}
+
+// warnEOLOnlyChanges warns about files that show as modified only because
+// of CR/LF line endings, which -a would commit as whole-file rewrites. The
+// check is advisory: any lookup failure skips the warning.
+func warnEOLOnlyChanges() {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	g := git.NewGit(cwd)
+	files, err := g.EOLOnlyChanges()
+	if err != nil || len(files) == 0 {
+		return
+	}
+	style.PrintWarning("these files differ from HEAD only in line endings (core.autocrlf=%s):", g.AutoCRLF())
+	for _, path := range files {
+		fmt.Printf("  %s\n", path)
+	}
+	fmt.Printf("  %s\n", style.Dim.Render("Restore them with 'git checkout -- <file>', or check core.autocrlf and .gitattributes eol settings"))
+}
+
+// checkStagedBinaries warns about staged binary files, or with block
+// returns an error listing them. Only the index is checked, so files that
+// -a stages at commit time are not seen. Lookup failures skip the check.
+func checkStagedBinaries(block bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	binaries, err := git.NewGit(cwd).BinaryFilesInDiff("")
+	if err != nil || len(binaries) == 0 {
+		return nil
+	}
+	if block {
+		return fmt.Errorf("binary files are staged (unstage them or drop --no-binary): %s", strings.Join(binaries, ", "))
+	}
+	style.PrintWarning("binary files are staged; build artifacts should not be committed:")
+	for _, path := range binaries {
+		fmt.Printf("  %s\n", path)
+	}
+	return nil
+}
+
+// parseCommitArgs separates gt-specific flags from the args passed to git.
+// Parsing stops at "--"; it and everything after are passed through verbatim.
+// Values of git flags that take an argument (e.g. -m) are never interpreted.
+func parseCommitArgs(args []string) (commitOptions, []string, error) {
+	var opts commitOptions
+	var gitArgs []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, inlineValue, hasInlineValue := strings.Cut(arg, "=")
+
+		// value returns the flag's argument, from "--flag=value" or "--flag value"
+		value := func() (string, error) {
+			if hasInlineValue {
+				return inlineValue, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("flag %s requires a value", name)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var err error
+		switch {
+		case arg == "--":
+			return opts, append(gitArgs, args[i:]...), nil
+		case arg == "--no-trailers":
+			opts.noTrailers = true
+		case arg == "--molecule-status":
+			opts.moleculeStatus = true
+		case arg == "--branch-trailer":
+			opts.branchTrailer = true
+		case arg == "--env-trailers":
+			opts.envTrailers = true
+		case arg == "--no-binary":
+			opts.noBinary = true
+		case arg == "--scan-secrets":
+			opts.scanSecrets = true
+		case arg == "--ticket-from-branch":
+			opts.ticketTrailer = true
+		case arg == "--ticket-prefix":
+			opts.ticketTrailer = true
+			opts.ticketPrefix = true
+		case arg == "--author-from-identity":
+			opts.authorIdentity = true
+		case arg == "--agent-author":
+			opts.agentAuthor = true
+		case arg == "--check":
+			opts.check = true
+		case arg == "--preflight-only":
+			opts.preflightOnly = true
+		case arg == "--amend-if-mine":
+			opts.amendIfMine = true
+		case arg == "--keep-date":
+			opts.keepDate = true
+		case arg == "--version-trailer":
+			opts.versionTrailer = true
+		case arg == "--timestamp-trailer":
+			opts.timestampTrailer = true
+		case arg == "--seed-from-molecule":
+			opts.seedFromMolecule = true
+		case name == "--subject-format":
+			opts.subjectFormat, err = value()
+		case arg == "--autoformat":
+			opts.autoformat = true
+		case name == "--autoformat-width":
+			opts.autoformatWidth, err = intValue(name, value)
+		case name == "--max-message-bytes":
+			opts.maxMessageBytes, err = intValue(name, value)
+		case arg == "--truncate-message":
+			opts.truncateMessage = true
+		case arg == "--conventional":
+			opts.conventional = true
+		case arg == "--sign", arg == "-S":
+			opts.sign = true
+		case arg == "--no-sign":
+			opts.noSign = true
+		case name == "--ssh-sign-key":
+			opts.sshSignKey, err = value()
+		case name == "--co-author":
+			var coAuthor string
+			if coAuthor, err = value(); err == nil {
+				err = validateCoAuthor(coAuthor)
+				opts.coAuthors = append(opts.coAuthors, strings.TrimSpace(coAuthor))
+			}
+		case name == "--trailer":
+			var trailer string
+			if trailer, err = value(); err == nil {
+				trailer, err = parseCustomTrailer(trailer)
+				opts.trailers = append(opts.trailers, trailer)
+			}
+		default:
+			normalized := normalizeMessageArg(arg)
+			gitArgs = append(gitArgs, normalized...)
+			if gitCommitFlagTakesValue(normalized[len(normalized)-1]) && i+1 < len(args) {
+				i++
+				gitArgs = append(gitArgs, args[i])
+			}
+		}
+		if err != nil {
+			return opts, nil, err
+		}
+	}
+	if opts.noSign && (opts.sign || opts.sshSignKey != "") {
+		return opts, nil, fmt.Errorf("--no-sign can't be combined with --sign or --ssh-sign-key")
+	}
+	return opts, gitArgs, nil
+}
+
+// isDryRun reports whether the commit is only previewed: with --check,
+// or git's own --dry-run.
+func isDryRun(opts commitOptions, gitArgs []string) bool {
+	return opts.check || slices.Contains(gitArgs, "--dry-run")
+}
+
+// commitSignArgs returns the git commit args for the signing options: -S
+// to sign, --no-gpg-sign to override commit.gpgsign, or nil to leave
+// signing to git's config.
+func commitSignArgs(opts commitOptions) []string {
+	switch {
+	case opts.sign || opts.sshSignKey != "":
+		return []string{"-S"}
+	case opts.noSign:
+		return []string{"--no-gpg-sign"}
+	}
+	return nil
+}
+
+// intValue parses an integer flag value obtained from value.
+func intValue(name string, value func() (string, error)) (int, error) {
+	v, err := value()
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("flag %s: invalid number %q", name, v)
+	}
+	return n, nil
+}
+
+// splitMessageArgs separates the -m values from the other normalized git
+// commit args. A bundled flag like "-am" keeps its other flags ("-a").
+func splitMessageArgs(gitArgs []string) (paragraphs, rest []string) {
+	isValue := make(map[int]bool)
+	for _, i := range messageArgIndexes(gitArgs) {
+		isValue[i] = true
+	}
+	for i, arg := range gitArgs {
+		switch {
+		case isValue[i]:
+			paragraphs = append(paragraphs, arg)
+		case isValue[i+1] && (arg == "-m" || arg == "--message"):
+		case isValue[i+1]:
+			rest = append(rest, strings.TrimSuffix(arg, "m"))
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return paragraphs, rest
+}
+
+// enforceMessageLimit rejects (or with truncation enabled, truncates) a
+// message given with -m that is over the size limit. The limit applies
+// before trailers are appended. Messages from -F or the editor aren't seen.
+func enforceMessageLimit(gitArgs []string, opts commitOptions, settings config.CommitSettings) ([]string, error) {
+	limit := opts.maxMessageBytes
+	if limit <= 0 {
+		limit = settings.MaxMessageBytes
+	}
+	if limit <= 0 {
+		limit = DefaultMaxMessageBytes
+	}
+
+	paragraphs, rest := splitMessageArgs(gitArgs)
+	message := strings.Join(paragraphs, "\n\n") // As git joins them
+	if len(message) <= limit {
+		return gitArgs, nil
+	}
+	if !opts.truncateMessage && !settings.TruncateMessage {
+		return nil, fmt.Errorf("commit message is %d bytes, over the %d byte limit (see --max-message-bytes, --truncate-message)", len(message), limit)
+	}
+
+	truncated := truncateMessage(message, limit)
+	fmt.Printf("%s Commit message truncated from %d to %d bytes\n", style.WarningPrefix, len(message), len(truncated))
+	return append([]string{"-m", truncated}, rest...), nil
+}
+
+// truncateMessage cuts message's body so that the whole message fits in
+// limit bytes where possible. The subject line and a trailing trailer block
+// are always kept, and a note records how much of the body was dropped.
+func truncateMessage(message string, limit int) string {
+	subject, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	body = strings.TrimSpace(body)
+
+	var trailerBlock string
+	if cut := strings.LastIndex(body, "\n\n"); cut >= 0 && isTrailerBlock(body[cut+2:]) {
+		body, trailerBlock = body[:cut], body[cut+2:]
+	} else if isTrailerBlock(body) {
+		body, trailerBlock = "", body
+	}
+
+	assemble := func(kept string, dropped int) string {
+		parts := []string{subject}
+		if kept != "" {
+			parts = append(parts, kept)
+		}
+		if dropped > 0 {
+			parts = append(parts, fmt.Sprintf("[gt commit: message truncated, %d bytes of body dropped]", dropped))
+		}
+		if trailerBlock != "" {
+			parts = append(parts, trailerBlock)
+		}
+		return strings.Join(parts, "\n\n")
+	}
+
+	// Size the note for the worst case, then keep as much body as fits
+	budget := len(body) - (len(assemble(body, len(body))) - limit)
+	if budget >= len(body) {
+		return assemble(body, 0)
+	}
+	kept := ""
+	if budget > 0 {
+		kept = body[:budget]
+		for !utf8.ValidString(kept) {
+			kept = kept[:len(kept)-1] // Don't split a multi-byte rune
+		}
+		kept = strings.TrimSpace(kept)
+	}
+	return assemble(kept, len(body)-len(kept))
+}
+
+// isTrailerBlock reports whether every line of paragraph is a "Key: value"
+// trailer.
+func isTrailerBlock(paragraph string) bool {
+	if paragraph == "" {
+		return false
+	}
+	for _, line := range strings.Split(paragraph, "\n") {
+		key, _, ok := strings.Cut(line, ": ")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return false
+		}
+	}
+	return true
+}
+
+// commitsIndexOnly reports whether git commit args commit exactly the
+// index: no -a, -i, -o, -p (or long forms) and no pathspec.
+func commitsIndexOnly(gitArgs []string) bool {
+	for i := 0; i < len(gitArgs); i++ {
+		arg := gitArgs[i]
+		name, _, _ := strings.Cut(arg, "=")
+		switch {
+		case arg == "--", !strings.HasPrefix(arg, "-"):
+			return false // Pathspec
+		case name == "--all", name == "--include", name == "--only", name == "--interactive",
+			name == "--patch", name == "--pathspec-from-file":
+			return false
+		case len(arg) > 1 && arg[1] != '-' && strings.ContainsAny(arg[1:], "aiop"):
+			return false
+		}
+		if gitCommitFlagTakesValue(arg) {
+			i++
+		}
+	}
+	return true
+}
+
+// splitPathspecs separates git commit args into flags (with their values)
+// and pathspecs: the non-flag args, and everything after "--", which is
+// dropped.
+func splitPathspecs(gitArgs []string) (flags, paths []string) {
+	for i := 0; i < len(gitArgs); i++ {
+		arg := gitArgs[i]
+		switch {
+		case arg == "--":
+			return flags, append(paths, gitArgs[i+1:]...)
+		case !strings.HasPrefix(arg, "-"):
+			paths = append(paths, arg)
+		case gitCommitFlagTakesValue(arg) && i+1 < len(gitArgs):
+			flags = append(flags, arg, gitArgs[i+1])
+			i++
+		default:
+			flags = append(flags, arg)
+		}
+	}
+	return flags, paths
+}
+
+// gitCommitFlagTakesValue reports whether a git commit flag consumes the next
+// argument as its value (e.g. "-m msg", "-am msg", "--author who").
+func gitCommitFlagTakesValue(arg string) bool {
+	switch arg {
+	case "--message", "--file", "--author", "--date", "--template",
+		"--reuse-message", "--reedit-message", "--fixup", "--squash",
+		"--cleanup", "--trailer", "--pathspec-from-file":
+		return true
+	}
+	// Short flags may be bundled ("-am"); only the last one can take a value.
+	if len(arg) >= 2 && arg[0] == '-' && arg[1] != '-' {
+		return strings.ContainsRune("mFCct", rune(arg[len(arg)-1]))
+	}
+	return false
+}
+
+// hasCommitMessageArg reports whether the git commit args already determine
+// the message: given directly (-m, -F, -C, -c or their long forms), reused
+// by --amend/--no-edit, or generated by --fixup/--squash.
+func hasCommitMessageArg(gitArgs []string) bool {
+	for i := 0; i < len(gitArgs); i++ {
+		arg := gitArgs[i]
+		if arg == "--" {
+			return false
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		switch name {
+		case "--message", "--file", "--reuse-message", "--reedit-message",
+			"--amend", "--no-edit", "--fixup", "--squash":
+			return true
+		}
+		if len(arg) >= 2 && arg[0] == '-' && arg[1] != '-' && strings.ContainsAny(arg[1:], "mFCc") {
+			return true
+		}
+		if gitCommitFlagTakesValue(arg) {
+			i++ // Skip the value so it isn't mistaken for a flag
+		}
+	}
+	return false
+}
+
+// formatMoleculeSubject renders a commit subject for the molecule using format's
+// {id} and {title} placeholders (DefaultCommitSubjectFormat if format is empty).
+func formatMoleculeSubject(format string, mol *MoleculeStatus) string {
+	if format == "" {
+		format = DefaultCommitSubjectFormat
+	}
+	return strings.NewReplacer("{id}", mol.MoleculeID, "{title}", mol.Title).Replace(format)
+}
+
+// buildAgentTrailers returns the "Key: value" trailers identifying the agent
+// (and its pinned molecule, if any) that produced the commit.
+func buildAgentTrailers(cc *commitContext, identity string, opts commitOptions) []string {
+	attr := git.Attribution{Identity: identity}
+
+	if roleInfo, err := cc.Role(); err == nil {
+		attr.Rig = roleInfo.Rig
+		if roleInfo.Role != RoleUnknown {
+			attr.Role = string(roleInfo.Role)
+		}
+	}
+
+	// Molecule lookup is best-effort: a commit must never fail because the
+	// agent's hook can't be read.
+	if mol := cc.Molecule(); len(mol.IDs()) > 0 {
+		attr.Molecules = mol.IDs()
+		if opts.moleculeStatus {
+			attr.MoleculeStatus = mol.Status
+		}
+	}
+
+	// Detached HEAD has no branch to record, so the trailer is skipped.
+	if opts.branchTrailer {
+		if cwd, err := os.Getwd(); err == nil {
+			attr.Branch, _ = git.NewGit(cwd).CurrentBranch()
+		}
+	}
+
+	if opts.envTrailers {
+		envAttribution(&attr)
+	}
+
+	if opts.versionTrailer {
+		attr.GeneratedBy = "gastown/" + Version
+	}
+
+	if opts.timestampTrailer {
+		attr.CommittedAt = cc.CommittedAt()
+	}
+
+	return git.AgentTrailers(attr)
+}
+
+// envAttribution records where the commit was made. PID is gt's parent,
+// i.e. the agent process that ran gt commit.
+func envAttribution(attr *git.Attribution) {
+	attr.Host, _ = os.Hostname()
+	attr.PID = os.Getppid()
+	attr.SessionID = os.Getenv("GT_SESSION_ID")
+	if attr.SessionID == "" {
+		attr.SessionID = runtime.SessionIDFromEnv()
+	}
+}
+
+// applyBranchTicket extracts the ticket from the current branch name and
+// returns its trailer, prefixing the subject in gitArgs if prefix is set.
+// Returns "" when the branch has no ticket (or HEAD is detached).
+func applyBranchTicket(gitArgs []string, prefix bool, settings config.CommitSettings) (string, error) {
+	pattern := settings.TicketPattern
+	if pattern == "" {
+		pattern = DefaultTicketPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid commit.ticket_pattern %q: %w", pattern, err)
+	}
+	key := settings.TicketTrailer
+	if key == "" {
+		key = DefaultTicketTrailer
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", nil
+	}
+	branch, err := git.NewGit(cwd).CurrentBranch()
+	if err != nil {
+		return "", nil
+	}
+	ticket := extractTicket(branch, re)
+	if ticket == "" {
+		return "", nil
+	}
+
+	if indexes := messageArgIndexes(gitArgs); prefix && len(indexes) > 0 {
+		gitArgs[indexes[0]] = prefixSubject(gitArgs[indexes[0]], ticket)
+	}
+	return formatTrailer(key, ticket), nil
+}
+
+// extractTicket returns the ticket reference that re finds in branch: the
+// first capture group if re has one, otherwise the whole match. Returns ""
+// when there is no match.
+func extractTicket(branch string, re *regexp.Regexp) string {
+	match := re.FindStringSubmatch(branch)
+	if match == nil {
+		return ""
+	}
+	if len(match) > 1 {
+		return match[1]
+	}
+	return match[0]
+}
+
+// prefixSubject prefixes message's subject with "<ticket>: " unless the
+// subject already mentions the ticket.
+func prefixSubject(message, ticket string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	if strings.Contains(subject, ticket) {
+		return message
+	}
+	return ticket + ": " + message
+}
+
+// singleValueTrailers are the agent trailers a commit carries at most once.
+// When amending, a new value replaces the old one (e.g. a changed Branch).
+// Molecule isn't one: a commit carries a trailer per pinned molecule, and
+// replace would keep only the last of them.
+var singleValueTrailers = map[string]bool{
+	TrailerExecutedBy: true, TrailerRig: true, TrailerRole: true,
+	TrailerMoleculeStatus: true, TrailerBranch: true,
+	TrailerGeneratedBy: true, TrailerHost: true, TrailerPID: true, TrailerSessionID: true,
+	TrailerCommittedAt: true,
+}
+
+// amendTrailerConfig returns git config (for -c) that stops an amend from
+// duplicating trailers the commit already has: single-value agent trailers
+// replace the existing value, and other trailers are skipped when the same
+// key and value are already present. Git's default would append them all.
+func amendTrailerConfig(trailers []string) []string {
+	var config []string
+	seen := make(map[string]bool)
+	for _, t := range trailers {
+		key, _, _ := strings.Cut(t, ":")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		action := "addIfDifferent"
+		if singleValueTrailers[key] {
+			action = "replace"
+		}
+		config = append(config, "trailer."+key+".ifexists="+action)
+	}
+	return config
+}
+
+// parseCustomTrailer converts a --trailer "key=value" into a "Key: value"
+// trailer line. The key must be a trailer token (letters, digits, dashes).
+func parseCustomTrailer(arg string) (string, error) {
+	key, value, ok := strings.Cut(arg, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !ok || key == "" || value == "" {
+		return "", fmt.Errorf("invalid --trailer %q: want key=value, e.g. --trailer Reviewed-By=alice", arg)
+	}
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+			return "", fmt.Errorf("invalid --trailer key %q: use letters, digits and dashes, e.g. Reviewed-By", key)
+		}
+	}
+	if strings.ContainsAny(value, "\n\r") {
+		return "", fmt.Errorf("invalid --trailer %q: value must be a single line", arg)
+	}
+	return formatTrailer(key, value), nil
+}
+
+// coAuthorPattern matches a "Name <email>" co-author.
+var coAuthorPattern = regexp.MustCompile(`^[^<>\n]+ <[^<>\s]+@[^<>\s]+>$`)
+
+// validateCoAuthor checks a --co-author value: "Name <email>", or an agent
+// identity, which can't contain whitespace or angle brackets.
+func validateCoAuthor(arg string) error {
+	arg = strings.TrimSpace(arg)
+	switch {
+	case strings.ContainsAny(arg, "<>"):
+		if !coAuthorPattern.MatchString(arg) {
+			return fmt.Errorf("invalid --co-author %q: want \"Name <email>\"", arg)
+		}
+	case arg == "" || strings.IndexFunc(arg, unicode.IsSpace) >= 0:
+		return fmt.Errorf("invalid --co-author %q: want \"Name <email>\" or an agent identity, e.g. gastown/polecats/toast", arg)
+	}
+	return nil
+}
+
+// coAuthorTrailers returns a Co-authored-by trailer for each distinct
+// co-author. Agent identities get their email from domain, as the
+// committing agent's does.
+func coAuthorTrailers(coAuthors []string, domain string) []string {
+	var trailers []string
+	seen := make(map[string]bool)
+	for _, coAuthor := range coAuthors {
+		if !strings.Contains(coAuthor, "<") {
+			identity := strings.TrimSuffix(coAuthor, "/")
+			coAuthor = fmt.Sprintf("%s <%s>", identity, identityToEmail(identity, domain))
+		}
+		if seen[coAuthor] {
+			continue
+		}
+		seen[coAuthor] = true
+		trailers = append(trailers, formatTrailer(TrailerCoAuthoredBy, coAuthor))
+	}
+	return trailers
+}
+
+// trailerValues returns the values of the "Key: value" trailers with key,
+// in order.
+func trailerValues(trailers []string, key string) []string {
+	var values []string
+	for _, t := range trailers {
+		if k, v, ok := strings.Cut(t, ": "); ok && k == key {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// formatTrailer renders a trailer line, e.g. formatTrailer("Rig", "gastown").
+func formatTrailer(key, value string) string {
+	return fmt.Sprintf("%s: %s", key, value)
+}
+
+// AgentTrailers is the agent attribution parsed from a commit's trailers.
+type AgentTrailers struct {
+	ExecutedBy string
+	Rig        string
+	Role       string
+	Molecules  []string // A squashed commit may credit several molecules
+	CoAuthors  []string // "Name <email>" from Co-authored-by, in any casing
+}
+
+// ParseAgentTrailers extracts the agent attribution from trailers as returned
+// by git.CommitTrailers. Missing trailers leave their fields empty.
+func ParseAgentTrailers(trailers map[string][]string) AgentTrailers {
+	first := func(key string) string {
+		if values := trailers[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	agent := AgentTrailers{
+		ExecutedBy: first(TrailerExecutedBy),
+		Rig:        first(TrailerRig),
+		Role:       first(TrailerRole),
+		Molecules:  trailers[TrailerMolecule],
+	}
+	// Like GitHub, accept any casing of Co-authored-by
+	for key, values := range trailers {
+		if strings.EqualFold(key, TrailerCoAuthoredBy) {
+			agent.CoAuthors = append(agent.CoAuthors, values...)
+		}
+	}
+	return agent
+}
+
+// shouldAmendIfMine decides whether --amend-if-mine amends HEAD: only when
+// HEAD's Executed-By trailer names this agent and no remote branch contains
+// it. The reason describes the decision for the user.
+func shouldAmendIfMine(identity string) (bool, string) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return false, "cannot determine working directory"
+	}
+	g := git.NewGit(cwd)
+
+	trailers, err := g.CommitTrailers("HEAD")
+	if err != nil {
+		return false, "no previous commit"
+	}
+	author := ParseAgentTrailers(trailers).ExecutedBy
+	if author == "" {
+		return false, "last commit has no Executed-By trailer"
+	}
+	if author != strings.TrimSuffix(identity, "/") {
+		return false, fmt.Sprintf("last commit was made by %s", author)
+	}
+
+	remotes, err := g.RemoteBranchesContaining("HEAD")
+	if err != nil {
+		return false, "cannot determine whether last commit was pushed"
+	}
+	if len(remotes) > 0 {
+		return false, fmt.Sprintf("last commit is already pushed to %s", remotes[0])
+	}
+	return true, "last commit is yours and unpushed"
+}
+
+// keepDateArgs implements --keep-date for an amend: it returns a --date arg
+// with HEAD's author date (which also survives --reset-author) and a
+// GIT_COMMITTER_DATE env var with HEAD's committer date. Without --amend in
+// gitArgs it's an error, unless --amend-if-mine decided on a new commit.
+func keepDateArgs(gitArgs []string, amendIfMine bool) (args, env []string, err error) {
+	if !isAmend(gitArgs) {
+		if amendIfMine {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("--keep-date only applies to --amend")
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting current directory: %w", err)
+	}
+	author, committer, err := git.NewGit(cwd).CommitDates("HEAD")
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading dates of the commit to amend: %w", err)
+	}
+	return []string{"--date=" + author}, []string{"GIT_COMMITTER_DATE=" + committer}, nil
+}
+
+// isAmend reports whether git commit args include --amend.
+func isAmend(gitArgs []string) bool {
+	for i := 0; i < len(gitArgs); i++ {
+		arg := gitArgs[i]
+		if arg == "--" {
+			return false
+		}
+		if arg == "--amend" {
+			return true
+		}
+		if gitCommitFlagTakesValue(arg) {
+			i++ // Skip the value so it isn't mistaken for a flag
+		}
+	}
+	return false
+}
+
+// commitContext memoizes the agent's role, pinned molecule and town commit
+// settings for one gt commit run. Several steps need them, and each lookup
+// walks the workspace or, for the molecule, spawns gt mol status. The zero
+// value is ready to use.
+type commitContext struct {
+	roleResolved bool
+	role         RoleContext
+	roleErr      error
+
+	molResolved bool
+	mol         *MoleculeStatus
+
+	settingsResolved bool
+	domain           string
+	settings         config.CommitSettings
+
+	committedAt time.Time
+}
+
+// Settings returns loadCommitSettings, resolved on first use.
+func (c *commitContext) Settings() (string, config.CommitSettings) {
+	if !c.settingsResolved {
+		c.domain, c.settings = loadCommitSettings()
+		c.settingsResolved = true
+	}
+	return c.domain, c.settings
+}
+
+// Role returns GetRole, resolved on first use.
+func (c *commitContext) Role() (RoleContext, error) {
+	if !c.roleResolved {
+		c.role, c.roleErr = GetRole()
+		c.roleResolved = true
+	}
+	return c.role, c.roleErr
+}
+
+// Molecule returns getPinnedMolecule, looked up on first use. A failed
+// lookup is remembered as nothing pinned rather than retried.
+func (c *commitContext) Molecule() *MoleculeStatus {
+	if !c.molResolved {
+		c.mol = getPinnedMolecule()
+		c.molResolved = true
+	}
+	return c.mol
+}
+
+// CommittedAt returns the time of the commit, taken on first use so every
+// trailer and preview shows the same moment.
+func (c *commitContext) CommittedAt() time.Time {
+	if c.committedAt.IsZero() {
+		c.committedAt = time.Now()
+	}
+	return c.committedAt
+}
+
+// getPinnedMolecule returns the work pinned to the current agent's hook,
+// or nil if nothing is pinned or the lookup fails.
+func getPinnedMolecule() *MoleculeStatus {
+	mol, _ := lookupPinnedMolecule()
+	return mol
+}
+
+// lookupPinnedMolecule is getPinnedMolecule, reporting lookup failures.
+// It returns nil and no error if nothing is pinned.
+func lookupPinnedMolecule() (*MoleculeStatus, error) {
+	return MoleculeLookup()
+}
+
+// MoleculeLookup returns the work pinned to the current agent's hook, or
+// nil and no error if nothing is pinned. gt commit records it in the
+// Molecule trailers. The default runs `gt mol status --json` with the
+// running gt binary; tests and embedders can replace it.
+var MoleculeLookup = molStatusLookup
+
+// molStatusLookup is the default MoleculeLookup.
+func molStatusLookup() (*MoleculeStatus, error) {
+	out, err := exec.Command(gtExecutable(), "mol", "status", "--json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("gt mol status: %w", err)
+	}
+	return parseMoleculeStatus(out)
+}
+
+// parseMoleculeStatus converts `gt mol status --json` output to the pinned
+// molecules, accepting both a single attached_molecule and, from newer gt
+// versions, the attached_molecules list.
+func parseMoleculeStatus(out []byte) (*MoleculeStatus, error) {
+	var info MoleculeStatusInfo
+	if err := json.Unmarshal(out, &info); err != nil {
+		return nil, fmt.Errorf("parsing gt mol status output: %w", err)
+	}
+	if !info.HasWork {
+		return nil, nil
+	}
+
+	mol := &MoleculeStatus{MoleculeID: info.AttachedMolecule}
+	if len(info.AttachedMolecules) > 0 {
+		mol.MoleculeID, mol.MoleculeIDs = info.AttachedMolecules[0], info.AttachedMolecules
+	}
+	if info.PinnedBead != nil {
+		if mol.MoleculeID == "" {
+			mol.MoleculeID = info.PinnedBead.ID
+		}
+		mol.Title = info.PinnedBead.Title
+		mol.Status = info.PinnedBead.Status
+	}
+	return mol, nil
+}
+
+// gtExecutable returns the path of the running gt binary, so subcommands
+// run the same version, or "gt" from PATH if it can't be determined.
+func gtExecutable() string {
+	if exe, err := os.Executable(); err == nil {
+		return exe
+	}
+	return "gt"
+}
+
+// appendTrailers adds trailers to the git commit args using git's --trailer
+// flag, so they land in a properly formatted trailer block regardless of
+// whether the message comes from -m, -F, or the editor.
+func appendTrailers(gitArgs, trailers []string) []string {
+	return insertBeforePathspec(gitArgs, git.TrailerArgs(trailers)...)
+}
+
+// insertBeforePathspec inserts args before a "--" separator if present, so
+// they are treated as flags rather than pathspecs.
+func insertBeforePathspec(gitArgs []string, args ...string) []string {
+	for i, arg := range gitArgs {
+		if arg == "--" {
+			result := append([]string{}, gitArgs[:i]...)
+			result = append(result, args...)
+			return append(result, gitArgs[i:]...)
+		}
+	}
+	return append(gitArgs, args...)
+}
+
+// runCommitCheck implements --check. It assembles the exact message the
+// commit would get (the -m paragraphs plus trailers, via git
+// interpret-trailers) and feeds it to `git commit --dry-run -F -` with the
+// remaining args, so git reports what would be committed (or why it can't
+// be, e.g. an empty index) without creating a commit.
+func runCommitCheck(gitArgs, trailers []string, name, email string) error {
+	indexes := messageArgIndexes(gitArgs)
+	if len(indexes) == 0 {
+		return fmt.Errorf("--check needs the message given with -m")
+	}
+
+	paragraphs, rest := splitMessageArgs(gitArgs)
+
+	interpretArgs := append([]string{"interpret-trailers"}, git.TrailerArgs(trailers)...)
+	interpret := exec.Command(git.Binary(), interpretArgs...)
+	interpret.Stdin = strings.NewReader(strings.Join(paragraphs, "\n\n") + "\n")
+	message, err := interpret.Output()
+	if err != nil {
+		return fmt.Errorf("applying trailers: %w", err)
+	}
+
+	fmt.Printf("%s\n\n%s\n", style.Bold.Render("Commit message:"), strings.TrimRight(string(message), "\n"))
+	if signing := describeSigning(rest); signing != "" {
+		fmt.Printf("\n%s %s\n", style.Bold.Render("Signing:"), signing)
+	}
+
+	// When the commit takes exactly the index, preview it from the index
+	// itself: git's dry-run output mixes in unstaged and untracked files
+	var staged []git.FileChange
+	if commitsIndexOnly(rest) {
+		if cwd, err := os.Getwd(); err == nil {
+			staged, _ = git.NewGit(cwd).StagedFiles()
+		}
+	}
+	if staged != nil {
+		fmt.Printf("\n%s\n\n", style.Bold.Render("Staged changes:"))
+		for _, c := range staged {
+			path := c.Path
+			if c.OldPath != "" {
+				path = c.OldPath + " → " + c.Path
+			}
+			fmt.Printf("  %-12s %s\n", c.Type, path)
+		}
+		if len(staged) == 0 {
+			fmt.Printf("  %s\n", style.Dim.Render("(nothing staged)"))
+		}
+	} else {
+		fmt.Printf("%s\n\n", style.Bold.Render("Git dry run:"))
+	}
 
-	return runGitCommit(args, name, email)
+	var checkArgs []string
+	if name != "" && email != "" {
+		checkArgs = append(checkArgs, "-c", "user.name="+name, "-c", "user.email="+email)
+	}
+	checkArgs = append(checkArgs, "commit", "--dry-run", "-F", "-")
+	checkArgs = append(checkArgs, rest...)
+
+	gitCmd := exec.Command(git.Binary(), checkArgs...)
+	gitCmd.Stdin = strings.NewReader(string(message))
+	var dryRun bytes.Buffer
+	gitCmd.Stdout = os.Stdout
+	if staged != nil {
+		gitCmd.Stdout = &dryRun // Only shown if the commit would fail
+	}
+	gitCmd.Stderr = os.Stderr
+	if err := gitCmd.Run(); err != nil {
+		os.Stdout.Write(dryRun.Bytes())
+		fmt.Printf("\n%s Commit would fail\n", style.ErrorPrefix)
+		return fmt.Errorf("commit check failed: %w", err)
+	}
+	fmt.Printf("\n%s Commit would succeed\n", style.SuccessPrefix)
+	return nil
+}
+
+// describeSigning says whether a commit with gitArgs would be signed, for
+// --check, whose dry run doesn't sign. Empty when it wouldn't be.
+func describeSigning(gitArgs []string) string {
+	signed, explicit := false, false
+	for _, arg := range gitArgs {
+		if arg == "--" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(arg, "-S"), arg == "--gpg-sign", strings.HasPrefix(arg, "--gpg-sign="):
+			signed, explicit = true, true
+		case arg == "--no-gpg-sign":
+			signed, explicit = false, true
+		}
+	}
+	if !explicit {
+		if cwd, err := os.Getwd(); err == nil {
+			if v, _ := git.NewGit(cwd).ConfigGet("commit.gpgsign"); v == "true" {
+				return "yes (commit.gpgsign)"
+			}
+		}
+		return ""
+	}
+	if !signed {
+		return "no (--no-gpg-sign)"
+	}
+	return "yes"
 }
 
 // identityToEmail converts a Gas Town identity to a git email address.
@@ -87,30 +1641,97 @@ func identityToEmail(identity, domain string) string {
 	return localPart + "@" + domain
 }
 
-// runGitCommit executes git commit with optional identity override.
-// If name and email are empty, runs git commit with no overrides.
-// Preserves git's exit code for proper wrapper behavior.
-func runGitCommit(args []string, name, email string) error {
-	var gitArgs []string
+// agentAuthor renders the --author-from-identity name and email for the agent
+// using the configured (or default) formats, and validates the result.
+func agentAuthor(ctx RoleContext, settings config.CommitSettings) (name, email string, err error) {
+	identity := strings.TrimSuffix(buildAgentIdentity(ctx), "/")
+	if identity == "" {
+		return "", "", fmt.Errorf("--author-from-identity: no agent identity for role %q", ctx.Role)
+	}
 
-	// If we have an identity, prepend -c flags
-	if name != "" && email != "" {
-		gitArgs = append(gitArgs, "-c", "user.name="+name)
-		gitArgs = append(gitArgs, "-c", "user.email="+email)
+	rig := ctx.Rig
+	if rig == "" {
+		rig = "town"
+	}
+	agentName := ctx.Polecat
+	if agentName == "" {
+		agentName = string(ctx.Role)
 	}
+	replacer := strings.NewReplacer(
+		"{identity}", strings.ReplaceAll(identity, "/", "-"),
+		"{rig}", rig,
+		"{role}", string(ctx.Role),
+		"{name}", agentName,
+	)
 
-	gitArgs = append(gitArgs, "commit")
-	gitArgs = append(gitArgs, args...)
+	nameFormat := settings.AuthorNameFormat
+	if nameFormat == "" {
+		nameFormat = DefaultAuthorNameFormat
+	}
+	emailFormat := settings.AuthorEmailFormat
+	if emailFormat == "" {
+		emailFormat = DefaultAuthorEmailFormat
+	}
+	name = replacer.Replace(nameFormat)
+	email = replacer.Replace(emailFormat)
 
-	gitCmd := exec.Command("git", gitArgs...)
-	gitCmd.Stdin = os.Stdin
-	gitCmd.Stdout = os.Stdout
-	gitCmd.Stderr = os.Stderr
+	if strings.TrimSpace(name) == "" || strings.ContainsAny(name, "<>\n") {
+		return "", "", fmt.Errorf("invalid author name %q from format %q", name, nameFormat)
+	}
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" || domain == "" || strings.ContainsAny(email, "<> \n") || strings.Count(email, "@") != 1 {
+		return "", "", fmt.Errorf("invalid author email %q from format %q", email, emailFormat)
+	}
+	return name, email, nil
+}
 
-	if err := gitCmd.Run(); err != nil {
+// sshSignConfig returns the inline config that signs with an SSH key. A
+// key file path is made absolute and must exist; "key::..." literals are
+// passed through.
+func sshSignConfig(key string) ([]string, error) {
+	if !strings.HasPrefix(key, "key::") {
+		abs, err := filepath.Abs(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return nil, fmt.Errorf("ssh signing key: %w", err)
+		}
+		key = abs
+	}
+	return []string{"gpg.format=ssh", "user.signingkey=" + key}, nil
+}
+
+// runGitCommit runs git commit with args and trailers through
+// git.CommitWithTrailers, attached to the terminal so git can open the
+// editor. If name and email are empty, runs git commit with no identity
+// override. config entries ("key=value") are passed as inline -c config,
+// and env entries (e.g. GIT_AUTHOR_NAME=...) are added to git's environment.
+// Preserves git's exit code for proper wrapper behavior.
+func runGitCommit(args, trailers []string, name, email string, config, env []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+
+	// If we have an identity, prepend it to the config
+	if name != "" && email != "" {
+		config = append([]string{"user.name=" + name, "user.email=" + email}, config...)
+	}
+
+	opts := git.CommitOptions{
+		Args:   args,
+		Config: config,
+		Env:    env,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+	if err := git.NewGit(cwd).CommitWithTrailers("", trailers, opts); err != nil {
 		// Preserve git's exit code for proper wrapper behavior
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			os.Exit(exitErr.ExitCode())
+		var gitErr *git.GitError
+		if errors.As(err, &gitErr) && gitErr.ExitCode > 0 {
+			os.Exit(gitErr.ExitCode)
 		}
 		return err
 	}
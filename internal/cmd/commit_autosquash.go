@@ -0,0 +1,46 @@
+package cmd
+
+import "strings"
+
+// Kinds of autosquash commit, as returned by autosquashKind.
+const (
+	autosquashFixup  = "fixup"  // --fixup REF: folded in, its message dropped
+	autosquashSquash = "squash" // --squash REF: folded in, its message appended
+	autosquashAmend  = "amend"  // --fixup=amend:REF or reword:REF: its message replaces the target's
+)
+
+// autosquashKind reports how git commit args create a commit that
+// rebase --autosquash later folds into an earlier one, or "" if they don't.
+func autosquashKind(gitArgs []string) string {
+	for i := 0; i < len(gitArgs); i++ {
+		arg := gitArgs[i]
+		if arg == "--" {
+			return ""
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		if (name == "--fixup" || name == "--squash") && !hasValue && i+1 < len(gitArgs) {
+			value = gitArgs[i+1]
+		}
+		switch name {
+		case "--fixup":
+			if strings.HasPrefix(value, "amend:") || strings.HasPrefix(value, "reword:") {
+				return autosquashAmend
+			}
+			return autosquashFixup
+		case "--squash":
+			return autosquashSquash
+		}
+		if gitCommitFlagTakesValue(arg) {
+			i++ // Skip the value so it isn't mistaken for a flag
+		}
+	}
+	return ""
+}
+
+// foldsAway reports whether an autosquash commit of kind disappears into
+// its target, keeping the target's message and trailers: fixups drop
+// their message, and squashes append theirs to the target's body, where
+// trailers would no longer be the final paragraph.
+func foldsAway(kind string) bool {
+	return kind == autosquashFixup || kind == autosquashSquash
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import "testing"
+
+func TestAutosquashKind(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-m", "msg"}, ""},
+		{[]string{"--fixup", "HEAD~2"}, autosquashFixup},
+		{[]string{"-q", "--fixup=abc123"}, autosquashFixup},
+		{[]string{"--squash", "HEAD~1", "-m", "more detail"}, autosquashSquash},
+		{[]string{"--fixup=amend:HEAD~1", "-m", "new message"}, autosquashAmend},
+		{[]string{"--fixup", "reword:HEAD~1"}, autosquashAmend},
+		{[]string{"-m", "--fixup"}, ""},               // A message, not the flag
+		{[]string{"-m", "msg", "--", "--squash"}, ""}, // A path
+	}
+	for _, tt := range tests {
+		if got := autosquashKind(tt.args); got != tt.want {
+			t.Errorf("autosquashKind(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	for kind, want := range map[string]bool{autosquashFixup: true, autosquashSquash: true, autosquashAmend: false, "": false} {
+		if got := foldsAway(kind); got != want {
+			t.Errorf("foldsAway(%q) = %v, want %v", kind, got, want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultConventionalTypes are the commit types --conventional accepts
+// unless town settings commit.conventional_types lists others.
+var DefaultConventionalTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+// conventionalSubject matches "type(scope)!: description"; the scope and
+// the breaking-change "!" are optional.
+var conventionalSubject = regexp.MustCompile(`^([A-Za-z]+)(\([^()\s]+\))?!?: \S`)
+
+// checkConventionalSubject checks the subject of a message given with -m
+// against the Conventional Commits grammar, allowing only types. It reports
+// whether there was a -m message to check; without one (e.g. the editor
+// or -F), nothing is checked.
+func checkConventionalSubject(gitArgs, types []string) (bool, error) {
+	paragraphs, _ := splitMessageArgs(gitArgs)
+	if len(paragraphs) == 0 {
+		return false, nil
+	}
+	if len(types) == 0 {
+		types = DefaultConventionalTypes
+	}
+
+	subject, _, _ := strings.Cut(strings.TrimSpace(paragraphs[0]), "\n")
+	m := conventionalSubject.FindStringSubmatch(subject)
+	switch {
+	case m == nil:
+		return true, fmt.Errorf("subject %q is not a conventional commit: want \"type(scope): description\", e.g. \"fix(parser): handle empty input\", with type one of %s",
+			subject, strings.Join(types, ", "))
+	case !slices.Contains(types, m[1]):
+		return true, fmt.Errorf("subject %q has type %q, which is not allowed: use one of %s", subject, m[1], strings.Join(types, ", "))
+	}
+	return true, nil
+}
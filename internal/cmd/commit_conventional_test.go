@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckConventionalSubject(t *testing.T) {
+	tests := []struct {
+		name    string
+		gitArgs []string
+		types   []string
+		wantErr string // Substring of the error, or "" for none
+	}{
+		{"type", []string{"-m", "feat: add status command"}, nil, ""},
+		{"scope", []string{"-a", "-m", "fix(git): handle empty output"}, nil, ""},
+		{"breaking", []string{"-m", "refactor(cmd)!: drop --legacy\n\nBody text.", "-m", "More body"}, nil, ""},
+		{"no type", []string{"-m", "Fix the parser"}, nil, `with type one of feat, fix,`},
+		{"no space", []string{"-m", "fix:handle it"}, nil, "not a conventional commit"},
+		{"empty scope", []string{"-m", "fix(): handle it"}, nil, "not a conventional commit"},
+		{"unknown type", []string{"-m", "feature: add it"}, nil, `type "feature", which is not allowed`},
+		{"configured types", []string{"-m", "chore: tidy"}, []string{"feat", "fix"}, "use one of feat, fix"},
+		{"configured type allowed", []string{"-m", "hotfix: patch prod"}, []string{"hotfix"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checked, err := checkConventionalSubject(tt.gitArgs, tt.types)
+			if !checked {
+				t.Error("checked = false for a -m message")
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Messages from the editor or -F aren't seen
+	if checked, err := checkConventionalSubject([]string{"-F", "msg.txt"}, nil); checked || err != nil {
+		t.Errorf("checkConventionalSubject(-F) = %v, %v; want false, nil", checked, err)
+	}
+}
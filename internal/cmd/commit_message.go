@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+// DefaultCommitWrapWidth is the default subject limit and body wrap width
+// used by --autoformat.
+const DefaultCommitWrapWidth = 72
+
+// CommitTemplatePath is the repo-relative path of the gastown commit
+// template. It takes precedence over git's commit.template, which may be a
+// personal (global) setting rather than the repo's convention.
+const CommitTemplatePath = ".gastown/commit-template.txt"
+
+// normalizeMessageArg rewrites attached message values ("--message=msg",
+// "-mmsg", "-amsg") into the separate "-m", "msg" form so messageArgIndexes
+// can find them. Other args are returned unchanged.
+func normalizeMessageArg(arg string) []string {
+	if value, ok := strings.CutPrefix(arg, "--message="); ok {
+		return []string{"-m", value}
+	}
+	if len(arg) < 3 || arg[0] != '-' || arg[1] == '-' {
+		return []string{arg}
+	}
+	// In a short bundle the first value-taking flag consumes the rest
+	for j := 1; j < len(arg); j++ {
+		if !strings.ContainsRune("mFCct", rune(arg[j])) {
+			continue
+		}
+		if arg[j] != 'm' || j == len(arg)-1 {
+			return []string{arg}
+		}
+		if j == 1 {
+			return []string{"-m", arg[2:]}
+		}
+		return []string{arg[:j], "-m", arg[j+1:]}
+	}
+	return []string{arg}
+}
+
+// messageArgIndexes returns the indexes of -m/--message values in normalized
+// git commit args. Git joins multiple -m values as separate paragraphs.
+func messageArgIndexes(gitArgs []string) []int {
+	var indexes []int
+	for i := 0; i < len(gitArgs); i++ {
+		arg := gitArgs[i]
+		if arg == "--" {
+			break
+		}
+		if !gitCommitFlagTakesValue(arg) {
+			continue
+		}
+		if arg == "--message" || (arg[1] != '-' && arg[len(arg)-1] == 'm') {
+			indexes = append(indexes, i+1)
+		}
+		i++ // Skip the value so it isn't mistaken for a flag
+	}
+	return indexes
+}
+
+// autoformatMessage turns sloppy single-line messages into well-formed git
+// messages: the first sentence (or as many words as fit in width) becomes
+// the subject, followed by a blank line and the rest wrapped at width.
+// Messages that already have a short subject followed by a blank line (or
+// nothing) are returned unchanged, as are paragraphs after the first.
+func autoformatMessage(raw string, width int) string {
+	if width <= 0 {
+		width = DefaultCommitWrapWidth
+	}
+
+	message := strings.TrimSpace(raw)
+	lines := strings.Split(message, "\n")
+	if len(lines[0]) <= width && (len(lines) == 1 || strings.TrimSpace(lines[1]) == "") {
+		return raw
+	}
+
+	// The first paragraph gets reflowed; later paragraphs are kept as-is
+	firstParagraph, rest, _ := strings.Cut(message, "\n\n")
+	text := strings.Join(strings.Fields(firstParagraph), " ")
+
+	subject, remainder := splitSubject(text, width)
+
+	var b strings.Builder
+	b.WriteString(subject)
+	if remainder != "" {
+		b.WriteString("\n\n")
+		b.WriteString(wrapText(remainder, width))
+	}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		b.WriteString("\n\n")
+		b.WriteString(rest)
+	}
+	return b.String()
+}
+
+// splitSubject takes the first sentence of text as the subject if it fits in
+// width, otherwise as many whole words as fit. A trailing period is dropped
+// from the subject, per git convention.
+func splitSubject(text string, width int) (subject, remainder string) {
+	for i := 0; i < len(text) && i < width; i++ {
+		if strings.ContainsRune(".!?", rune(text[i])) && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSuffix(text[:i+1], "."), strings.TrimSpace(text[i+1:])
+		}
+	}
+	if len(text) <= width {
+		return text, ""
+	}
+
+	cut := strings.LastIndex(text[:width+1], " ")
+	if cut <= 0 {
+		cut = width // A single overlong word: hard cut
+	}
+	return strings.TrimSpace(text[:cut]), strings.TrimSpace(text[cut:])
+}
+
+// wrapText greedily wraps words into lines of at most width characters.
+// Words longer than width are placed on a line of their own.
+func wrapText(text string, width int) string {
+	var lines []string
+	var line strings.Builder
+	for _, word := range strings.Fields(text) {
+		if line.Len() > 0 && line.Len()+1+len(word) > width {
+			lines = append(lines, line.String())
+			line.Reset()
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(word)
+	}
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// loadCommitTemplate returns the commit template for the repo, with comment
+// lines stripped: .gastown/commit-template.txt at the repo root, else the
+// file named by commit.template (relative paths are taken from the repo
+// root). Returns "" if there is no template or it is only comments.
+func loadCommitTemplate(g *git.Git) (string, error) {
+	root, err := g.RepoRoot()
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(root, CommitTemplatePath)
+	if _, err := os.Stat(path); err != nil {
+		if path, err = g.ConfigGetPath("commit.template"); err != nil || path == "" {
+			return "", err
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading commit template: %w", err)
+	}
+	commentChar, _ := g.ConfigGet("core.commentChar")
+	if len(commentChar) != 1 {
+		commentChar = "#" // Unset or "auto"
+	}
+	return stripCommentLines(string(data), commentChar), nil
+}
+
+// templateArgs replaces the message seeded from the commit template in
+// gitArgs with a -t file holding it and trailers, so git aborts the commit
+// if the message is left unedited, as it does for commit.template. Passing
+// the message with -m, or the trailers with --trailer, would count as an
+// edit. The returned cleanup removes the file.
+func templateArgs(gitArgs, trailers []string) ([]string, func(), error) {
+	paragraphs, rest := splitMessageArgs(gitArgs)
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting current directory: %w", err)
+	}
+	batch := make([]git.Trailer, len(trailers))
+	for i, t := range trailers {
+		key, value, _ := strings.Cut(t, ":")
+		batch[i] = git.Trailer{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)}
+	}
+	message, err := git.NewGit(cwd).ApplyTrailersBatch(strings.Join(paragraphs, "\n\n")+"\n", batch)
+	if err != nil {
+		return nil, nil, fmt.Errorf("applying trailers: %w", err)
+	}
+
+	f, err := os.CreateTemp("", "gt-commit-template-*.txt")
+	if err != nil {
+		return nil, nil, fmt.Errorf("writing commit template: %w", err)
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	_, err = f.WriteString(message)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("writing commit template: %w", err)
+	}
+	return append([]string{"-t", f.Name()}, rest...), cleanup, nil
+}
+
+// stripCommentLines removes lines starting with commentChar, as git does
+// when cleaning up a message, and trims surrounding blank lines.
+func stripCommentLines(text, commentChar string) string {
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(line, commentChar) {
+			kept = append(kept, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return strings.Trim(strings.Join(kept, "\n"), "\n")
+}
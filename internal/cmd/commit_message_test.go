@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+func TestNormalizeMessageArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want []string
+	}{
+		{"-m", []string{"-m"}},
+		{"-am", []string{"-am"}},
+		{"-mFix bug", []string{"-m", "Fix bug"}},
+		{"-amFix bug", []string{"-a", "-m", "Fix bug"}},
+		{"--message=Fix bug", []string{"-m", "Fix bug"}},
+		{"-Fmsg.txt", []string{"-Fmsg.txt"}},
+		{"--amend", []string{"--amend"}},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeMessageArg(tt.arg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("normalizeMessageArg(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestMessageArgIndexes(t *testing.T) {
+	args := []string{"-a", "-m", "subject", "--author", "-m", "--message", "body", "--", "-m"}
+	want := []int{2, 6}
+	if got := messageArgIndexes(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("messageArgIndexes = %v, want %v", got, want)
+	}
+}
+
+func TestAutoformatMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		width int
+		want  string
+	}{
+		{
+			name:  "short subject unchanged",
+			raw:   "Fix the bug",
+			width: 72,
+			want:  "Fix the bug",
+		},
+		{
+			name:  "well-formed message unchanged",
+			raw:   "Fix the bug\n\nThe body explains why.",
+			width: 72,
+			want:  "Fix the bug\n\nThe body explains why.",
+		},
+		{
+			name:  "first sentence becomes subject",
+			raw:   "Fix the parser crash. It dereferenced a nil token when the input ended early and nothing checked.",
+			width: 40,
+			want:  "Fix the parser crash\n\nIt dereferenced a nil token when the\ninput ended early and nothing checked.",
+		},
+		{
+			name:  "no sentence break splits at word boundary",
+			raw:   "update the config loader so that it handles missing files gracefully",
+			width: 30,
+			want:  "update the config loader so\n\nthat it handles missing files\ngracefully",
+		},
+		{
+			name:  "multi-line first paragraph is reflowed, later paragraphs kept",
+			raw:   "Fix the bug.\nIt was bad.\n\n- keep\n- this",
+			width: 72,
+			want:  "Fix the bug\n\nIt was bad.\n\n- keep\n- this",
+		},
+		{
+			name:  "single overlong word is hard cut",
+			raw:   "abcdefghij",
+			width: 4,
+			want:  "abcd\n\nefghij",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := autoformatMessage(tt.raw, tt.width); got != tt.want {
+				t.Errorf("autoformatMessage() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripCommentLines(t *testing.T) {
+	text := "# Summary (50 chars)\n\nWhy:  \n# Explain the change\n\nTested: \n\n"
+	if got, want := stripCommentLines(text, "#"), "Why:\n\nTested:"; got != want {
+		t.Errorf("stripCommentLines = %q, want %q", got, want)
+	}
+	if got := stripCommentLines("# only comments\n", "#"); got != "" {
+		t.Errorf("comment-only template = %q, want empty", got)
+	}
+}
+
+func TestLoadCommitTemplate(t *testing.T) {
+	dir := initCommitTestRepo(t)
+	g := git.NewGit(dir)
+
+	if got, err := loadCommitTemplate(g); err != nil || got != "" {
+		t.Fatalf("no template: got %q, %v", got, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "git-template.txt"), []byte("Git template\n# comment\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitIn(t, dir, "config", "commit.template", "git-template.txt")
+	if got, err := loadCommitTemplate(g); err != nil || got != "Git template" {
+		t.Errorf("commit.template: got %q, %v", got, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".gastown"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	gastownTemplate := "Subject\n\n; Describe the change\nBody: why\n"
+	if err := os.WriteFile(filepath.Join(dir, CommitTemplatePath), []byte(gastownTemplate), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitIn(t, dir, "config", "core.commentChar", ";")
+	if got, err := loadCommitTemplate(g); err != nil || got != "Subject\n\nBody: why" {
+		t.Errorf("gastown template should take precedence: got %q, %v", got, err)
+	}
+}
+
+func TestCommitTemplateBeforeTrailers(t *testing.T) {
+	dir := initCommitTestRepo(t)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".gastown"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	template := "Subject line\n\n# What changed and why\nBody text\n"
+	if err := os.WriteFile(filepath.Join(dir, CommitTemplatePath), []byte(template), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	message, err := loadCommitTemplate(git.NewGit(dir))
+	if err != nil {
+		t.Fatalf("loadCommitTemplate: %v", err)
+	}
+	trailers := []string{"Executed-By: gastown/crew/jack"}
+	args, cleanup, err := templateArgs([]string{"-e", "-m", message, "--allow-empty", "-q"}, trailers)
+	if err != nil {
+		t.Fatalf("templateArgs: %v", err)
+	}
+	defer cleanup()
+
+	// Like commit.template, an unedited message aborts the commit
+	unedited := exec.Command("git", append([]string{"commit"}, args...)...)
+	unedited.Env = append(os.Environ(), "GIT_EDITOR=true")
+	if out, err := unedited.CombinedOutput(); err == nil || !strings.Contains(string(out), "did not edit") {
+		t.Fatalf("unedited template commit = %v\n%s, want it aborted", err, out)
+	}
+
+	editor := "GIT_EDITOR=sed -i -e 's/Body text/Edited body/'"
+	if err := runGitCommit(args, nil, "", "", nil, []string{editor}); err != nil {
+		t.Fatalf("runGitCommit: %v", err)
+	}
+
+	out, err := exec.Command("git", "log", "-1", "--format=%B").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	want := "Subject line\n\nEdited body\n\nExecuted-By: gastown/crew/jack"
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/doctor"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// preflightResult is the outcome of one gt commit --preflight-only check.
+type preflightResult struct {
+	Name    string
+	Status  doctor.CheckStatus
+	Message string
+}
+
+// runCommitPreflight implements --preflight-only: it reports each check
+// and returns an error if any failed. Warnings don't fail the preflight.
+func runCommitPreflight() error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+
+	failed := 0
+	for _, r := range commitPreflightChecks(cwd) {
+		prefix := style.SuccessPrefix
+		switch r.Status {
+		case doctor.StatusWarning:
+			prefix = style.WarningPrefix
+		case doctor.StatusError:
+			prefix = style.ErrorPrefix
+			failed++
+		}
+		fmt.Printf("%s %s: %s\n", prefix, style.Bold.Render(r.Name), r.Message)
+	}
+	if failed > 0 {
+		return fmt.Errorf("preflight failed: %d check(s) failed", failed)
+	}
+	return nil
+}
+
+// commitPreflightChecks checks whether gt commit can run as expected in dir.
+// Repository checks are skipped when git or the repository is missing.
+func commitPreflightChecks(dir string) []preflightResult {
+	var results []preflightResult
+	add := func(name string, status doctor.CheckStatus, format string, args ...interface{}) {
+		results = append(results, preflightResult{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if err := git.CheckGit(); err != nil {
+		add("git", doctor.StatusError, "%v", err)
+		return results
+	}
+	version, _ := git.Version()
+	add("git", doctor.StatusOK, "version %s", version)
+
+	g := git.NewGit(dir)
+	root, err := g.RepoRoot()
+	if err != nil {
+		add("repository", doctor.StatusError, "not inside a git work tree")
+		return results
+	}
+	add("repository", doctor.StatusOK, "%s", root)
+
+	op, err := g.InProgressOperation()
+	switch {
+	case err != nil:
+		add("operation", doctor.StatusWarning, "cannot check for an operation in progress: %v", err)
+	case op != "":
+		add("operation", doctor.StatusError, "a %s is in progress; finish it (gt resolve --continue) or abort it first", op)
+	default:
+		add("operation", doctor.StatusOK, "no merge, rebase, cherry-pick or revert in progress")
+	}
+
+	branch, err := g.CurrentBranch()
+	switch {
+	case errors.Is(err, git.ErrDetachedHead):
+		add("HEAD", doctor.StatusWarning, "detached; commits won't be on any branch")
+	case err != nil:
+		add("HEAD", doctor.StatusWarning, "no commits yet")
+	default:
+		add("HEAD", doctor.StatusOK, "on branch %s", branch)
+	}
+
+	identity := commitIdentity(&commitContext{})
+	switch {
+	case identity == "overseer" && os.Getenv("GT_ROLE") != "":
+		add("identity", doctor.StatusError, "GT_ROLE=%s is set but no agent identity could be resolved; commits would pass through as the overseer", os.Getenv("GT_ROLE"))
+	case identity == "overseer":
+		add("identity", doctor.StatusOK, "overseer (human); commits pass through to git unchanged")
+	default:
+		domain, _ := loadCommitSettings()
+		add("identity", doctor.StatusOK, "%s <%s>", identity, identityToEmail(identity, domain))
+		results = append(results, checkMoleculeLookup())
+	}
+
+	if branch != "" {
+		untracked, err := g.BranchesWithoutUpstream()
+		switch {
+		case err != nil:
+			add("upstream", doctor.StatusWarning, "cannot read upstream config: %v", err)
+		case slices.Contains(untracked, branch):
+			add("upstream", doctor.StatusWarning, "%s has no upstream; set one with 'git push -u'", branch)
+		default:
+			add("upstream", doctor.StatusOK, "%s tracks an upstream branch", branch)
+		}
+	}
+	return results
+}
+
+// checkMoleculeLookup checks that the pinned work, recorded in the Molecule
+// trailer, can be read. Commits never fail on it, so a failure is a warning.
+func checkMoleculeLookup() preflightResult {
+	mol, err := lookupPinnedMolecule()
+	switch {
+	case err != nil:
+		return preflightResult{"molecule", doctor.StatusWarning, fmt.Sprintf("lookup failed, so commits get no Molecule trailer: %v", err)}
+	case len(mol.IDs()) == 0:
+		return preflightResult{"molecule", doctor.StatusOK, "nothing pinned; commits get no Molecule trailer"}
+	default:
+		return preflightResult{"molecule", doctor.StatusOK, "pinned " + strings.Join(mol.IDs(), ", ")}
+	}
+}
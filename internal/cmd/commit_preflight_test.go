@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/doctor"
+)
+
+// preflightStatuses maps each check name to its status.
+func preflightStatuses(results []preflightResult) map[string]doctor.CheckStatus {
+	statuses := make(map[string]doctor.CheckStatus)
+	for _, r := range results {
+		statuses[r.Name] = r.Status
+	}
+	return statuses
+}
+
+func TestCommitPreflightChecks(t *testing.T) {
+	t.Setenv("GT_ROLE", "")
+
+	statuses := preflightStatuses(commitPreflightChecks(t.TempDir()))
+	if statuses["repository"] != doctor.StatusError {
+		t.Errorf("outside a repo: repository = %v, want Error", statuses["repository"])
+	}
+	if _, ok := statuses["HEAD"]; ok {
+		t.Error("repository checks ran outside a repo")
+	}
+
+	dir := initCommitTestRepo(t)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte(content), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	write("base\n")
+	runGitIn(t, dir, "add", "file.txt")
+	runGitIn(t, dir, "commit", "-q", "-m", "base")
+
+	statuses = preflightStatuses(commitPreflightChecks(dir))
+	want := map[string]doctor.CheckStatus{
+		"git":        doctor.StatusOK,
+		"repository": doctor.StatusOK,
+		"operation":  doctor.StatusOK,
+		"HEAD":       doctor.StatusOK,
+		"identity":   doctor.StatusOK,
+		"upstream":   doctor.StatusWarning, // No remote configured
+	}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Errorf("clean repo: %s = %v, want %v", name, statuses[name], status)
+		}
+	}
+
+	runGitIn(t, dir, "checkout", "-q", "--detach")
+	if statuses = preflightStatuses(commitPreflightChecks(dir)); statuses["HEAD"] != doctor.StatusWarning {
+		t.Errorf("detached HEAD: HEAD = %v, want Warning", statuses["HEAD"])
+	}
+
+	// Conflicting edits on two branches leave a merge in progress
+	runGitIn(t, dir, "checkout", "-q", "-b", "other")
+	write("other\n")
+	runGitIn(t, dir, "commit", "-q", "-am", "other")
+	runGitIn(t, dir, "checkout", "-q", "-b", "mine", "HEAD~1")
+	write("mine\n")
+	runGitIn(t, dir, "commit", "-q", "-am", "mine")
+	cmd := exec.Command("git", "merge", "other")
+	cmd.Dir = dir
+	if err := cmd.Run(); err == nil {
+		t.Fatal("expected a merge conflict")
+	}
+	if statuses = preflightStatuses(commitPreflightChecks(dir)); statuses["operation"] != doctor.StatusError {
+		t.Errorf("mid-merge: operation = %v, want Error", statuses["operation"])
+	}
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+// secretAllowMarkers suppress secret findings on the line that contains
+// them, e.g. in a trailing comment.
+var secretAllowMarkers = []string{"gitleaks:allow", "gt:allow-secret"}
+
+// secretRule is a known secret format.
+type secretRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// secretRules are the known secret formats checked by --scan-secrets.
+var secretRules = []secretRule{
+	{"AWS access key ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED |PGP )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[baprs]-[A-Za-z0-9-]{10,}`)},
+	{"Stripe secret key", regexp.MustCompile(`\b[sr]k_live_[0-9A-Za-z]{24,}\b`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+}
+
+// highEntropyCandidate matches strings that could be random tokens.
+var highEntropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/=_-]{32,}`)
+
+// minSecretEntropy is the Shannon entropy, in bits per character, above
+// which a candidate is reported. Random base64 scores around 5; hex (at
+// most 4) and identifiers score lower.
+const minSecretEntropy = 4.5
+
+// SecretFinding is a likely secret in an added line of a diff.
+type SecretFinding struct {
+	File  string
+	Line  int    // Line number in the new version of the file
+	Rule  string // Which detector matched
+	Match string // The matched text, redacted
+}
+
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", f.File, f.Line, f.Rule, f.Match)
+}
+
+// scanDiffForSecrets scans the added lines of a unified diff for known
+// secret formats and high-entropy strings. Lines with an allow marker, and
+// matches (or files) matching an allowlist pattern, are skipped.
+func scanDiffForSecrets(diff string, allowlist []*regexp.Regexp) []SecretFinding {
+	var findings []SecretFinding
+	file, line := "", 0
+	for _, text := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+			continue
+		case strings.HasPrefix(text, "@@ "):
+			line = hunkNewStart(text)
+			continue
+		case strings.HasPrefix(text, "+"):
+		case strings.HasPrefix(text, " "):
+			line++
+			continue
+		default:
+			continue // Removed lines and diff headers
+		}
+
+		added := text[1:]
+		if file != "" && !secretAllowed(added, file, allowlist) {
+			findings = append(findings, scanLineForSecrets(file, line, added, allowlist)...)
+		}
+		line++
+	}
+	return findings
+}
+
+// hunkNewStart returns the new-file start line of a "@@ -a,b +c,d @@" header.
+func hunkNewStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0
+	}
+	start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	n, _ := strconv.Atoi(start)
+	return n
+}
+
+// secretAllowed reports whether a line is exempt: it has an allow marker,
+// or its file matches the allowlist.
+func secretAllowed(text, file string, allowlist []*regexp.Regexp) bool {
+	for _, marker := range secretAllowMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	for _, re := range allowlist {
+		if re.MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanLineForSecrets returns the secrets found in one added line.
+func scanLineForSecrets(file string, line int, text string, allowlist []*regexp.Regexp) []SecretFinding {
+	var findings []SecretFinding
+	allowed := func(match string) bool {
+		for _, re := range allowlist {
+			if re.MatchString(match) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, rule := range secretRules {
+		for _, match := range rule.pattern.FindAllString(text, -1) {
+			if !allowed(match) {
+				findings = append(findings, SecretFinding{File: file, Line: line, Rule: rule.name, Match: redactSecret(match)})
+			}
+		}
+	}
+	if len(findings) > 0 {
+		return findings // Don't report a known secret twice
+	}
+
+	for _, match := range highEntropyCandidate.FindAllString(text, -1) {
+		if isHighEntropy(match) && !allowed(match) {
+			findings = append(findings, SecretFinding{File: file, Line: line, Rule: "high-entropy string", Match: redactSecret(match)})
+		}
+	}
+	return findings
+}
+
+// isHighEntropy reports whether s looks like a random token: mixed letters
+// and digits with high Shannon entropy per character.
+func isHighEntropy(s string) bool {
+	if !strings.ContainsAny(s, "0123456789") || strings.ToLower(s) == s || strings.ToUpper(s) == s {
+		return false // Random base64 mixes digits and both cases
+	}
+	return shannonEntropy(s) >= minSecretEntropy
+}
+
+// shannonEntropy returns the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	var entropy float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// redactSecret keeps only the first few characters of a secret, enough to
+// find it without repeating it in logs.
+func redactSecret(s string) string {
+	if len(s) <= 8 {
+		return "****"
+	}
+	return s[:4] + "…" + fmt.Sprintf("(%d chars)", len(s))
+}
+
+// checkStagedSecrets scans the staged diff for secrets and returns an error
+// listing them. allowlist holds regexps matched against each finding's
+// text and file path. Only the index is scanned, so files that -a stages at
+// commit time are not seen.
+func checkStagedSecrets(allowlist []string) error {
+	var patterns []*regexp.Regexp
+	for _, expr := range allowlist {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid commit.secret_allowlist pattern %q: %w", expr, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+	diff, err := git.NewGit(cwd).StagedDiff(git.DiffOptions{})
+	if err != nil {
+		return fmt.Errorf("reading staged diff: %w", err)
+	}
+
+	findings := scanDiffForSecrets(diff, patterns)
+	if len(findings) == 0 {
+		return nil
+	}
+	lines := make([]string, len(findings))
+	for i, f := range findings {
+		lines[i] = "  " + f.String()
+	}
+	return fmt.Errorf("possible secrets in staged changes (unstage them, or mark false positives with a \"gitleaks:allow\" comment or commit.secret_allowlist):\n%s",
+		strings.Join(lines, "\n"))
+}
@@ -1,6 +1,23 @@
 package cmd
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/config"
+	"github.com/steveyegge/gastown/internal/doctor"
+	"github.com/steveyegge/gastown/internal/git"
+)
 
 func TestIdentityToEmail(t *testing.T) {
 	tests := []struct {
@@ -69,3 +86,1032 @@ func TestIdentityToEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCommitArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantOpts    commitOptions
+		wantGitArgs []string
+	}{
+		{
+			name:        "passthrough",
+			args:        []string{"-am", "Quick fix"},
+			wantGitArgs: []string{"-am", "Quick fix"},
+		},
+		{
+			name:        "gt flags are removed",
+			args:        []string{"--no-trailers", "-m", "msg", "--molecule-status", "--branch-trailer", "--version-trailer", "--keep-date", "--preflight-only", "--no-binary", "--scan-secrets", "--ticket-prefix", "--max-message-bytes", "1024", "--truncate-message", "--ssh-sign-key", "id_ed25519", "--agent-author", "--conventional", "--timestamp-trailer"},
+			wantOpts:    commitOptions{noTrailers: true, moleculeStatus: true, branchTrailer: true, versionTrailer: true, keepDate: true, preflightOnly: true, noBinary: true, scanSecrets: true, ticketTrailer: true, ticketPrefix: true, maxMessageBytes: 1024, truncateMessage: true, sshSignKey: "id_ed25519", agentAuthor: true, conventional: true, timestampTrailer: true},
+			wantGitArgs: []string{"-m", "msg"},
+		},
+		{
+			name:        "message value is not interpreted",
+			args:        []string{"-m", "--no-trailers"},
+			wantGitArgs: []string{"-m", "--no-trailers"},
+		},
+		{
+			name:        "value flag with separate and inline values",
+			args:        []string{"--seed-from-molecule", "--subject-format", "{title}", "-a"},
+			wantOpts:    commitOptions{seedFromMolecule: true, subjectFormat: "{title}"},
+			wantGitArgs: []string{"-a"},
+		},
+		{
+			name:     "inline value",
+			args:     []string{"--subject-format={id} {title}"},
+			wantOpts: commitOptions{subjectFormat: "{id} {title}"},
+		},
+		{
+			name:        "custom trailers",
+			args:        []string{"--trailer", "Ticket=JIRA-123", "-m", "msg", "--trailer=Reviewed-By= alice "},
+			wantOpts:    commitOptions{trailers: []string{"Ticket: JIRA-123", "Reviewed-By: alice"}},
+			wantGitArgs: []string{"-m", "msg"},
+		},
+		{
+			name:        "co-authors",
+			args:        []string{"--co-author", "Ada Lovelace <ada@example.com>", "-m", "msg", "--co-author=gastown/polecats/toast"},
+			wantOpts:    commitOptions{coAuthors: []string{"Ada Lovelace <ada@example.com>", "gastown/polecats/toast"}},
+			wantGitArgs: []string{"-m", "msg"},
+		},
+		{
+			name:        "signing",
+			args:        []string{"-S", "--amend", "--sign", "-Skeyid", "--no-edit"},
+			wantOpts:    commitOptions{sign: true},
+			wantGitArgs: []string{"--amend", "-Skeyid", "--no-edit"},
+		},
+		{
+			name:        "autoformat with width",
+			args:        []string{"--autoformat", "--autoformat-width=50", "-amQuick fix"},
+			wantOpts:    commitOptions{autoformat: true, autoformatWidth: 50},
+			wantGitArgs: []string{"-a", "-m", "Quick fix"},
+		},
+		{
+			name:        "args after separator pass through",
+			args:        []string{"-m", "msg", "--", "--molecule-status"},
+			wantGitArgs: []string{"-m", "msg", "--", "--molecule-status"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, gitArgs, err := parseCommitArgs(tt.args)
+			if err != nil {
+				t.Fatalf("parseCommitArgs: %v", err)
+			}
+			if !reflect.DeepEqual(opts, tt.wantOpts) {
+				t.Errorf("opts = %+v, want %+v", opts, tt.wantOpts)
+			}
+			if !reflect.DeepEqual(gitArgs, tt.wantGitArgs) {
+				t.Errorf("gitArgs = %q, want %q", gitArgs, tt.wantGitArgs)
+			}
+		})
+	}
+}
+
+func TestAppendTrailers(t *testing.T) {
+	trailers := []string{"Executed-By: gastown/crew/jack", "Rig: gastown"}
+
+	got := appendTrailers([]string{"-m", "msg"}, trailers)
+	want := []string{"-m", "msg", "--trailer", "Executed-By: gastown/crew/jack", "--trailer", "Rig: gastown"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("appendTrailers = %q, want %q", got, want)
+	}
+
+	// Trailers must stay ahead of a pathspec separator
+	got = appendTrailers([]string{"-m", "msg", "--", "file.go"}, trailers[:1])
+	want = []string{"-m", "msg", "--trailer", "Executed-By: gastown/crew/jack", "--", "file.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("appendTrailers with pathspec = %q, want %q", got, want)
+	}
+}
+
+func TestCoAuthorTrailers(t *testing.T) {
+	got := coAuthorTrailers([]string{
+		"gastown/polecats/toast/",
+		"Ada Lovelace <ada@example.com>",
+		"gastown/polecats/toast",
+	}, "agents.example")
+	want := []string{
+		"Co-authored-by: gastown/polecats/toast <gastown.polecats.toast@agents.example>",
+		"Co-authored-by: Ada Lovelace <ada@example.com>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("coAuthorTrailers = %q, want %q", got, want)
+	}
+
+	// Amending keeps distinct co-authors rather than replacing them
+	config := amendTrailerConfig(want)
+	if wantConfig := []string{"trailer.Co-authored-by.ifexists=addIfDifferent"}; !reflect.DeepEqual(config, wantConfig) {
+		t.Errorf("amendTrailerConfig = %v, want %v", config, wantConfig)
+	}
+}
+
+func TestParseCommitArgs_MissingValue(t *testing.T) {
+	if _, _, err := parseCommitArgs([]string{"--subject-format"}); err == nil {
+		t.Error("expected error for flag without value")
+	}
+	if _, _, err := parseCommitArgs([]string{"--autoformat-width", "wide"}); err == nil {
+		t.Error("expected error for non-numeric width")
+	}
+	for _, trailer := range []string{"Reviewed-By: alice", "=alice", "Reviewed-By=", "Reviewed By=alice", "Note=a\nb"} {
+		if _, _, err := parseCommitArgs([]string{"--trailer", trailer}); err == nil {
+			t.Errorf("expected error for --trailer %q", trailer)
+		}
+	}
+	for _, coAuthor := range []string{"", "Ada Lovelace", "Ada <ada>", "<ada@example.com>", "Ada <ada@example.com"} {
+		if _, _, err := parseCommitArgs([]string{"--co-author", coAuthor}); err == nil {
+			t.Errorf("expected error for --co-author %q", coAuthor)
+		}
+	}
+	if _, _, err := parseCommitArgs([]string{"--no-sign", "-S"}); err == nil {
+		t.Error("expected error for --no-sign with -S")
+	}
+}
+
+func TestEnvAttribution(t *testing.T) {
+	t.Setenv("GT_SESSION_ID", "sess-123\n")
+
+	var attr git.Attribution
+	envAttribution(&attr)
+	trailers := git.AgentTrailers(attr)
+	want := []string{
+		fmt.Sprintf("PID: %d", os.Getppid()),
+		"Session-Id: sess-123",
+	}
+	if host, err := os.Hostname(); err == nil && host != "" {
+		want = append([]string{"Host: " + strings.Join(strings.Fields(host), " ")}, want...)
+	}
+	if !reflect.DeepEqual(trailers, want) {
+		t.Errorf("env trailers = %q, want %q", trailers, want)
+	}
+}
+
+func TestHasCommitMessageArg(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"-m", "msg"}, true},
+		{[]string{"-am", "msg"}, true},
+		{[]string{"--message=msg"}, true},
+		{[]string{"-F", "msg.txt"}, true},
+		{[]string{"-a", "--amend"}, true},
+		{[]string{"--fixup", "HEAD~1"}, true},
+		{[]string{"-a", "-v"}, false},
+		{[]string{"--author", "-m"}, false},
+		{[]string{"--", "-m"}, false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := hasCommitMessageArg(tt.args); got != tt.want {
+			t.Errorf("hasCommitMessageArg(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFormatMoleculeSubject(t *testing.T) {
+	mol := &MoleculeStatus{MoleculeID: "gt-abc12", Title: "Fix the flux capacitor"}
+
+	if got := formatMoleculeSubject("", mol); got != "gt-abc12: Fix the flux capacitor" {
+		t.Errorf("default format = %q", got)
+	}
+	if got := formatMoleculeSubject("{title} ({id})", mol); got != "Fix the flux capacitor (gt-abc12)" {
+		t.Errorf("custom format = %q", got)
+	}
+}
+
+// initCommitTestRepo creates an empty git repo with a test identity.
+func initCommitTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGitIn(t, dir, "init")
+	runGitIn(t, dir, "config", "user.email", "test@test.com")
+	runGitIn(t, dir, "config", "user.name", "Test User")
+	return dir
+}
+
+// runGitIn runs a git command in dir, failing the test on error.
+func runGitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestGenerateCommitMessage(t *testing.T) {
+	dir := initCommitTestRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "old.go"), []byte("package x\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitIn(t, dir, "add", "old.go")
+	runGitIn(t, dir, "commit", "-m", "initial")
+
+	// One staged file and one unstaged edit to a tracked file
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), []byte("package x\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitIn(t, dir, "add", "new.go")
+	if err := os.WriteFile(filepath.Join(dir, "old.go"), []byte("package y\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	// Default generator defers to git
+	if _, err := generateCommitMessage(&commitContext{}); !errors.Is(err, ErrNoMessageGenerator) {
+		t.Fatalf("default generator error = %v, want ErrNoMessageGenerator", err)
+	}
+
+	originalGenerator := MessageGenerator
+	defer func() { MessageGenerator = originalGenerator }()
+
+	var gotStaged []string
+	MessageGenerator = func(ctx RoleContext, staged []string) (string, error) {
+		gotStaged = staged
+		return "Add new.go", nil
+	}
+	message, err := generateCommitMessage(&commitContext{})
+	if err != nil {
+		t.Fatalf("generateCommitMessage: %v", err)
+	}
+	if message != "Add new.go" {
+		t.Errorf("message = %q, want %q", message, "Add new.go")
+	}
+	if !reflect.DeepEqual(gotStaged, []string{"new.go"}) {
+		t.Errorf("staged = %q, want [new.go]", gotStaged)
+	}
+
+	MessageGenerator = func(ctx RoleContext, staged []string) (string, error) {
+		return "  \n", nil
+	}
+	if _, err := generateCommitMessage(&commitContext{}); err == nil {
+		t.Error("expected error for empty generated message")
+	}
+}
+
+// stubMoleculeLookup replaces MoleculeLookup for the rest of the test.
+func stubMoleculeLookup(t *testing.T, mol *MoleculeStatus, err error) {
+	t.Helper()
+	orig := MoleculeLookup
+	t.Cleanup(func() { MoleculeLookup = orig })
+	MoleculeLookup = func() (*MoleculeStatus, error) { return mol, err }
+}
+
+func TestBuildAgentTrailersMolecule(t *testing.T) {
+	t.Setenv("GT_ROLE", "")
+	t.Setenv(EnvGastownRole, "")
+
+	stubMoleculeLookup(t, &MoleculeStatus{MoleculeID: "gt-abc", Status: "In Progress"}, nil)
+	got := buildAgentTrailers(&commitContext{}, "gastown/polecats/toast/", commitOptions{moleculeStatus: true})
+	for _, want := range []string{"Executed-By: gastown/polecats/toast", "Molecule: gt-abc", "Molecule-Status: in-progress"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("buildAgentTrailers = %q, want it to contain %q", got, want)
+		}
+	}
+	if r := checkMoleculeLookup(); r.Status != doctor.StatusOK || r.Message != "pinned gt-abc" {
+		t.Errorf("checkMoleculeLookup = %+v, want OK pinned gt-abc", r)
+	}
+
+	// One Molecule trailer per pinned molecule, in order
+	stubMoleculeLookup(t, &MoleculeStatus{MoleculeID: "gt-abc", MoleculeIDs: []string{"gt-abc", "gt-def"}}, nil)
+	got = buildAgentTrailers(&commitContext{}, "gastown/polecats/toast", commitOptions{})
+	if molecules := trailerValues(got, TrailerMolecule); !reflect.DeepEqual(molecules, []string{"gt-abc", "gt-def"}) {
+		t.Errorf("Molecule trailers = %q, want [gt-abc gt-def]", molecules)
+	}
+	if r := checkMoleculeLookup(); r.Message != "pinned gt-abc, gt-def" {
+		t.Errorf("checkMoleculeLookup = %+v, want both molecules", r)
+	}
+
+	// A failed lookup never fails the commit; it only drops the trailer
+	stubMoleculeLookup(t, nil, errors.New("hook unreadable"))
+	got = buildAgentTrailers(&commitContext{}, "gastown/polecats/toast", commitOptions{})
+	for _, trailer := range got {
+		if strings.HasPrefix(trailer, TrailerMolecule+":") {
+			t.Errorf("buildAgentTrailers after a failed lookup = %q, want no Molecule trailer", got)
+		}
+	}
+	if r := checkMoleculeLookup(); r.Status != doctor.StatusWarning {
+		t.Errorf("checkMoleculeLookup after a failed lookup = %+v, want Warning", r)
+	}
+}
+
+func TestCommitContextLooksUpOnce(t *testing.T) {
+	t.Setenv("GT_ROLE", "")
+	t.Setenv(EnvGastownRole, "")
+
+	calls := 0
+	orig := MoleculeLookup
+	t.Cleanup(func() { MoleculeLookup = orig })
+	MoleculeLookup = func() (*MoleculeStatus, error) {
+		calls++
+		return nil, errors.New("hook unreadable")
+	}
+
+	cc := &commitContext{}
+	for range 3 {
+		cc.Molecule()
+		buildAgentTrailers(cc, "gastown/polecats/toast", commitOptions{})
+	}
+	if calls != 1 {
+		t.Errorf("MoleculeLookup ran %d times, want once per commit", calls)
+	}
+	if !cc.roleResolved {
+		t.Error("buildAgentTrailers did not resolve the role through the context")
+	}
+}
+
+func TestCommitContextSettingsOnce(t *testing.T) {
+	townRoot := setupTestTownForConfig(t)
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(townRoot); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	saveDomain := func(domain string) {
+		t.Helper()
+		settings := config.NewTownSettings()
+		settings.AgentEmailDomain = domain
+		if err := config.SaveTownSettings(config.TownSettingsPath(townRoot), settings); err != nil {
+			t.Fatalf("SaveTownSettings: %v", err)
+		}
+	}
+
+	saveDomain("first.example")
+	cc := &commitContext{}
+	if domain, _ := cc.Settings(); domain != "first.example" {
+		t.Fatalf("domain = %q, want first.example", domain)
+	}
+
+	// Later steps of the same commit reuse the first lookup
+	saveDomain("second.example")
+	if domain, _ := cc.Settings(); domain != "first.example" {
+		t.Errorf("domain after a settings change = %q, want the memoized first.example", domain)
+	}
+	if domain, _ := (&commitContext{}).Settings(); domain != "second.example" {
+		t.Errorf("new context domain = %q, want second.example", domain)
+	}
+}
+
+func TestBuildAgentTrailersTimestamp(t *testing.T) {
+	t.Setenv("GT_ROLE", "")
+	t.Setenv(EnvGastownRole, "")
+	stubMoleculeLookup(t, nil, nil)
+
+	cc := &commitContext{committedAt: time.Date(2026, 1, 2, 10, 4, 5, 0, time.FixedZone("PST", -8*3600))}
+	got := buildAgentTrailers(cc, "gastown/polecats/toast", commitOptions{timestampTrailer: true})
+	if values := trailerValues(got, TrailerCommittedAt); !reflect.DeepEqual(values, []string{"2026-01-02T18:04:05Z"}) {
+		t.Errorf("Committed-At trailers = %q, want the commit time in UTC", values)
+	}
+
+	if got := buildAgentTrailers(&commitContext{}, "gastown/polecats/toast", commitOptions{}); len(trailerValues(got, TrailerCommittedAt)) > 0 {
+		t.Errorf("buildAgentTrailers without --timestamp-trailer = %q, want no Committed-At", got)
+	}
+	if first := (&commitContext{}); !first.CommittedAt().Equal(first.CommittedAt()) {
+		t.Error("CommittedAt changed between calls")
+	}
+}
+
+func TestParseMoleculeStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want *MoleculeStatus
+	}{
+		{"no work", `{"has_work": false}`, nil},
+		{"single molecule", `{"has_work": true, "attached_molecule": "gt-abc"}`, &MoleculeStatus{MoleculeID: "gt-abc"}},
+		{
+			"several molecules",
+			`{"has_work": true, "attached_molecule": "gt-abc", "attached_molecules": ["gt-abc", "gt-def"], "pinned_bead": {"id": "gt-1", "title": "Fix it", "status": "open"}}`,
+			&MoleculeStatus{MoleculeID: "gt-abc", MoleculeIDs: []string{"gt-abc", "gt-def"}, Title: "Fix it", Status: "open"},
+		},
+		{"pinned bead only", `{"has_work": true, "pinned_bead": {"id": "gt-1"}}`, &MoleculeStatus{MoleculeID: "gt-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMoleculeStatus([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("parseMoleculeStatus: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMoleculeStatus = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if _, err := parseMoleculeStatus([]byte("not json")); err == nil {
+		t.Error("expected error for invalid output")
+	}
+}
+
+func TestParseAgentTrailers(t *testing.T) {
+	got := ParseAgentTrailers(map[string][]string{
+		"Executed-By":    {"gastown/crew/jack"},
+		"Rig":            {"gastown"},
+		"Role":           {"crew"},
+		"Molecule":       {"gt-abc", "gt-def"},
+		"Signed-off-by":  {"Someone"},
+		"Co-Authored-By": {"Ada Lovelace <ada@example.com>"},
+	})
+	want := AgentTrailers{
+		ExecutedBy: "gastown/crew/jack",
+		Rig:        "gastown",
+		Role:       "crew",
+		Molecules:  []string{"gt-abc", "gt-def"},
+		CoAuthors:  []string{"Ada Lovelace <ada@example.com>"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseAgentTrailers = %+v, want %+v", got, want)
+	}
+
+	if got := ParseAgentTrailers(map[string][]string{}); !reflect.DeepEqual(got, AgentTrailers{}) {
+		t.Errorf("ParseAgentTrailers(empty) = %+v, want zero value", got)
+	}
+}
+
+func TestShouldAmendIfMine(t *testing.T) {
+	dir := initCommitTestRepo(t)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	if amend, _ := shouldAmendIfMine("gastown/crew/jack"); amend {
+		t.Error("expected no amend without a previous commit")
+	}
+
+	runGitIn(t, dir, "commit", "--allow-empty", "-m", "work", "--trailer", "Executed-By: gastown/crew/jack")
+
+	if amend, reason := shouldAmendIfMine("gastown/crew/jack"); !amend {
+		t.Errorf("expected amend for own unpushed commit, got %q", reason)
+	}
+	if amend, _ := shouldAmendIfMine("gastown/crew/max"); amend {
+		t.Error("expected no amend for another agent's commit")
+	}
+
+	// Simulate a push by creating a remote-tracking ref at HEAD
+	runGitIn(t, dir, "update-ref", "refs/remotes/origin/main", "HEAD")
+	if amend, _ := shouldAmendIfMine("gastown/crew/jack"); amend {
+		t.Error("expected no amend for a pushed commit")
+	}
+}
+
+func TestExtractTicket(t *testing.T) {
+	jira := regexp.MustCompile(DefaultTicketPattern)
+	github := regexp.MustCompile(`(?:^|/)(\d+)-`)
+	tests := []struct {
+		branch string
+		re     *regexp.Regexp
+		want   string
+	}{
+		{"feature/JIRA-123-foo", jira, "JIRA-123"},
+		{"PROJ2-7", jira, "PROJ2-7"},
+		{"polecat/jack", jira, ""},
+		{"main", jira, ""},
+		{"fix/42-crash-on-start", github, "42"},
+		{"fix/crash", github, ""},
+	}
+	for _, tt := range tests {
+		if got := extractTicket(tt.branch, tt.re); got != tt.want {
+			t.Errorf("extractTicket(%q, %s) = %q, want %q", tt.branch, tt.re, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixSubject(t *testing.T) {
+	if got := prefixSubject("Fix crash\n\nDetails", "JIRA-1"); got != "JIRA-1: Fix crash\n\nDetails" {
+		t.Errorf("prefixSubject = %q", got)
+	}
+	if got := prefixSubject("Fix crash (JIRA-1)", "JIRA-1"); got != "Fix crash (JIRA-1)" {
+		t.Errorf("prefixSubject should keep a subject that mentions the ticket, got %q", got)
+	}
+}
+
+func TestCheckStagedBinaries(t *testing.T) {
+	dir := initCommitTestRepo(t)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	if err := checkStagedBinaries(true); err != nil {
+		t.Errorf("checkStagedBinaries with nothing staged: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "build.o"), []byte{0x7f, 'E', 'L', 'F', 0}, 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitIn(t, dir, "add", "build.o")
+
+	if err := checkStagedBinaries(false); err != nil {
+		t.Errorf("checkStagedBinaries(false) = %v, want warning only", err)
+	}
+	err := checkStagedBinaries(true)
+	if err == nil || !strings.Contains(err.Error(), "build.o") {
+		t.Errorf("checkStagedBinaries(true) = %v, want error naming build.o", err)
+	}
+}
+
+func TestCommitsIndexOnly(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"-m", "msg"}, true},
+		{[]string{"--author", "x <x@y>", "-s", "-m", "a path-looking message"}, true},
+		{[]string{"-a"}, false},
+		{[]string{"-sa"}, false},
+		{[]string{"--all"}, false},
+		{[]string{"-m", "msg", "file.go"}, false},
+		{[]string{"-m", "msg", "--", "file.go"}, false},
+		{[]string{"--pathspec-from-file=list"}, false},
+	}
+	for _, tt := range tests {
+		if got := commitsIndexOnly(tt.args); got != tt.want {
+			t.Errorf("commitsIndexOnly(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSplitPathspecs(t *testing.T) {
+	tests := []struct {
+		args      []string
+		wantFlags []string
+		wantPaths []string
+	}{
+		{[]string{"-m", "msg"}, []string{"-m", "msg"}, nil},
+		{[]string{"-am", "a.go", "b.go"}, []string{"-am", "a.go"}, []string{"b.go"}},
+		{[]string{"-m", "msg", "a.go", "-s", "b.go"}, []string{"-m", "msg", "-s"}, []string{"a.go", "b.go"}},
+		{[]string{"--author", "x <x@y>", "--", "-dash.go", "c.go"}, []string{"--author", "x <x@y>"}, []string{"-dash.go", "c.go"}},
+		{[]string{"--message=msg", "d.go"}, []string{"--message=msg"}, []string{"d.go"}},
+	}
+	for _, tt := range tests {
+		flags, paths := splitPathspecs(tt.args)
+		if !reflect.DeepEqual(flags, tt.wantFlags) || !reflect.DeepEqual(paths, tt.wantPaths) {
+			t.Errorf("splitPathspecs(%q) = %q, %q, want %q, %q", tt.args, flags, paths, tt.wantFlags, tt.wantPaths)
+		}
+	}
+}
+
+func TestCommitPathSubset(t *testing.T) {
+	dir := initCommitTestRepo(t)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("one\n"), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	runGitIn(t, dir, "add", ".")
+	runGitIn(t, dir, "commit", "-q", "-m", "base")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("two\n"), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	runGitIn(t, dir, "add", "b.txt")
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	// The path comes before a flag, and trailers are added after it
+	flags, paths := splitPathspecs([]string{"-q", "a.txt", "-m", "only a"})
+	gitArgs := appendTrailers(append(append(flags, "--"), paths...), []string{"Executed-By: gastown/crew/jack"})
+	if err := runGitCommit(gitArgs, nil, "", "", nil, nil); err != nil {
+		t.Fatalf("runGitCommit: %v", err)
+	}
+
+	out, err := exec.Command("git", "show", "--name-only", "--format=%(trailers:only,unfold)", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git show: %v", err)
+	}
+	if got := strings.Fields(string(out)); !reflect.DeepEqual(got, []string{"Executed-By:", "gastown/crew/jack", "a.txt"}) {
+		t.Errorf("commit = %q, want only a.txt with the trailer", got)
+	}
+	out, err = exec.Command("git", "diff", "--cached", "--name-only").Output()
+	if err != nil {
+		t.Fatalf("git diff: %v", err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "b.txt" {
+		t.Errorf("staged after commit = %q, want b.txt still staged", got)
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	body := strings.Repeat("log line\n", 1000)
+	message := "Fix crash\n\n" + body + "\nRefs: JIRA-1\nSigned-off-by: a <a@b>"
+
+	got := truncateMessage(message, 500)
+	if len(got) > 500 {
+		t.Errorf("truncated message is %d bytes, want <= 500", len(got))
+	}
+	if !strings.HasPrefix(got, "Fix crash\n\nlog line\n") {
+		t.Errorf("subject or start of body lost:\n%s", got)
+	}
+	if !strings.Contains(got, "bytes of body dropped]\n\n") {
+		t.Errorf("truncation note missing:\n%s", got)
+	}
+	if !strings.HasSuffix(got, "\n\nRefs: JIRA-1\nSigned-off-by: a <a@b>") {
+		t.Errorf("trailers lost:\n%s", got)
+	}
+
+	if got := truncateMessage("Short\n\nBody", 500); got != "Short\n\nBody" {
+		t.Errorf("message under the limit changed: %q", got)
+	}
+}
+
+func TestEnforceMessageLimit(t *testing.T) {
+	big := strings.Repeat("x", 100)
+	args := []string{"-am", "Subject", "-m", big}
+
+	if _, err := enforceMessageLimit(args, commitOptions{maxMessageBytes: 50}, config.CommitSettings{}); err == nil {
+		t.Error("expected oversized message to be rejected")
+	}
+	if got, err := enforceMessageLimit(args, commitOptions{}, config.CommitSettings{}); err != nil || !reflect.DeepEqual(got, args) {
+		t.Errorf("message under the default limit: got %q, %v", got, err)
+	}
+
+	got, err := enforceMessageLimit(args, commitOptions{maxMessageBytes: 50}, config.CommitSettings{TruncateMessage: true})
+	if err != nil {
+		t.Fatalf("enforceMessageLimit: %v", err)
+	}
+	if len(got) != 3 || got[0] != "-m" || got[2] != "-a" || !strings.HasPrefix(got[1], "Subject\n\n") {
+		t.Errorf("truncated args = %q, want [-m <truncated> -a]", got)
+	}
+}
+
+func TestRunCommitCheck(t *testing.T) {
+	dir := initCommitTestRepo(t)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	trailers := []string{"Executed-By: gastown/crew/jack"}
+
+	// Empty index: git refuses
+	if err := runCommitCheck([]string{"-m", "Add file"}, trailers, "", ""); err == nil {
+		t.Error("expected check to fail with nothing staged")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitIn(t, dir, "add", "file.txt")
+
+	if err := runCommitCheck([]string{"-m", "Add file"}, trailers, "gastown/crew/jack", "gastown.crew.jack@gastown.local"); err != nil {
+		t.Errorf("runCommitCheck: %v", err)
+	}
+	if err := runCommitCheck([]string{"-a"}, trailers, "", ""); err == nil {
+		t.Error("expected error without -m")
+	}
+
+	// Nothing was committed
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--verify", "HEAD").Run(); err == nil {
+		t.Error("check created a commit")
+	}
+}
+
+func TestAgentAuthor(t *testing.T) {
+	crew := RoleContext{Role: RoleCrew, Rig: "beads", Polecat: "dave"}
+
+	name, email, err := agentAuthor(crew, config.CommitSettings{})
+	if err != nil {
+		t.Fatalf("agentAuthor: %v", err)
+	}
+	if name != "beads-crew-dave" || email != "dave@beads.agents" {
+		t.Errorf("author = %s <%s>, want beads-crew-dave <dave@beads.agents>", name, email)
+	}
+
+	name, email, err = agentAuthor(RoleContext{Role: RoleMayor}, config.CommitSettings{})
+	if err != nil {
+		t.Fatalf("agentAuthor mayor: %v", err)
+	}
+	if name != "mayor" || email != "mayor@town.agents" {
+		t.Errorf("mayor author = %s <%s>", name, email)
+	}
+
+	custom := config.CommitSettings{AuthorNameFormat: "{name} ({rig})", AuthorEmailFormat: "{rig}+{name}@example.com"}
+	name, email, _ = agentAuthor(crew, custom)
+	if name != "dave (beads)" || email != "beads+dave@example.com" {
+		t.Errorf("custom author = %s <%s>", name, email)
+	}
+
+	for _, bad := range []config.CommitSettings{
+		{AuthorEmailFormat: "{name}"},
+		{AuthorEmailFormat: "{name} @x"},
+		{AuthorNameFormat: "<{name}>"},
+	} {
+		if _, _, err := agentAuthor(crew, bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestRunGitCommit_AuthorEnv(t *testing.T) {
+	dir := initCommitTestRepo(t)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	env := []string{"GIT_AUTHOR_NAME=beads-crew-dave", "GIT_AUTHOR_EMAIL=dave@beads.agents"}
+	if err := runGitCommit([]string{"--allow-empty", "-q", "-m", "work"}, nil, "beads/crew/dave", "beads.crew.dave@gastown.local", nil, env); err != nil {
+		t.Fatalf("runGitCommit: %v", err)
+	}
+
+	out, err := exec.Command("git", "log", "-1", "--format=%an <%ae>|%cn <%ce>").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	want := "beads-crew-dave <dave@beads.agents>|beads/crew/dave <beads.crew.dave@gastown.local>"
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Errorf("author|committer = %q, want %q", got, want)
+	}
+}
+
+func TestRunGitCommit_SSHSigned(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	dir := initCommitTestRepo(t)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	key := filepath.Join(t.TempDir(), "agent_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v\n%s", err, out)
+	}
+	if _, err := sshSignConfig(key + ".missing"); err == nil {
+		t.Error("expected error for a missing key file")
+	}
+	config, err := sshSignConfig(key)
+	if err != nil {
+		t.Fatalf("sshSignConfig: %v", err)
+	}
+
+	if err := runGitCommit([]string{"-S", "--allow-empty", "-q", "-m", "signed"}, nil, "", "", config, nil); err != nil {
+		t.Fatalf("runGitCommit: %v", err)
+	}
+	out, err := exec.Command("git", "cat-file", "commit", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git cat-file: %v", err)
+	}
+	if !strings.Contains(string(out), "-----BEGIN SSH SIGNATURE-----") {
+		t.Errorf("commit is not SSH-signed:\n%s", out)
+	}
+}
+
+func TestRunPostCommit(t *testing.T) {
+	dir := initCommitTestRepo(t)
+	runGitIn(t, dir, "commit", "--allow-empty", "-q", "-m", "work")
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	originalHook := PostCommit
+	defer func() { PostCommit = originalHook }()
+
+	var got CommitResult
+	PostCommit = func(result CommitResult) error {
+		got = result
+		return nil
+	}
+	trailers := []string{"Executed-By: gastown/crew/jack", "Molecule: gt-abc12", "Molecule: gt-def34"}
+	runPostCommit(commitOptions{}, []string{"-m", "work"}, trailers)
+
+	head, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git rev-parse: %v", err)
+	}
+	want := CommitResult{Hash: strings.TrimSpace(string(head)), Trailers: trailers, Molecule: "gt-abc12", Molecules: []string{"gt-abc12", "gt-def34"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PostCommit got %+v, want %+v", got, want)
+	}
+
+	// A dry run made no commit to report
+	called := false
+	PostCommit = func(CommitResult) error {
+		called = true
+		return nil
+	}
+	runPostCommit(commitOptions{}, []string{"--dry-run", "-m", "work"}, trailers)
+	if called {
+		t.Error("PostCommit ran for --dry-run")
+	}
+
+	// A failing hook is only reported
+	PostCommit = func(CommitResult) error { return errors.New("orchestrator down") }
+	runPostCommit(commitOptions{}, nil, nil)
+}
+
+func TestKeepDateAmend(t *testing.T) {
+	dir := initCommitTestRepo(t)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	const authorDate, committerDate = "2021-03-04T05:06:07+00:00", "2021-03-05T08:09:10+00:00"
+	cmd := exec.Command("git", "commit", "--allow-empty", "-q", "-m", "original")
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+authorDate, "GIT_COMMITTER_DATE="+committerDate)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v\n%s", err, out)
+	}
+
+	if _, _, err := keepDateArgs([]string{"-m", "--amend"}, false); err == nil {
+		t.Error("expected an error for --keep-date without --amend")
+	}
+	if args, env, err := keepDateArgs([]string{"-m", "new"}, true); err != nil || args != nil || env != nil {
+		t.Errorf("--amend-if-mine without amend: got %v, %v, %v", args, env, err)
+	}
+
+	gitArgs := []string{"--amend", "--reset-author", "--allow-empty", "-q", "-m", "amended"}
+	dateArgs, env, err := keepDateArgs(gitArgs, false)
+	if err != nil {
+		t.Fatalf("keepDateArgs: %v", err)
+	}
+	if err := runGitCommit(append(dateArgs, gitArgs...), nil, "", "", nil, env); err != nil {
+		t.Fatalf("runGitCommit: %v", err)
+	}
+
+	out, err := exec.Command("git", "log", "-1", "--format=%s %aI %cI").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	want := "amended " + authorDate + " " + committerDate
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Errorf("after amend got %q, want %q", got, want)
+	}
+}
+
+func TestAmendTrailerConfig(t *testing.T) {
+	dir := initCommitTestRepo(t)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	first := []string{"Executed-By: gastown/polecats/toast", "Molecule: gt-abc", "Refs: GT-1"}
+	if err := runGitCommit([]string{"--allow-empty", "-q", "-m", "work"}, first, "", "", nil, nil); err != nil {
+		t.Fatalf("runGitCommit: %v", err)
+	}
+
+	second := []string{"Executed-By: gastown/polecats/toast", "Molecule: gt-def", "Molecule: gt-ghi", "Refs: GT-1", "Refs: GT-2"}
+	config := amendTrailerConfig(second)
+	wantConfig := []string{"trailer.Executed-By.ifexists=replace", "trailer.Molecule.ifexists=addIfDifferent", "trailer.Refs.ifexists=addIfDifferent"}
+	if !reflect.DeepEqual(config, wantConfig) {
+		t.Errorf("amendTrailerConfig = %v, want %v", config, wantConfig)
+	}
+	if err := runGitCommit([]string{"--amend", "--no-edit", "--allow-empty", "-q"}, second, "", "", config, nil); err != nil {
+		t.Fatalf("runGitCommit amend: %v", err)
+	}
+
+	out, err := exec.Command("git", "log", "-1", "--format=%(trailers:only,unfold)").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	got := strings.Split(strings.TrimSpace(string(out)), "\n")
+	// Replaced trailers move to the end; existing Molecule and Refs values
+	// stay put, and every new molecule is added
+	want := []string{"Molecule: gt-abc", "Refs: GT-1", "Executed-By: gastown/polecats/toast", "Molecule: gt-def", "Molecule: gt-ghi", "Refs: GT-2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("trailers after amend = %q, want %q", got, want)
+	}
+}
+
+func TestCommitSigning(t *testing.T) {
+	dir := initCommitTestRepo(t)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	for _, tt := range []struct {
+		opts commitOptions
+		want []string
+	}{
+		{commitOptions{}, nil},
+		{commitOptions{sign: true}, []string{"-S"}},
+		{commitOptions{sshSignKey: "id_ed25519"}, []string{"-S"}},
+		{commitOptions{noSign: true}, []string{"--no-gpg-sign"}},
+	} {
+		if got := commitSignArgs(tt.opts); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("commitSignArgs(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+
+	if got := describeSigning([]string{"-a"}); got != "" {
+		t.Errorf("describeSigning(unsigned) = %q", got)
+	}
+	if got := describeSigning([]string{"-S", "--amend"}); got != "yes" {
+		t.Errorf("describeSigning(-S) = %q", got)
+	}
+
+	// commit.gpgsign with no usable key: only --no-sign lets the amend through
+	runGitIn(t, dir, "commit", "--allow-empty", "-q", "-m", "work", "--trailer", "Executed-By: gastown/crew/jack")
+	runGitIn(t, dir, "config", "commit.gpgsign", "true")
+	runGitIn(t, dir, "config", "gpg.program", "false")
+	if got := describeSigning([]string{"-a"}); got != "yes (commit.gpgsign)" {
+		t.Errorf("describeSigning(commit.gpgsign) = %q", got)
+	}
+	if got := describeSigning([]string{"--no-gpg-sign"}); got != "no (--no-gpg-sign)" {
+		t.Errorf("describeSigning(--no-gpg-sign) = %q", got)
+	}
+
+	trailers := []string{"Executed-By: gastown/crew/jack"}
+	args := appendTrailers([]string{"--amend", "--no-edit", "--allow-empty", "-q"}, trailers)
+	config := amendTrailerConfig(trailers)
+	if err := exec.Command("git", append([]string{"commit"}, args...)...).Run(); err == nil {
+		t.Fatal("expected the signed amend to fail with gpg.program=false")
+	}
+	if err := runGitCommit(append(commitSignArgs(commitOptions{noSign: true}), args...), nil, "", "", config, nil); err != nil {
+		t.Fatalf("runGitCommit --no-gpg-sign: %v", err)
+	}
+	out, err := exec.Command("git", "log", "-1", "--format=%(trailers:only,unfold)").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	if got := strings.TrimSpace(string(out)); got != trailers[0] {
+		t.Errorf("trailers after amend = %q, want %q", got, trailers[0])
+	}
+}
+
+func TestCommitGitBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("wrapper is a shell script")
+	}
+	realGit, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not installed")
+	}
+	dir := initCommitTestRepo(t)
+
+	// A wrapper that logs each invocation's args before running git
+	wrapperDir := t.TempDir()
+	calls := filepath.Join(wrapperDir, "calls")
+	wrapper := filepath.Join(wrapperDir, "git-wrapper")
+	script := "#!/bin/sh\necho \"$*\" >> '" + calls + "'\nexec '" + realGit + "' \"$@\"\n"
+	if err := os.WriteFile(wrapper, []byte(script), 0755); err != nil {
+		t.Fatalf("write wrapper: %v", err)
+	}
+	t.Setenv(git.EnvGitBinary, wrapper)
+
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	if err := runGitCommit([]string{"--allow-empty", "-q", "-m", "work"}, nil, "gastown/crew/jack", "gastown.crew.jack@gastown.local", nil, nil); err != nil {
+		t.Fatalf("runGitCommit: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitIn(t, dir, "add", "a.txt")
+	if err := runCommitCheck([]string{"-m", "check"}, []string{"Rig: gastown"}, "", ""); err != nil {
+		t.Fatalf("runCommitCheck: %v", err)
+	}
+	if err := amendHeadTrailers(git.NewGit(dir), "gastown.local"); err != nil {
+		t.Fatalf("amendHeadTrailers: %v", err)
+	}
+
+	out, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("read calls: %v", err)
+	}
+	for _, want := range []string{"commit --allow-empty -q -m work", "interpret-trailers", "commit --dry-run", "commit --amend"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("wrapper calls missing %q:\n%s", want, out)
+		}
+	}
+}
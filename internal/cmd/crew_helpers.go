@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -236,7 +237,9 @@ func ensureDefaultBranch(dir, roleName, rigPath string) bool { //nolint:unparam
 	g := git.NewGit(dir)
 
 	branch, err := g.CurrentBranch()
-	if err != nil {
+	if errors.Is(err, git.ErrDetachedHead) {
+		branch = "(detached HEAD)"
+	} else if err != nil {
 		// Not a git repo or other error, skip check
 		return true
 	}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -90,7 +91,10 @@ func runCrewStatus(cmd *cobra.Command, args []string) error {
 		// Git status
 		crewGit := git.NewGit(w.ClonePath)
 		gitStatus, _ := crewGit.Status()
-		branch, _ := crewGit.CurrentBranch()
+		branch, err := crewGit.CurrentBranch()
+		if errors.Is(err, git.ErrDetachedHead) {
+			branch = "HEAD" // As git shows it
+		}
 
 		gitClean := true
 		var modified, untracked []string
@@ -98,6 +102,9 @@ func runCrewStatus(cmd *cobra.Command, args []string) error {
 			gitClean = gitStatus.Clean
 			modified = append(gitStatus.Modified, gitStatus.Added...)
 			modified = append(modified, gitStatus.Deleted...)
+			for _, r := range gitStatus.Renamed {
+				modified = append(modified, r.To)
+			}
 			untracked = gitStatus.Untracked
 		}
 
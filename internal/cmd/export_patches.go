@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Export-patches command flags
+var (
+	exportPatchesOutputDir   string
+	exportPatchesStdout      bool
+	exportPatchesCoverLetter bool
+)
+
+var exportPatchesCmd = &cobra.Command{
+	Use:     "export-patches <base>",
+	GroupID: GroupWork,
+	Short:   "Export the current branch's commits as a patch series",
+	Long: `Export the commits in <base>..HEAD as a patch series for review.
+
+Each commit becomes one mbox-formatted patch (git format-patch), keeping its
+author, author date and full message, including the agent trailers
+(Executed-By, Rig, Role, Molecule). Reviewers without access to the agent's
+worktree can apply the series with 'git am'.
+
+With --cover-letter, a 0000-cover-letter.patch is added with a shortlog,
+a diffstat and a summary of the pinned molecule.
+
+Examples:
+  gt export-patches main                    # Write patches to ./patches/
+  gt export-patches origin/main -o review/  # Write patches to ./review/
+  gt export-patches main --stdout > w.mbox  # Single mbox stream
+  gt export-patches main --cover-letter     # Add a molecule summary`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExportPatches,
+}
+
+func init() {
+	exportPatchesCmd.Flags().StringVarP(&exportPatchesOutputDir, "output-dir", "o", "patches", "Directory to write the patch files to")
+	exportPatchesCmd.Flags().BoolVar(&exportPatchesStdout, "stdout", false, "Write the series to stdout as a single mbox")
+	exportPatchesCmd.Flags().BoolVar(&exportPatchesCoverLetter, "cover-letter", false, "Add a cover letter summarizing the molecule")
+	rootCmd.AddCommand(exportPatchesCmd)
+}
+
+func runExportPatches(cmd *cobra.Command, args []string) error {
+	base := args[0]
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+	g := git.NewGit(cwd)
+
+	count, err := g.CommitsAhead(base, "HEAD")
+	if err != nil {
+		return fmt.Errorf("comparing with %s: %w", base, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("no commits in %s..HEAD to export", base)
+	}
+	revRange := base + "..HEAD"
+
+	var subject, blurb string
+	if exportPatchesCoverLetter {
+		subject, blurb = coverLetterSummary(g, count)
+	}
+
+	if exportPatchesStdout {
+		mbox, err := g.FormatPatchMbox(revRange, exportPatchesCoverLetter)
+		if err != nil {
+			return fmt.Errorf("formatting patches: %w", err)
+		}
+		if exportPatchesCoverLetter {
+			mbox = fillCoverLetter(mbox, subject, blurb)
+		}
+		fmt.Print(mbox)
+		return nil
+	}
+
+	files, err := g.FormatPatch(revRange, exportPatchesOutputDir, exportPatchesCoverLetter)
+	if err != nil {
+		return fmt.Errorf("formatting patches: %w", err)
+	}
+
+	if exportPatchesCoverLetter && len(files) > 0 {
+		coverPath := files[0]
+		if !filepath.IsAbs(coverPath) {
+			coverPath = filepath.Join(cwd, coverPath)
+		}
+		content, err := os.ReadFile(coverPath)
+		if err != nil {
+			return fmt.Errorf("reading cover letter: %w", err)
+		}
+		if err := os.WriteFile(coverPath, []byte(fillCoverLetter(string(content), subject, blurb)), 0644); err != nil {
+			return fmt.Errorf("writing cover letter: %w", err)
+		}
+	}
+
+	fmt.Printf("%s Exported %d commit(s) from %s\n", style.SuccessPrefix, count, revRange)
+	for _, f := range files {
+		fmt.Printf("  %s\n", f)
+	}
+	fmt.Printf("\n%s\n", style.Dim.Render("Apply with: git am "+filepath.Join(exportPatchesOutputDir, "*.patch")))
+	return nil
+}
+
+// coverLetterSummary returns the cover letter subject and blurb for the
+// series, describing the pinned molecule and the agent when known.
+func coverLetterSummary(g *git.Git, count int) (subject, blurb string) {
+	branch, err := g.CurrentBranch()
+	if err != nil {
+		branch = "HEAD" // Detached
+	}
+	subject = fmt.Sprintf("%d commit(s) from %s", count, branch)
+
+	var lines []string
+	if mol := getPinnedMolecule(); len(mol.IDs()) > 0 {
+		subject = formatMoleculeSubject("", mol)
+		for _, id := range mol.IDs() {
+			lines = append(lines, formatTrailer(TrailerMolecule, id))
+		}
+		if mol.Title != "" {
+			lines = append(lines, "Title: "+mol.Title)
+		}
+		if mol.Status != "" {
+			lines = append(lines, "Status: "+mol.Status)
+		}
+	}
+	if identity := detectSender(); identity != "overseer" {
+		lines = append(lines, formatTrailer(TrailerExecutedBy, strings.TrimSuffix(identity, "/")))
+	}
+	if err == nil {
+		lines = append(lines, formatTrailer(TrailerBranch, branch))
+	}
+	return subject, strings.Join(lines, "\n")
+}
+
+// fillCoverLetter replaces git format-patch's cover letter placeholders.
+func fillCoverLetter(text, subject, blurb string) string {
+	return strings.NewReplacer(
+		"*** SUBJECT HERE ***", subject,
+		"*** BLURB HERE ***", blurb,
+	).Replace(text)
+}
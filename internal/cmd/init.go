@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -43,7 +44,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	// Check if it's a git repository
 	g := git.NewGit(cwd)
-	if _, err := g.CurrentBranch(); err != nil {
+	if _, err := g.CurrentBranch(); err != nil && !errors.Is(err, git.ErrDetachedHead) {
 		return fmt.Errorf("not a git repository (run 'git init' first)")
 	}
 
@@ -113,17 +113,18 @@ type MoleculeProgressInfo struct {
 
 // MoleculeStatusInfo contains status information for an agent's work.
 type MoleculeStatusInfo struct {
-	Target           string                `json:"target"`
-	Role             string                `json:"role"`
-	AgentBeadID      string                `json:"agent_bead_id,omitempty"` // The agent bead if found
-	HasWork          bool                  `json:"has_work"`
-	PinnedBead       *beads.Issue          `json:"pinned_bead,omitempty"`
-	AttachedMolecule string                `json:"attached_molecule,omitempty"`
-	AttachedAt       string                `json:"attached_at,omitempty"`
-	AttachedArgs     string                `json:"attached_args,omitempty"`
-	IsWisp           bool                  `json:"is_wisp"`
-	Progress         *MoleculeProgressInfo `json:"progress,omitempty"`
-	NextAction       string                `json:"next_action,omitempty"`
+	Target            string                `json:"target"`
+	Role              string                `json:"role"`
+	AgentBeadID       string                `json:"agent_bead_id,omitempty"` // The agent bead if found
+	HasWork           bool                  `json:"has_work"`
+	PinnedBead        *beads.Issue          `json:"pinned_bead,omitempty"`
+	AttachedMolecule  string                `json:"attached_molecule,omitempty"`
+	AttachedMolecules []string              `json:"attached_molecules,omitempty"` // All attached, in order, when several; AttachedMolecule is the first
+	AttachedAt        string                `json:"attached_at,omitempty"`
+	AttachedArgs      string                `json:"attached_args,omitempty"`
+	IsWisp            bool                  `json:"is_wisp"`
+	Progress          *MoleculeProgressInfo `json:"progress,omitempty"`
+	NextAction        string                `json:"next_action,omitempty"`
 }
 
 // MoleculeCurrentInfo contains info about what an agent should be working on.
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+var moleculeVerifyStrict bool
+
+var moleculeVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that HEAD's Molecule trailer matches the pinned molecule",
+	Long: `Compare the molecule pinned to your hook with the Molecule trailer
+that 'gt commit' recorded on HEAD.
+
+A mismatch means the last commit was made against a different molecule
+(e.g. work was repinned, or the commit was made before pinning), which
+corrupts molecule tracking. Run this right after committing to catch it
+while the commit can still be amended.
+
+By default a mismatch is reported as a warning. With --strict, it exits
+non-zero, for use in CI.
+
+Examples:
+  gt mol verify            # Warn on mismatch
+  gt mol verify --strict   # Fail on mismatch`,
+	Args: cobra.NoArgs,
+	RunE: runMoleculeVerify,
+}
+
+func init() {
+	moleculeVerifyCmd.Flags().BoolVar(&moleculeVerifyStrict, "strict", false, "Exit non-zero on mismatch")
+	moleculeCmd.AddCommand(moleculeVerifyCmd)
+}
+
+func runMoleculeVerify(cmd *cobra.Command, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+
+	trailers, err := git.NewGit(cwd).CommitTrailers("HEAD")
+	if err != nil {
+		return fmt.Errorf("reading HEAD trailers: %w", err)
+	}
+	recorded := ParseAgentTrailers(trailers).Molecules
+
+	var pinned string
+	if mol := getPinnedMolecule(); mol != nil {
+		pinned = mol.MoleculeID
+	}
+
+	ok, msg := compareMolecules(pinned, recorded)
+	if ok {
+		fmt.Printf("%s %s\n", style.SuccessPrefix, msg)
+		return nil
+	}
+	if moleculeVerifyStrict {
+		return fmt.Errorf("molecule mismatch: %s", msg)
+	}
+	style.PrintWarning("%s", msg)
+	return nil
+}
+
+// compareMolecules checks the pinned molecule against the Molecule trailers
+// recorded on a commit and describes the result.
+func compareMolecules(pinned string, recorded []string) (bool, string) {
+	switch {
+	case pinned == "" && len(recorded) == 0:
+		return true, "nothing pinned and HEAD records no molecule"
+	case pinned == "":
+		return false, fmt.Sprintf("HEAD records molecule %s but nothing is pinned", strings.Join(recorded, ", "))
+	case len(recorded) == 0:
+		return false, fmt.Sprintf("%s is pinned but HEAD has no Molecule trailer", pinned)
+	}
+	for _, id := range recorded {
+		if id == pinned {
+			return true, fmt.Sprintf("HEAD matches pinned molecule %s", pinned)
+		}
+	}
+	return false, fmt.Sprintf("HEAD records molecule %s but %s is pinned", strings.Join(recorded, ", "), pinned)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import "testing"
+
+func TestCompareMolecules(t *testing.T) {
+	tests := []struct {
+		name     string
+		pinned   string
+		recorded []string
+		wantOK   bool
+	}{
+		{"nothing either side", "", nil, true},
+		{"match", "gt-abc", []string{"gt-abc"}, true},
+		{"match among several", "gt-def", []string{"gt-abc", "gt-def"}, true},
+		{"mismatch", "gt-abc", []string{"gt-xyz"}, false},
+		{"missing trailer", "gt-abc", nil, false},
+		{"nothing pinned", "", []string{"gt-abc"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, msg := compareMolecules(tt.pinned, tt.recorded)
+			if ok != tt.wantOK {
+				t.Errorf("compareMolecules(%q, %v) = %v (%s), want %v", tt.pinned, tt.recorded, ok, msg, tt.wantOK)
+			}
+		})
+	}
+}
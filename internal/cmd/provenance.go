@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+var provenanceJSON bool
+
+var provenanceCmd = &cobra.Command{
+	Use:     "provenance [ref]",
+	GroupID: GroupDiag,
+	Short:   "Show which agent produced a commit, and where it went",
+	Long: `Show the full agent provenance of a commit (default HEAD).
+
+Reports:
+- The agent trailers written by 'gt commit' (Executed-By, Rig, Role,
+  Molecule) and any others (Branch, Host, PID, Session-Id, ...)
+- The author and committer
+- The signature status
+- The branches and tags that contain the commit
+
+Examples:
+  gt provenance               # HEAD
+  gt provenance a1b2c3d       # A specific commit
+  gt provenance HEAD~3 --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runProvenance,
+}
+
+func init() {
+	provenanceCmd.Flags().BoolVar(&provenanceJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(provenanceCmd)
+}
+
+// ProvenanceReport is the output of gt provenance.
+type ProvenanceReport struct {
+	Hash           string              `json:"hash"`
+	Subject        string              `json:"subject"`
+	Author         string              `json:"author"`
+	AuthorEmail    string              `json:"author_email"`
+	AuthorDate     time.Time           `json:"author_date"`
+	Committer      string              `json:"committer"`
+	CommitterEmail string              `json:"committer_email"`
+	ExecutedBy     string              `json:"executed_by,omitempty"`
+	Rig            string              `json:"rig,omitempty"`
+	Role           string              `json:"role,omitempty"`
+	Molecules      []string            `json:"molecules,omitempty"`
+	Trailers       map[string][]string `json:"trailers"`
+	Signature      string              `json:"signature"` // git's %G? code
+	Signer         string              `json:"signer,omitempty"`
+	Branches       []string            `json:"branches"`
+	Tags           []string            `json:"tags"` // Tags containing the commit
+}
+
+func runProvenance(cmd *cobra.Command, args []string) error {
+	ref := "HEAD"
+	if len(args) > 0 {
+		ref = args[0]
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+	report, err := buildProvenanceReport(git.NewGit(cwd), ref)
+	if err != nil {
+		return err
+	}
+
+	if provenanceJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printProvenanceReport(report)
+	return nil
+}
+
+// buildProvenanceReport gathers the provenance of the commit at ref.
+func buildProvenanceReport(g *git.Git, ref string) (*ProvenanceReport, error) {
+	commits, err := g.Log(git.LogOptions{Range: ref, MaxCount: 1})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ref, err)
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commit at %s", ref)
+	}
+	c := commits[0]
+	agent := ParseAgentTrailers(c.Trailers)
+
+	report := &ProvenanceReport{
+		Hash:           c.Hash,
+		Subject:        c.Subject,
+		Author:         c.Author,
+		AuthorEmail:    c.AuthorEmail,
+		AuthorDate:     c.Date,
+		Committer:      c.Committer,
+		CommitterEmail: c.CommitterEmail,
+		ExecutedBy:     agent.ExecutedBy,
+		Rig:            agent.Rig,
+		Role:           agent.Role,
+		Molecules:      agent.Molecules,
+		Trailers:       c.Trailers,
+		Branches:       []string{},
+		Tags:           []string{},
+	}
+
+	sig, err := g.VerifyCommit(c.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("checking signature: %w", err)
+	}
+	report.Signature, report.Signer = sig.Code, sig.Signer
+
+	if branches, err := g.BranchesContaining(c.Hash); err == nil && branches != nil {
+		report.Branches = branches
+	}
+	if tags, err := g.TagsContaining(c.Hash); err == nil && tags != nil {
+		report.Tags = tags
+	}
+	return report, nil
+}
+
+// signatureDescriptions explains git's %G? signature codes.
+var signatureDescriptions = map[string]string{
+	"G": "good",
+	"B": "BAD",
+	"U": "good (unknown validity)",
+	"X": "good (signature expired)",
+	"Y": "good (key expired)",
+	"R": "good (key revoked)",
+	"E": "cannot be checked (missing key?)",
+	"N": "unsigned",
+}
+
+func printProvenanceReport(r *ProvenanceReport) {
+	fmt.Printf("%s %s\n\n", style.Bold.Render(r.Hash), r.Subject)
+
+	fmt.Printf("%s\n", style.Bold.Render("Agent"))
+	if r.ExecutedBy == "" {
+		fmt.Printf("  %s\n", style.Dim.Render("No Executed-By trailer (not made with gt commit?)"))
+	} else {
+		fmt.Printf("  Executed-By: %s\n", r.ExecutedBy)
+		if r.Rig != "" {
+			fmt.Printf("  Rig:         %s\n", r.Rig)
+		}
+		if r.Role != "" {
+			fmt.Printf("  Role:        %s\n", r.Role)
+		}
+		if len(r.Molecules) > 0 {
+			fmt.Printf("  Molecule:    %s\n", strings.Join(r.Molecules, ", "))
+		}
+	}
+
+	agentKeys := map[string]bool{TrailerExecutedBy: true, TrailerRig: true, TrailerRole: true, TrailerMolecule: true}
+	var other []string
+	for key, values := range r.Trailers {
+		if !agentKeys[key] {
+			for _, v := range values {
+				other = append(other, formatTrailer(key, v))
+			}
+		}
+	}
+	if len(other) > 0 {
+		sort.Strings(other)
+		fmt.Printf("\n%s\n", style.Bold.Render("Other trailers"))
+		for _, t := range other {
+			fmt.Printf("  %s\n", t)
+		}
+	}
+
+	fmt.Printf("\n%s\n", style.Bold.Render("Identity"))
+	fmt.Printf("  Author:    %s <%s> (%s)\n", r.Author, r.AuthorEmail, r.AuthorDate.Format(time.RFC3339))
+	fmt.Printf("  Committer: %s <%s>\n", r.Committer, r.CommitterEmail)
+	signature := signatureDescriptions[r.Signature]
+	if signature == "" {
+		signature = r.Signature
+	}
+	if r.Signer != "" {
+		signature += " by " + r.Signer
+	}
+	fmt.Printf("  Signature: %s\n", signature)
+
+	fmt.Printf("\n%s\n", style.Bold.Render("Contained in"))
+	if len(r.Branches) == 0 && len(r.Tags) == 0 {
+		fmt.Printf("  %s\n", style.Dim.Render("No branches or tags"))
+	}
+	if len(r.Branches) > 0 {
+		fmt.Printf("  Branches: %s\n", strings.Join(r.Branches, ", "))
+	}
+	if len(r.Tags) > 0 {
+		fmt.Printf("  Tags:     %s\n", strings.Join(r.Tags, ", "))
+	}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+func TestBuildProvenanceReport(t *testing.T) {
+	dir := initCommitTestRepo(t)
+	runGitIn(t, dir, "-c", "user.name=gastown/crew/jack", "-c", "user.email=gastown.crew.jack@gastown.local",
+		"commit", "--allow-empty", "-m", "Fix bug",
+		"--trailer", "Executed-By: gastown/crew/jack",
+		"--trailer", "Rig: gastown",
+		"--trailer", "Role: crew",
+		"--trailer", "Molecule: gt-abc",
+		"--trailer", "Host: build-7")
+	runGitIn(t, dir, "tag", "v1.0")
+
+	report, err := buildProvenanceReport(git.NewGit(dir), "HEAD")
+	if err != nil {
+		t.Fatalf("buildProvenanceReport: %v", err)
+	}
+
+	if report.ExecutedBy != "gastown/crew/jack" || report.Rig != "gastown" || report.Role != "crew" {
+		t.Errorf("agent = %s %s %s", report.ExecutedBy, report.Rig, report.Role)
+	}
+	if !reflect.DeepEqual(report.Molecules, []string{"gt-abc"}) {
+		t.Errorf("Molecules = %v", report.Molecules)
+	}
+	if !reflect.DeepEqual(report.Trailers["Host"], []string{"build-7"}) {
+		t.Errorf("Host trailer = %v", report.Trailers["Host"])
+	}
+	if report.Author != "gastown/crew/jack" || report.Committer != "gastown/crew/jack" {
+		t.Errorf("author/committer = %s / %s", report.Author, report.Committer)
+	}
+	if report.Signature != "N" {
+		t.Errorf("Signature = %q, want N (unsigned)", report.Signature)
+	}
+	if len(report.Branches) != 1 {
+		t.Errorf("Branches = %v, want the current branch", report.Branches)
+	}
+	if !reflect.DeepEqual(report.Tags, []string{"v1.0"}) {
+		t.Errorf("Tags = %v, want [v1.0]", report.Tags)
+	}
+
+	if _, err := buildProvenanceReport(git.NewGit(dir), "no-such-ref"); err == nil {
+		t.Error("expected error for unknown ref")
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Rebase-update command flags
+var (
+	rebaseUpdateDryRun bool
+	rebaseUpdateRemote string
+)
+
+var rebaseUpdateCmd = &cobra.Command{
+	Use:     "rebase-update",
+	GroupID: GroupWork,
+	Short:   "Rebase the current branch onto the latest default branch",
+	Long: `Fetch the remote's default branch and rebase the current branch onto it.
+
+Before rebasing, shows how many of your commits will be replayed and a
+diffstat of what changed upstream since your branch diverged. Local changes
+are stashed and reapplied automatically (--autostash).
+
+If the rebase stops on conflicts, the conflicted files are listed along with
+the commands to continue or abort.
+
+Examples:
+  gt rebase-update            # Fetch and rebase onto origin's default branch
+  gt rebase-update --dry-run  # Show the plan without rebasing`,
+	Args: cobra.NoArgs,
+	RunE: runRebaseUpdate,
+}
+
+func init() {
+	rebaseUpdateCmd.Flags().BoolVarP(&rebaseUpdateDryRun, "dry-run", "n", false, "Show the plan without rebasing")
+	rebaseUpdateCmd.Flags().StringVar(&rebaseUpdateRemote, "remote", "origin", "Remote to fetch the default branch from")
+	rootCmd.AddCommand(rebaseUpdateCmd)
+}
+
+func runRebaseUpdate(cmd *cobra.Command, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+	g := git.NewGit(cwd)
+
+	branch, err := g.CurrentBranch()
+	if errors.Is(err, git.ErrDetachedHead) {
+		return fmt.Errorf("HEAD is detached; check out a branch first")
+	}
+	if err != nil {
+		return fmt.Errorf("getting current branch: %w", err)
+	}
+
+	defaultBranch := g.RemoteDefaultBranch()
+	upstream := rebaseUpdateRemote + "/" + defaultBranch
+	if branch == defaultBranch {
+		return fmt.Errorf("already on %s; nothing to rebase onto", defaultBranch)
+	}
+
+	fmt.Printf("%s Fetching %s...\n", style.ArrowPrefix, upstream)
+	if err := g.FetchBranch(rebaseUpdateRemote, defaultBranch); err != nil {
+		return fmt.Errorf("fetching %s: %w", upstream, err)
+	}
+
+	replay, err := g.CommitsAhead(upstream, "HEAD")
+	if err != nil {
+		return fmt.Errorf("counting local commits: %w", err)
+	}
+	behind, err := g.CountCommitsBehind(upstream)
+	if err != nil {
+		return fmt.Errorf("counting upstream commits: %w", err)
+	}
+
+	if behind == 0 {
+		fmt.Printf("%s %s is already up to date with %s\n", style.SuccessPrefix, branch, upstream)
+		return nil
+	}
+
+	base, err := g.BranchBase("HEAD", upstream)
+	if err != nil {
+		return err
+	}
+	stat, err := g.DiffStat(base, upstream)
+	if err != nil {
+		return fmt.Errorf("computing upstream changes: %w", err)
+	}
+
+	fmt.Printf("\n%s: %d new commit(s) on %s, %d local commit(s) to replay\n",
+		style.Bold.Render(branch), behind, upstream, replay)
+	printDiffStat(stat)
+
+	if rebaseUpdateDryRun {
+		fmt.Printf("\n%s\n", style.Dim.Render("Dry run: no changes made"))
+		return nil
+	}
+
+	fmt.Printf("\n%s Rebasing %s onto %s...\n", style.ArrowPrefix, branch, upstream)
+	if err := g.RebaseWithOptions(upstream, git.RebaseOptions{Autostash: true}); err != nil {
+		conflicts, cerr := g.ConflictedFiles()
+		if cerr != nil || len(conflicts) == 0 {
+			return fmt.Errorf("rebasing onto %s: %w", upstream, err)
+		}
+
+		fmt.Printf("\n%s Rebase stopped on %d conflicted file(s):\n", style.ErrorPrefix, len(conflicts))
+		for _, c := range conflicts {
+			fmt.Printf("  %s  %s\n", style.Warning.Render(string(c.Type)), c.Path)
+		}
+		fmt.Printf("\nNext steps:\n")
+		fmt.Printf("  1. Resolve the conflicts in the files above\n")
+		fmt.Printf("  2. Stage them:   git add <file>...\n")
+		fmt.Printf("  3. Continue:     git rebase --continue\n")
+		fmt.Printf("  Or give up:      git rebase --abort\n")
+		return fmt.Errorf("rebase onto %s stopped on conflicts", upstream)
+	}
+
+	fmt.Printf("%s Rebased %s onto %s\n", style.SuccessPrefix, branch, upstream)
+	return nil
+}
+
+// printDiffStat prints a per-file summary of line changes, like git --stat.
+func printDiffStat(stat *git.DiffStat) {
+	for _, f := range stat.Files {
+		if f.Binary {
+			fmt.Printf("  %s | %s\n", f.Path, style.Dim.Render("binary"))
+			continue
+		}
+		fmt.Printf("  %s | %s %s\n", f.Path,
+			style.Success.Render(fmt.Sprintf("+%d", f.Insertions)),
+			style.Error.Render(fmt.Sprintf("-%d", f.Deletions)))
+	}
+	fmt.Printf("  %d file(s) changed, %d insertion(s), %d deletion(s)\n",
+		len(stat.Files), stat.Insertions, stat.Deletions)
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+	"golang.org/x/term"
+)
+
+// Resolve command flags
+var (
+	resolveStrategy string
+	resolveMapFile  string
+	resolveContinue bool
+)
+
+// resolveSkip leaves a conflicted path for manual resolution.
+const resolveSkip = "skip"
+
+var resolveCmd = &cobra.Command{
+	Use:     "resolve",
+	GroupID: GroupWork,
+	Short:   "Resolve merge/rebase conflicts file by file",
+	Long: `Work through the conflicted files of a stopped merge, rebase,
+cherry-pick or revert, choosing a resolution for each:
+
+  ours    Take our side (during a rebase: the upstream being rebased onto)
+  theirs  Take their side (during a rebase: the commit being replayed)
+  union   Keep both sides' lines, without conflict markers
+  skip    Leave the file for manual resolution
+
+Resolved files are staged. With a terminal, each file is prompted for;
+--strategy sets the answer for files that aren't otherwise decided, and
+--map gives per-path strategies from a file of "<pattern> <strategy>"
+lines (# starts a comment). Patterns without a "/" match the file name in
+any directory, like .gitattributes:
+
+  go.sum          theirs
+  docs/*.md       union
+  *.lock          ours
+
+Without a terminal, files with no strategy are skipped. Once nothing is
+left conflicted, the operation is continued with --continue (or after
+confirming, on a terminal).
+
+Examples:
+  gt resolve                          # Prompt for each file
+  gt resolve --strategy theirs --continue
+  gt resolve --map .gt-resolve --strategy skip`,
+	Args: cobra.NoArgs,
+	RunE: runResolve,
+}
+
+func init() {
+	resolveCmd.Flags().StringVar(&resolveStrategy, "strategy", "", "Default strategy: ours, theirs, union or skip")
+	resolveCmd.Flags().StringVar(&resolveMapFile, "map", "", "File of per-path strategies (\"<pattern> <strategy>\" lines)")
+	resolveCmd.Flags().BoolVar(&resolveContinue, "continue", false, "Continue the operation once all conflicts are resolved")
+
+	rootCmd.AddCommand(resolveCmd)
+}
+
+// resolveRule assigns a strategy to the paths matching pattern.
+type resolveRule struct {
+	pattern  string
+	strategy string
+}
+
+// parseResolveMap reads "<pattern> <strategy>" lines. Blank lines and
+// lines starting with # are ignored.
+func parseResolveMap(r io.Reader) ([]resolveRule, error) {
+	var rules []resolveRule
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want \"<pattern> <strategy>\", got %q", n, line)
+		}
+		if err := validateResolveStrategy(fields[1]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, fmt.Errorf("line %d: bad pattern %q: %w", n, fields[0], err)
+		}
+		rules = append(rules, resolveRule{pattern: fields[0], strategy: fields[1]})
+	}
+	return rules, scanner.Err()
+}
+
+// strategyFor returns the strategy of the last rule matching file (later
+// rules override earlier ones, as in .gitattributes), or "" if none match.
+func strategyFor(rules []resolveRule, file string) string {
+	strategy := ""
+	for _, rule := range rules {
+		target := file
+		if !strings.Contains(rule.pattern, "/") {
+			target = path.Base(file)
+		}
+		if ok, _ := path.Match(rule.pattern, target); ok {
+			strategy = rule.strategy
+		}
+	}
+	return strategy
+}
+
+// validateResolveStrategy checks that s is ours, theirs, union or skip.
+func validateResolveStrategy(s string) error {
+	switch git.ResolveStrategy(s) {
+	case git.ResolveOurs, git.ResolveTheirs, git.ResolveUnion, resolveSkip:
+		return nil
+	}
+	return fmt.Errorf("unknown strategy %q (want ours, theirs, union or skip)", s)
+}
+
+func runResolve(cmd *cobra.Command, args []string) error {
+	if resolveStrategy != "" {
+		if err := validateResolveStrategy(resolveStrategy); err != nil {
+			return err
+		}
+	}
+	var rules []resolveRule
+	if resolveMapFile != "" {
+		f, err := os.Open(resolveMapFile)
+		if err != nil {
+			return fmt.Errorf("opening strategy map: %w", err)
+		}
+		rules, err = parseResolveMap(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", resolveMapFile, err)
+		}
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+	g := git.NewGit(cwd)
+	if root, err := g.RepoRoot(); err == nil {
+		g = git.NewGit(root) // Conflicted paths are relative to the root
+	}
+
+	op, err := g.InProgressOperation()
+	if err != nil {
+		return err
+	}
+	files, err := g.ConflictedFiles()
+	if err != nil {
+		return fmt.Errorf("listing conflicts: %w", err)
+	}
+	if op == "" && len(files) == 0 {
+		fmt.Printf("%s No merge, rebase, cherry-pick or revert in progress\n", style.SuccessPrefix)
+		return nil
+	}
+
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	stdin := bufio.NewReader(os.Stdin)
+
+	if len(files) > 0 {
+		fmt.Printf("%d conflicted file(s) in %s:\n\n", len(files), op)
+	}
+	remaining := 0
+	for _, f := range files {
+		strategy := strategyFor(rules, f.Path)
+		if strategy == "" {
+			strategy = resolveStrategy
+		}
+		if strategy == "" && interactive {
+			strategy = promptResolveStrategy(stdin, f)
+		}
+
+		if strategy == "" || strategy == resolveSkip {
+			fmt.Printf("  %s %s %s\n", style.Dim.Render("skip  "), f.Path, style.Dim.Render(conflictDescription(f.Type)))
+			remaining++
+			continue
+		}
+		if err := g.ResolveConflict(f.Path, git.ResolveStrategy(strategy)); err != nil {
+			fmt.Printf("  %s %s: %v\n", style.ErrorPrefix, f.Path, err)
+			remaining++
+			continue
+		}
+		fmt.Printf("  %s %-6s %s\n", style.SuccessPrefix, strategy, f.Path)
+	}
+
+	if remaining > 0 {
+		fmt.Printf("\n%s %d file(s) still conflicted; resolve them, then rerun 'gt resolve'\n", style.WarningPrefix, remaining)
+		return nil
+	}
+	if !resolveContinue && !(interactive && promptYesNo(fmt.Sprintf("\nAll conflicts resolved. Continue the %s?", op))) {
+		fmt.Printf("\n%s All conflicts resolved; continue with 'git %s --continue'\n", style.SuccessPrefix, op)
+		return nil
+	}
+	if err := g.Continue(); err != nil {
+		return fmt.Errorf("continuing %s: %w", op, err)
+	}
+	fmt.Printf("\n%s Continued %s\n", style.SuccessPrefix, op)
+	return nil
+}
+
+// promptResolveStrategy asks how to resolve f. Returns "" for skip or on
+// end of input.
+func promptResolveStrategy(stdin *bufio.Reader, f git.ConflictedFile) string {
+	for {
+		fmt.Printf("  %s %s — [o]urs, [t]heirs, [u]nion, [s]kip? ", f.Path, style.Dim.Render(conflictDescription(f.Type)))
+		answer, err := stdin.ReadString('\n')
+		switch strings.TrimSpace(strings.ToLower(answer)) {
+		case "o", "ours":
+			return string(git.ResolveOurs)
+		case "t", "theirs":
+			return string(git.ResolveTheirs)
+		case "u", "union":
+			return string(git.ResolveUnion)
+		case "s", "skip":
+			return ""
+		}
+		if err != nil {
+			return ""
+		}
+	}
+}
+
+// conflictDescription describes a conflict type in words.
+func conflictDescription(t git.ConflictType) string {
+	switch t {
+	case git.ConflictBothModified:
+		return "(both modified)"
+	case git.ConflictBothAdded:
+		return "(both added)"
+	case git.ConflictDeletedByUs:
+		return "(deleted by us)"
+	case git.ConflictDeletedByThem:
+		return "(deleted by them)"
+	case git.ConflictAddedByUs:
+		return "(added by us)"
+	case git.ConflictAddedByThem:
+		return "(added by them)"
+	case git.ConflictBothDeleted:
+		return "(both deleted)"
+	}
+	return ""
+}
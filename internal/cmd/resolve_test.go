@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseResolveMap(t *testing.T) {
+	rules, err := parseResolveMap(strings.NewReader(`
+# Generated files: take upstream
+go.sum      theirs
+*.lock      ours
+docs/*.md   union
+docs/keep.md skip
+`))
+	if err != nil {
+		t.Fatalf("parseResolveMap: %v", err)
+	}
+
+	tests := map[string]string{
+		"go.sum":           "theirs",
+		"sub/dir/go.sum":   "theirs",
+		"yarn.lock":        "ours",
+		"docs/guide.md":    "union",
+		"docs/keep.md":     "skip", // Later rules win
+		"docs/sub/deep.md": "",
+		"main.go":          "",
+	}
+	for path, want := range tests {
+		if got := strategyFor(rules, path); got != want {
+			t.Errorf("strategyFor(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	for _, bad := range []string{"go.sum mine", "go.sum", "[ ours"} {
+		if _, err := parseResolveMap(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseResolveMap(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -1150,7 +1151,10 @@ func runRigStatus(cmd *cobra.Command, args []string) error {
 
 			// Get git info
 			crewGit := git.NewGit(w.ClonePath)
-			branch, _ := crewGit.CurrentBranch()
+			branch, err := crewGit.CurrentBranch()
+			if errors.Is(err, git.ErrDetachedHead) {
+				branch = "(detached)"
+			}
 			gitStatus, _ := crewGit.Status()
 
 			gitInfo := ""
@@ -24,7 +24,7 @@ const (
 // and detectRole() functions.
 type RoleInfo struct {
 	Role          Role   `json:"role"`
-	Source        string `json:"source"` // "env", "cwd", or "explicit"
+	Source        string `json:"source"` // "env", "cwd", "explicit", "fallback-env" or "manifest"
 	Home          string `json:"home"`
 	Rig           string `json:"rig,omitempty"`
 	Polecat       string `json:"polecat,omitempty"`
@@ -121,6 +121,8 @@ func init() {
 
 // GetRole returns the current role, checking GT_ROLE first then falling back to cwd.
 // This is the canonical function for role detection.
+// Outside a town, or when neither resolves a role, the identity comes from
+// the GASTOWN_ROLE env vars or the repo's .gastown/identity.json, if set.
 func GetRole() (RoleInfo, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -132,10 +134,20 @@ func GetRole() (RoleInfo, error) {
 		return RoleInfo{}, fmt.Errorf("finding workspace: %w", err)
 	}
 	if townRoot == "" {
+		if info, fallbackErr := fallbackRole(cwd); fallbackErr == nil {
+			return info, nil
+		}
 		return RoleInfo{}, fmt.Errorf("not in a Gas Town workspace")
 	}
 
-	return GetRoleWithContext(cwd, townRoot)
+	info, err := GetRoleWithContext(cwd, townRoot)
+	if err == nil && info.Role == RoleUnknown {
+		if fallback, fallbackErr := fallbackRole(cwd); fallbackErr == nil {
+			fallback.TownRoot = townRoot
+			return fallback, nil
+		}
+	}
+	return info, err
 }
 
 // GetRoleWithContext returns role info given explicit cwd and town root.
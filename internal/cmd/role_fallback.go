@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Environment variables for identity outside a town (e.g. CI replays of
+// agent work), consulted only when filesystem discovery fails.
+const (
+	EnvGastownRole    = "GASTOWN_ROLE"
+	EnvGastownRig     = "GASTOWN_RIG"
+	EnvGastownPolecat = "GASTOWN_POLECAT"
+)
+
+// IdentityManifestPath is the repo-relative path of the identity manifest,
+// the last identity fallback after the GASTOWN_* env vars.
+const IdentityManifestPath = ".gastown/identity.json"
+
+// Role sources for identities resolved without the town layout.
+const (
+	RoleSourceFallbackEnv = "fallback-env"
+	RoleSourceManifest    = "manifest"
+)
+
+// errNoFallbackIdentity is returned when neither fallback source is set.
+var errNoFallbackIdentity = errors.New("no GASTOWN_ROLE set and no " + IdentityManifestPath)
+
+// IdentityManifest is the content of .gastown/identity.json. Role is a role
+// name ("polecat") or a full address ("gastown/polecats/toast"), in which
+// case Rig and Polecat may be omitted.
+type IdentityManifest struct {
+	Role    string `json:"role"`
+	Rig     string `json:"rig,omitempty"`
+	Polecat string `json:"polecat,omitempty"`
+}
+
+// roleInfo validates the identity and converts it to a RoleInfo.
+func (m IdentityManifest) roleInfo(source string) (RoleInfo, error) {
+	if strings.TrimSpace(m.Role) == "" {
+		return RoleInfo{}, fmt.Errorf("role is empty")
+	}
+	role, rig, polecat := parseRoleString(m.Role)
+	if rig == "" {
+		rig = strings.TrimSpace(m.Rig)
+	}
+	if polecat == "" {
+		polecat = strings.TrimSpace(m.Polecat)
+	}
+
+	switch role {
+	case RoleMayor, RoleDeacon:
+	case RoleWitness, RoleRefinery:
+		if rig == "" {
+			return RoleInfo{}, fmt.Errorf("role %s needs a rig", role)
+		}
+	case RolePolecat, RoleCrew:
+		if rig == "" || polecat == "" {
+			return RoleInfo{}, fmt.Errorf("role %s needs a rig and a name", role)
+		}
+	default:
+		return RoleInfo{}, fmt.Errorf("unknown role %q", m.Role)
+	}
+	for _, name := range []string{rig, polecat} {
+		if strings.ContainsAny(name, "/ \t\n") {
+			return RoleInfo{}, fmt.Errorf("invalid name %q", name)
+		}
+	}
+	return RoleInfo{Role: role, Rig: rig, Polecat: polecat, Source: source}, nil
+}
+
+// fallbackRole resolves the identity when the town layout isn't present:
+// from the GASTOWN_ROLE/GASTOWN_RIG/GASTOWN_POLECAT env vars, then from
+// .gastown/identity.json at the root of the repo containing cwd. An invalid
+// source is reported with a warning and skipped. With GT_DEBUG set, the
+// source that was used is reported too.
+func fallbackRole(cwd string) (RoleInfo, error) {
+	if envRole := os.Getenv(EnvGastownRole); envRole != "" {
+		m := IdentityManifest{Role: envRole, Rig: os.Getenv(EnvGastownRig), Polecat: os.Getenv(EnvGastownPolecat)}
+		info, err := m.roleInfo(RoleSourceFallbackEnv)
+		if err == nil {
+			info.WorkDir = cwd
+			debugIdentitySource(info, EnvGastownRole)
+			return info, nil
+		}
+		fmt.Fprintf(os.Stderr, "%s ignoring %s: %v\n", style.WarningPrefix, EnvGastownRole, err)
+	}
+
+	root, err := git.NewGit(cwd).RepoRoot()
+	if err != nil {
+		return RoleInfo{}, errNoFallbackIdentity
+	}
+	path := filepath.Join(root, IdentityManifestPath)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return RoleInfo{}, errNoFallbackIdentity
+	}
+	if err != nil {
+		return RoleInfo{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var m IdentityManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		fmt.Fprintf(os.Stderr, "%s ignoring %s: %v\n", style.WarningPrefix, path, err)
+		return RoleInfo{}, errNoFallbackIdentity
+	}
+	info, err := m.roleInfo(RoleSourceManifest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s ignoring %s: %v\n", style.WarningPrefix, path, err)
+		return RoleInfo{}, errNoFallbackIdentity
+	}
+	info.WorkDir = cwd
+	debugIdentitySource(info, path)
+	return info, nil
+}
+
+// debugIdentitySource reports a fallback identity's source under GT_DEBUG.
+func debugIdentitySource(info RoleInfo, from string) {
+	if os.Getenv("GT_DEBUG") != "" {
+		fmt.Fprintf(os.Stderr, "[DEBUG] identity %s from %s (no town layout found)\n", info.ActorString(), from)
+	}
+}
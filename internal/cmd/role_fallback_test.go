@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIdentityManifestRoleInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest IdentityManifest
+		want     string // ActorString, or "" for a validation error
+	}{
+		{"full address", IdentityManifest{Role: "gastown/polecats/toast"}, "gastown/polecats/toast"},
+		{"role with rig and name", IdentityManifest{Role: "crew", Rig: "beads", Polecat: "dave"}, "beads/crew/dave"},
+		{"town role", IdentityManifest{Role: "mayor"}, "mayor"},
+		{"rig role", IdentityManifest{Role: "witness", Rig: "gastown"}, "gastown/witness"},
+		{"empty", IdentityManifest{}, ""},
+		{"unknown role", IdentityManifest{Role: "janitor"}, ""},
+		{"missing name", IdentityManifest{Role: "polecat", Rig: "gastown"}, ""},
+		{"missing rig", IdentityManifest{Role: "refinery"}, ""},
+		{"bad name", IdentityManifest{Role: "crew", Rig: "gastown", Polecat: "jack smith"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := tt.manifest.roleInfo(RoleSourceManifest)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("got %s, want a validation error", info.ActorString())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("roleInfo: %v", err)
+			}
+			if got := info.ActorString(); got != tt.want {
+				t.Errorf("ActorString = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackRole(t *testing.T) {
+	for _, key := range []string{EnvGastownRole, EnvGastownRig, EnvGastownPolecat} {
+		t.Setenv(key, "")
+	}
+	dir := initCommitTestRepo(t)
+
+	if _, err := fallbackRole(dir); err == nil {
+		t.Error("expected an error with no env vars or manifest")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".gastown"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	manifest := `{"role": "polecat", "rig": "gastown", "polecat": "toast"}`
+	if err := os.WriteFile(filepath.Join(dir, IdentityManifestPath), []byte(manifest), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	sub := filepath.Join(dir, "src")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	info, err := fallbackRole(sub)
+	if err != nil || info.ActorString() != "gastown/polecats/toast" || info.Source != RoleSourceManifest {
+		t.Errorf("manifest: got %+v, %v", info, err)
+	}
+
+	// Env vars take precedence over the manifest
+	t.Setenv(EnvGastownRole, "crew")
+	t.Setenv(EnvGastownRig, "beads")
+	t.Setenv(EnvGastownPolecat, "dave")
+	info, err = fallbackRole(dir)
+	if err != nil || info.ActorString() != "beads/crew/dave" || info.Source != RoleSourceFallbackEnv {
+		t.Errorf("env: got %+v, %v", info, err)
+	}
+
+	// Invalid env vars are skipped in favor of the manifest
+	t.Setenv(EnvGastownPolecat, "")
+	info, err = fallbackRole(dir)
+	if err != nil || info.Source != RoleSourceManifest {
+		t.Errorf("invalid env: got %+v, %v", info, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, IdentityManifestPath), []byte(`{"role": "janitor"}`), 0644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if info, err := fallbackRole(dir); err == nil {
+		t.Errorf("invalid manifest: got %+v, want an error", info)
+	}
+}
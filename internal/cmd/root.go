@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
 	"github.com/steveyegge/gastown/internal/style"
 	"github.com/steveyegge/gastown/internal/version"
 	"github.com/steveyegge/gastown/internal/workspace"
@@ -53,11 +54,16 @@ func persistentPreRun(cmd *cobra.Command, args []string) error {
 		warnIfTownRootOffMain()
 	}
 
-	// Skip beads check for exempt commands
+	// Skip git and beads checks for exempt commands
 	if beadsExemptCommands[cmdName] {
 		return nil
 	}
 
+	// Fail early with a clear message if git is missing or too old
+	if err := git.CheckGit(); err != nil {
+		return err
+	}
+
 	// Check beads version
 	return CheckBeadsVersion()
 }
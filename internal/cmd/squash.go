@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Squash command flags
+var (
+	squashMessage          string
+	squashPreserveTrailers bool
+)
+
+// squashPreservedTrailers are the trailers collected from every squashed
+// commit by --preserve-trailers.
+var squashPreservedTrailers = []string{TrailerExecutedBy, TrailerMolecule}
+
+var squashCmd = &cobra.Command{
+	Use:     "squash <base>",
+	GroupID: GroupWork,
+	Short:   "Squash the commits since <base> into one",
+	Long: `Replace the commits in <base>..HEAD with a single commit.
+
+The squashed commit has HEAD's tree and the oldest commit's author. Its
+message is the oldest commit's message unless -m is given. The working
+tree and index are not touched.
+
+With --preserve-trailers, the Executed-By and Molecule trailers of every
+squashed commit are collected into the new commit's trailer block, so all
+contributing agents and molecules stay credited. A value that appears on
+several commits is kept once, in the order first seen (oldest first).
+
+Examples:
+  gt squash main
+  gt squash origin/main -m "Add widget support" --preserve-trailers`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSquash,
+}
+
+func init() {
+	squashCmd.Flags().StringVarP(&squashMessage, "message", "m", "", "Message for the squashed commit")
+	squashCmd.Flags().BoolVar(&squashPreserveTrailers, "preserve-trailers", false, "Keep the Executed-By and Molecule trailers of all squashed commits")
+
+	rootCmd.AddCommand(squashCmd)
+}
+
+func runSquash(cmd *cobra.Command, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+	g := git.NewGit(cwd)
+
+	opts := git.SquashOptions{Message: squashMessage}
+	if squashPreserveTrailers {
+		opts.PreserveTrailers = squashPreservedTrailers
+	}
+	hash, err := g.Squash(args[0], opts)
+	if err != nil {
+		return fmt.Errorf("squashing: %w", err)
+	}
+	fmt.Printf("%s Squashed %s..HEAD into %s\n", style.SuccessPrefix, args[0], hash[:8])
+	return nil
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+var summarizeJSON bool
+
+// unassignedMolecule names the group of commits without a Molecule trailer.
+const unassignedMolecule = "unassigned"
+
+var summarizeCmd = &cobra.Command{
+	Use:     "summarize <base>",
+	GroupID: GroupWork,
+	Short:   "Summarize a branch's work by molecule",
+	Long: `Group the commits in <base>..HEAD by their Molecule trailer and show,
+per molecule, the commit subjects and the combined diffstat.
+
+Commits without a Molecule trailer are grouped as "unassigned". A commit
+that credits several molecules is listed under each. Groups are in the
+order their first commit was made.
+
+Useful for PR descriptions and standups; --json output can feed a PR-body
+generator.
+
+Examples:
+  gt summarize main
+  gt summarize origin/main --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSummarize,
+}
+
+func init() {
+	summarizeCmd.Flags().BoolVar(&summarizeJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(summarizeCmd)
+}
+
+// MoleculeSummary is one molecule's share of a branch's work.
+type MoleculeSummary struct {
+	Molecule   string            `json:"molecule"` // "unassigned" for commits without one
+	Commits    []SummaryCommit   `json:"commits"`  // Oldest first
+	Files      []SummaryFileStat `json:"files"`
+	Insertions int               `json:"insertions"`
+	Deletions  int               `json:"deletions"`
+}
+
+// SummaryCommit is a commit listed in a MoleculeSummary.
+type SummaryCommit struct {
+	Hash       string `json:"hash"`
+	Subject    string `json:"subject"`
+	ExecutedBy string `json:"executed_by,omitempty"`
+}
+
+// SummaryFileStat is the combined line counts of a file across a
+// molecule's commits.
+type SummaryFileStat struct {
+	Path       string `json:"path"`
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+	Binary     bool   `json:"binary,omitempty"`
+}
+
+func runSummarize(cmd *cobra.Command, args []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+	g := git.NewGit(cwd)
+	base := args[0]
+
+	ahead, err := g.CommitsAhead(base, "HEAD")
+	if err != nil {
+		return fmt.Errorf("counting commits: %w", err)
+	}
+	summaries, err := summarizeByMolecule(g, base)
+	if err != nil {
+		return err
+	}
+
+	if summarizeJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaries)
+	}
+
+	if ahead == 0 {
+		fmt.Printf("%s No commits in %s..HEAD\n", style.SuccessPrefix, base)
+		return nil
+	}
+	fmt.Printf("%d commit(s) in %s..HEAD across %d group(s)\n", ahead, base, len(summaries))
+	for _, s := range summaries {
+		fmt.Printf("\n%s %s\n", style.Bold.Render(s.Molecule), style.Dim.Render(fmt.Sprintf("(%d commit(s))", len(s.Commits))))
+		for _, c := range s.Commits {
+			fmt.Printf("  %s %s\n", style.Dim.Render(c.Hash[:8]), c.Subject)
+		}
+		stat := &git.DiffStat{Insertions: s.Insertions, Deletions: s.Deletions}
+		for _, f := range s.Files {
+			stat.Files = append(stat.Files, git.FileStat{Path: f.Path, Insertions: f.Insertions, Deletions: f.Deletions, Binary: f.Binary})
+		}
+		printDiffStat(stat)
+	}
+	return nil
+}
+
+// summarizeByMolecule groups the commits in base..HEAD by Molecule trailer,
+// with each group's diffstat the sum of its commits' diffstats.
+func summarizeByMolecule(g *git.Git, base string) ([]*MoleculeSummary, error) {
+	commits, err := g.Log(git.LogOptions{Range: base + "..HEAD"})
+	if err != nil {
+		return nil, fmt.Errorf("listing commits: %w", err)
+	}
+
+	summaries := []*MoleculeSummary{}
+	byMolecule := make(map[string]*MoleculeSummary)
+	var unassigned *MoleculeSummary
+	for i := len(commits) - 1; i >= 0; i-- { // Oldest first
+		c := commits[i]
+		stat, err := g.DiffStat(c.Hash+"^", c.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("diffstat of %s: %w", c.ShortHash, err)
+		}
+
+		agent := ParseAgentTrailers(c.Trailers)
+		molecules := agent.Molecules
+		if len(molecules) == 0 {
+			if unassigned == nil {
+				unassigned = &MoleculeSummary{Molecule: unassignedMolecule}
+			}
+			addToSummary(unassigned, c, agent, stat)
+			continue
+		}
+		for _, mol := range molecules {
+			s := byMolecule[mol]
+			if s == nil {
+				s = &MoleculeSummary{Molecule: mol}
+				byMolecule[mol] = s
+				summaries = append(summaries, s)
+			}
+			addToSummary(s, c, agent, stat)
+		}
+	}
+	if unassigned != nil {
+		summaries = append(summaries, unassigned)
+	}
+	return summaries, nil
+}
+
+// addToSummary adds a commit and its diffstat to s, keeping s.Files sorted.
+func addToSummary(s *MoleculeSummary, c git.Commit, agent AgentTrailers, stat *git.DiffStat) {
+	s.Commits = append(s.Commits, SummaryCommit{Hash: c.Hash, Subject: c.Subject, ExecutedBy: agent.ExecutedBy})
+	s.Insertions += stat.Insertions
+	s.Deletions += stat.Deletions
+	for _, f := range stat.Files {
+		i := sort.Search(len(s.Files), func(i int) bool { return s.Files[i].Path >= f.Path })
+		if i == len(s.Files) || s.Files[i].Path != f.Path {
+			s.Files = append(s.Files, SummaryFileStat{})
+			copy(s.Files[i+1:], s.Files[i:])
+			s.Files[i] = SummaryFileStat{Path: f.Path}
+		}
+		s.Files[i].Insertions += f.Insertions
+		s.Files[i].Deletions += f.Deletions
+		s.Files[i].Binary = s.Files[i].Binary || f.Binary
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+func TestSummarizeByMolecule(t *testing.T) {
+	dir := initCommitTestRepo(t)
+	runGitIn(t, dir, "commit", "--allow-empty", "-m", "base")
+
+	commit := func(file, content, subject string, trailers ...string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+		runGitIn(t, dir, "add", file)
+		args := []string{"commit", "-m", subject}
+		for _, tr := range trailers {
+			args = append(args, "--trailer", tr)
+		}
+		runGitIn(t, dir, args...)
+	}
+	commit("a.txt", "1\n2\n", "Start widget", "Molecule: gt-w")
+	commit("notes.txt", "n\n", "Update notes")
+	commit("a.txt", "1\n2\n3\n", "Finish widget", "Molecule: gt-w", "Executed-By: gastown/crew/jack")
+	commit("b.txt", "b\n", "Shared fix", "Molecule: gt-x", "Molecule: gt-w")
+
+	summaries, err := summarizeByMolecule(git.NewGit(dir), "HEAD~4")
+	if err != nil {
+		t.Fatalf("summarizeByMolecule: %v", err)
+	}
+
+	var groups []string
+	for _, s := range summaries {
+		groups = append(groups, s.Molecule)
+	}
+	if want := []string{"gt-w", "gt-x", unassignedMolecule}; !reflect.DeepEqual(groups, want) {
+		t.Fatalf("groups = %v, want %v", groups, want)
+	}
+
+	w := summaries[0]
+	var subjects []string
+	for _, c := range w.Commits {
+		subjects = append(subjects, c.Subject)
+	}
+	if want := []string{"Start widget", "Finish widget", "Shared fix"}; !reflect.DeepEqual(subjects, want) {
+		t.Errorf("gt-w commits = %v, want %v", subjects, want)
+	}
+	if w.Commits[1].ExecutedBy != "gastown/crew/jack" {
+		t.Errorf("ExecutedBy = %q", w.Commits[1].ExecutedBy)
+	}
+	wantFiles := []SummaryFileStat{{Path: "a.txt", Insertions: 3}, {Path: "b.txt", Insertions: 1}}
+	if !reflect.DeepEqual(w.Files, wantFiles) || w.Insertions != 4 {
+		t.Errorf("gt-w files = %+v (+%d), want %+v (+4)", w.Files, w.Insertions, wantFiles)
+	}
+
+	if len(summaries[2].Commits) != 1 || summaries[2].Commits[0].Subject != "Update notes" {
+		t.Errorf("unassigned = %+v", summaries[2].Commits)
+	}
+}
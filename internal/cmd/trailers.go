@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Trailers fix command flags
+var (
+	trailersFixApply bool
+	trailersFixForce bool
+)
+
+var trailersCmd = &cobra.Command{
+	Use:     "trailers",
+	GroupID: GroupWork,
+	Short:   "Inspect and repair agent commit trailers",
+	RunE:    requireSubcommand,
+	Long: `Commands for the agent trailers that 'gt commit' writes
+(Executed-By, Rig, Role).`,
+}
+
+var trailersFixCmd = &cobra.Command{
+	Use:   "fix <range>",
+	Short: "Add missing agent trailers to commits in a range",
+	Long: `Find agent commits in <range> that are missing Executed-By, Rig or Role
+trailers, and (with --apply) rewrite them to add the trailers.
+
+Agent commits are those whose author email is in the agent email domain.
+The trailers are inferred from the author name, which 'gt commit' sets to
+the agent's identity (e.g. gastown/crew/jack). Commits that already have
+all required trailers are left as-is, so the command is safe to rerun.
+
+<range> is "<base>..HEAD" or just "<base>". Rewriting uses
+'git rebase --exec' over the range, so the current branch must be the tip
+and the working tree must be clean.
+
+By default nothing is changed: the commits that would be fixed are listed.
+Commits already on a remote branch are refused unless --force is given,
+since rewriting them requires a force-push.
+
+Examples:
+  gt trailers fix main               # List commits that need fixing
+  gt trailers fix main..HEAD --apply # Rewrite them
+  gt trailers fix origin/main --apply --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTrailersFix,
+}
+
+// trailersAmendHeadCmd is run by 'git rebase --exec' for each commit.
+var trailersAmendHeadCmd = &cobra.Command{
+	Use:    "amend-head",
+	Short:  "Add missing agent trailers to HEAD (used by 'gt trailers fix')",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting current directory: %w", err)
+		}
+		domain, _ := loadCommitSettings()
+		return amendHeadTrailers(git.NewGit(cwd), domain)
+	},
+}
+
+func init() {
+	trailersFixCmd.Flags().BoolVar(&trailersFixApply, "apply", false, "Rewrite the commits (default: list only)")
+	trailersFixCmd.Flags().BoolVar(&trailersFixForce, "force", false, "Allow rewriting commits that are already pushed")
+
+	trailersCmd.AddCommand(trailersFixCmd)
+	trailersCmd.AddCommand(trailersAmendHeadCmd)
+	rootCmd.AddCommand(trailersCmd)
+}
+
+func runTrailersFix(cmd *cobra.Command, args []string) error {
+	base, tip, found := strings.Cut(args[0], "..")
+	if !found || tip == "" {
+		tip = "HEAD"
+	}
+	if tip != "HEAD" {
+		return fmt.Errorf("range must end at HEAD (got %s); check out %s first", args[0], tip)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting current directory: %w", err)
+	}
+	g := git.NewGit(cwd)
+	domain, _ := loadCommitSettings()
+
+	commits, err := g.Log(git.LogOptions{Range: base + "..HEAD"})
+	if err != nil {
+		return fmt.Errorf("listing commits: %w", err)
+	}
+
+	var broken []git.Commit
+	for _, c := range commits {
+		if len(missingAgentTrailers(c, domain)) > 0 {
+			broken = append(broken, c)
+		}
+	}
+	if len(broken) == 0 {
+		fmt.Printf("%s All agent commits in %s..HEAD have the required trailers\n", style.SuccessPrefix, base)
+		return nil
+	}
+
+	fmt.Printf("%d commit(s) missing agent trailers:\n\n", len(broken))
+	var pushed []string
+	for _, c := range broken {
+		var keys []string
+		for _, t := range missingAgentTrailers(c, domain) {
+			key, _, _ := strings.Cut(t, ":")
+			keys = append(keys, key)
+		}
+		fmt.Printf("  %s %s\n", style.Bold.Render(c.ShortHash), c.Subject)
+		fmt.Printf("      %s\n", style.Dim.Render(fmt.Sprintf("author %s, missing %s", c.Author, strings.Join(keys, ", "))))
+		if remotes, err := g.RemoteBranchesContaining(c.Hash); err == nil && len(remotes) > 0 {
+			pushed = append(pushed, fmt.Sprintf("%s (on %s)", c.ShortHash, remotes[0]))
+		}
+	}
+
+	if len(pushed) > 0 && !trailersFixForce {
+		return fmt.Errorf("refusing to rewrite pushed commits %s; use --force (and force-push afterwards)", strings.Join(pushed, ", "))
+	}
+	if !trailersFixApply {
+		fmt.Printf("\n%s\n", style.Dim.Render("Dry run: rerun with --apply to rewrite these commits"))
+		return nil
+	}
+
+	if dirty, err := g.HasUncommittedChanges(); err != nil || dirty {
+		return fmt.Errorf("working tree must be clean to rewrite history")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating gt binary: %w", err)
+	}
+
+	fmt.Printf("\n%s Rewriting %s..HEAD...\n", style.ArrowPrefix, base)
+	if err := g.RebaseWithOptions(base, git.RebaseOptions{Exec: shellQuote(exe) + " trailers amend-head"}); err != nil {
+		return fmt.Errorf("rewriting commits (resolve, then 'git rebase --continue' or '--abort'): %w", err)
+	}
+	fmt.Printf("%s Added trailers to %d commit(s)\n", style.SuccessPrefix, len(broken))
+	if len(pushed) > 0 {
+		fmt.Printf("%s\n", style.Dim.Render("Rewritten commits were pushed; update the remote with git push --force-with-lease"))
+	}
+	return nil
+}
+
+// missingAgentTrailers returns the agent trailers ("Key: value") that an
+// agent commit should have but lacks, inferred from its author. Commits not
+// authored in the agent email domain need none.
+func missingAgentTrailers(c git.Commit, domain string) []string {
+	if !strings.HasSuffix(c.AuthorEmail, "@"+domain) {
+		return nil
+	}
+	identity := strings.TrimSuffix(c.Author, "/")
+	role, rig, _ := parseRoleString(identity)
+
+	expected := map[string]string{TrailerExecutedBy: identity}
+	if rig != "" {
+		expected[TrailerRig] = rig
+	}
+	if role != "" && role != RoleUnknown {
+		expected[TrailerRole] = string(role)
+	}
+
+	var missing []string
+	for _, key := range []string{TrailerExecutedBy, TrailerRig, TrailerRole} {
+		if value, ok := expected[key]; ok && len(c.Trailers[key]) == 0 {
+			missing = append(missing, formatTrailer(key, value))
+		}
+	}
+	return missing
+}
+
+// amendHeadTrailers adds any missing agent trailers to HEAD, keeping its
+// author and message otherwise unchanged. Compliant commits are untouched.
+func amendHeadTrailers(g *git.Git, domain string) error {
+	commits, err := g.Log(git.LogOptions{MaxCount: 1})
+	if err != nil || len(commits) == 0 {
+		return fmt.Errorf("reading HEAD: %w", err)
+	}
+	missing := missingAgentTrailers(commits[0], domain)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	args := appendTrailers([]string{"commit", "--amend", "--no-edit", "--no-verify", "--allow-empty", "--quiet"}, missing)
+	gitCmd := exec.Command(git.Binary(), args...)
+	gitCmd.Dir = g.WorkDir()
+	gitCmd.Stderr = os.Stderr
+	if err := gitCmd.Run(); err != nil {
+		return fmt.Errorf("amending %s: %w", commits[0].ShortHash, err)
+	}
+	return nil
+}
+
+// shellQuote quotes s for use as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+func TestMissingAgentTrailers(t *testing.T) {
+	tests := []struct {
+		name   string
+		commit git.Commit
+		want   []string
+	}{
+		{
+			name:   "human commit",
+			commit: git.Commit{Author: "Jane", AuthorEmail: "jane@example.com"},
+		},
+		{
+			name:   "crew commit without trailers",
+			commit: git.Commit{Author: "gastown/crew/jack", AuthorEmail: "gastown.crew.jack@gastown.local"},
+			want:   []string{"Executed-By: gastown/crew/jack", "Rig: gastown", "Role: crew"},
+		},
+		{
+			name: "partially compliant",
+			commit: git.Commit{
+				Author:      "gastown/polecats/nux",
+				AuthorEmail: "gastown.polecats.nux@gastown.local",
+				Trailers:    map[string][]string{"Executed-By": {"gastown/polecats/nux"}},
+			},
+			want: []string{"Rig: gastown", "Role: polecat"},
+		},
+		{
+			name:   "mayor has no rig",
+			commit: git.Commit{Author: "mayor/", AuthorEmail: "mayor.@gastown.local"},
+			want:   []string{"Executed-By: mayor", "Role: mayor"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingAgentTrailers(tt.commit, "gastown.local"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingAgentTrailers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmendHeadTrailers(t *testing.T) {
+	dir := initCommitTestRepo(t)
+	runGitIn(t, dir, "-c", "user.name=gastown/crew/jack", "-c", "user.email=gastown.crew.jack@gastown.local",
+		"commit", "--allow-empty", "-m", "work", "--trailer", "Rig: gastown")
+
+	g := git.NewGit(dir)
+	if err := amendHeadTrailers(g, "gastown.local"); err != nil {
+		t.Fatalf("amendHeadTrailers: %v", err)
+	}
+	head, _ := g.Rev("HEAD")
+
+	trailers, err := g.CommitTrailers("HEAD")
+	if err != nil {
+		t.Fatalf("CommitTrailers: %v", err)
+	}
+	want := map[string][]string{
+		"Rig":         {"gastown"},
+		"Executed-By": {"gastown/crew/jack"},
+		"Role":        {"crew"},
+	}
+	if !reflect.DeepEqual(trailers, want) {
+		t.Errorf("trailers = %v, want %v", trailers, want)
+	}
+
+	out, _ := exec.Command("git", "-C", dir, "log", "-1", "--format=%an").Output()
+	if author := strings.TrimSpace(string(out)); author != "gastown/crew/jack" {
+		t.Errorf("author = %q, want it preserved", author)
+	}
+
+	// Idempotent: a compliant commit is not rewritten
+	if err := amendHeadTrailers(g, "gastown.local"); err != nil {
+		t.Fatalf("amendHeadTrailers again: %v", err)
+	}
+	if after, _ := g.Rev("HEAD"); after != head {
+		t.Error("compliant commit was rewritten")
+	}
+}
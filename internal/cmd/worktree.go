@@ -279,8 +279,8 @@ func getGitStatusSummary(dir string) string {
 		return "clean"
 	}
 
-	// Count uncommitted files (modified, added, deleted, untracked)
-	uncommitted := len(status.Modified) + len(status.Added) + len(status.Deleted) + len(status.Untracked)
+	// Count uncommitted files (modified, added, deleted, renamed, untracked)
+	uncommitted := len(status.Modified) + len(status.Added) + len(status.Deleted) + len(status.Renamed) + len(status.Untracked)
 
 	return fmt.Sprintf("%d uncommitted", uncommitted)
 }
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/constants"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Worktree reap command flags
+var (
+	worktreeReapRig       string
+	worktreeReapInto      string
+	worktreeReapDryRun    bool
+	worktreeReapKeepDirty bool
+	worktreeReapYes       bool
+)
+
+var worktreeReapCmd = &cobra.Command{
+	Use:   "reap",
+	Short: "Remove worktrees whose branches are merged",
+	Long: `Remove stale worktrees left behind after their work was merged.
+
+A worktree is reaped when its branch is fully merged into the default
+branch (or --into) and it has no uncommitted or untracked changes. Its
+branch is deleted along with it. The main worktree, locked worktrees,
+detached worktrees and the one you are in are never reaped.
+
+Works on the repository containing the current directory, or with --rig
+on the rig's shared repository, where polecat worktrees are created.
+
+Examples:
+  gt worktree reap --dry-run            # List what would be reaped
+  gt worktree reap --rig gastown        # Reap merged polecat worktrees
+  gt worktree reap --into origin/main   # Merged into the remote branch
+  gt worktree reap --keep-dirty=false   # Also reap merged but dirty worktrees`,
+	Args: cobra.NoArgs,
+	RunE: runWorktreeReap,
+}
+
+func init() {
+	worktreeReapCmd.Flags().StringVar(&worktreeReapRig, "rig", "", "Reap worktrees of this rig's repository")
+	worktreeReapCmd.Flags().StringVar(&worktreeReapInto, "into", "", "Branch the work must be merged into (default: the default branch)")
+	worktreeReapCmd.Flags().BoolVarP(&worktreeReapDryRun, "dry-run", "n", false, "List the worktrees that would be reaped")
+	worktreeReapCmd.Flags().BoolVar(&worktreeReapKeepDirty, "keep-dirty", true, "Never reap worktrees with uncommitted or untracked changes")
+	worktreeReapCmd.Flags().BoolVarP(&worktreeReapYes, "yes", "y", false, "Don't ask for confirmation")
+	worktreeCmd.AddCommand(worktreeReapCmd)
+}
+
+// reapCandidate is a worktree whose branch is merged.
+type reapCandidate struct {
+	Path   string
+	Branch string
+	Dirty  bool // Has uncommitted or untracked changes
+}
+
+func runWorktreeReap(cmd *cobra.Command, args []string) error {
+	g, err := reapRepo(worktreeReapRig)
+	if err != nil {
+		return err
+	}
+	into := worktreeReapInto
+	if into == "" {
+		into = g.DefaultBranch()
+	}
+
+	candidates, err := reapableWorktrees(g, into)
+	if err != nil {
+		return err
+	}
+
+	var reap []reapCandidate
+	for _, c := range candidates {
+		if c.Dirty && worktreeReapKeepDirty {
+			fmt.Printf("  %s %s (%s): has uncommitted changes, kept\n", style.Dim.Render("skip"), c.Path, c.Branch)
+			continue
+		}
+		reap = append(reap, c)
+	}
+	if len(reap) == 0 {
+		fmt.Printf("%s No merged worktrees to reap (merged into %s)\n", style.SuccessPrefix, into)
+		return nil
+	}
+
+	fmt.Printf("Worktrees merged into %s:\n", style.Bold.Render(into))
+	for _, c := range reap {
+		note := ""
+		if c.Dirty {
+			note = style.Warning.Render(" (uncommitted changes will be lost)")
+		}
+		fmt.Printf("  %s (%s)%s\n", c.Path, c.Branch, note)
+	}
+	if worktreeReapDryRun {
+		return nil
+	}
+	if !worktreeReapYes && !promptYesNo(fmt.Sprintf("Remove %d worktree(s) and their branches?", len(reap))) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	failed := 0
+	for _, c := range reap {
+		if err := g.WorktreeRemove(c.Path, c.Dirty); err != nil {
+			fmt.Printf("%s %s: %v\n", style.ErrorPrefix, c.Path, err)
+			failed++
+			continue
+		}
+		// Merged into `into`, which git's own -d check (against HEAD) doesn't know
+		if err := g.DeleteBranch(c.Branch, true); err != nil {
+			fmt.Printf("%s removed %s but not branch %s: %v\n", style.WarningPrefix, c.Path, c.Branch, err)
+			continue
+		}
+		fmt.Printf("%s Reaped %s (%s)\n", style.SuccessPrefix, c.Path, c.Branch)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d worktree(s) could not be removed", failed)
+	}
+	return nil
+}
+
+// reapRepo returns the repository to reap: the rig's shared bare repo
+// (.repo.git) or mayor/rig clone, or without a rig the one containing cwd.
+func reapRepo(rigName string) (*git.Git, error) {
+	if rigName == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("getting current directory: %w", err)
+		}
+		return git.NewGit(cwd), nil
+	}
+
+	_, r, err := getRig(rigName)
+	if err != nil {
+		return nil, fmt.Errorf("rig '%s' not found - run 'gt rig list' to see available rigs", rigName)
+	}
+	bareRepoPath := filepath.Join(r.Path, ".repo.git")
+	if info, err := os.Stat(bareRepoPath); err == nil && info.IsDir() {
+		return git.NewGitWithDir(bareRepoPath, ""), nil
+	}
+	return git.NewGit(constants.RigMayorPath(r.Path)), nil
+}
+
+// reapableWorktrees returns the linked worktrees whose branches are merged
+// into into. The main worktree, locked and detached worktrees, into's own
+// worktree and the worktree containing cwd are left out, as are worktrees
+// whose status can't be read (e.g. deleted directories awaiting prune).
+func reapableWorktrees(g *git.Git, into string) ([]reapCandidate, error) {
+	worktrees, err := g.WorktreeList()
+	if err != nil {
+		return nil, fmt.Errorf("listing worktrees: %w", err)
+	}
+	merged, err := g.BranchesMergedInto(into)
+	if err != nil {
+		return nil, fmt.Errorf("listing branches merged into %s: %w", into, err)
+	}
+	cwd, _ := os.Getwd()
+
+	var candidates []reapCandidate
+	for i, wt := range worktrees {
+		if i == 0 || wt.Locked || wt.Branch == "" || wt.Branch == into || !slices.Contains(merged, wt.Branch) {
+			continue
+		}
+		if rel, err := filepath.Rel(wt.Path, cwd); err == nil && filepath.IsLocal(rel) {
+			continue
+		}
+		clean, err := git.NewGit(wt.Path).IsClean()
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, reapCandidate{Path: wt.Path, Branch: wt.Branch, Dirty: !clean})
+	}
+	return candidates, nil
+}
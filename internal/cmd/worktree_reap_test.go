@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+func TestReapableWorktrees(t *testing.T) {
+	repo := initCommitTestRepo(t)
+	runGitIn(t, repo, "commit", "--allow-empty", "-q", "-m", "base")
+	runGitIn(t, repo, "branch", "-M", "main")
+
+	wtDir := t.TempDir()
+	add := func(name string) string {
+		t.Helper()
+		path := filepath.Join(wtDir, name)
+		runGitIn(t, repo, "worktree", "add", "-q", "-b", "polecat/"+name, path)
+		return path
+	}
+	merged := add("merged")
+	dirty := add("dirty")
+	unmerged := add("unmerged")
+	locked := add("locked")
+	runGitIn(t, unmerged, "commit", "--allow-empty", "-q", "-m", "unmerged work")
+	runGitIn(t, repo, "worktree", "lock", locked)
+	if err := os.WriteFile(filepath.Join(dirty, "notes.txt"), []byte("wip\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	got, err := reapableWorktrees(git.NewGit(repo), "main")
+	if err != nil {
+		t.Fatalf("reapableWorktrees: %v", err)
+	}
+	want := []reapCandidate{ // In git's worktree order, sorted by path
+		{Path: dirty, Branch: "polecat/dirty", Dirty: true},
+		{Path: merged, Branch: "polecat/merged"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reapableWorktrees = %+v, want %+v", got, want)
+	}
+
+	// The worktree you're in is never reaped
+	originalWd, _ := os.Getwd()
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(merged); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	got, err = reapableWorktrees(git.NewGit(repo), "main")
+	if err != nil {
+		t.Fatalf("reapableWorktrees: %v", err)
+	}
+	if len(got) != 1 || got[0].Path != dirty {
+		t.Errorf("from inside %s: got %+v, want only %s", merged, got, dirty)
+	}
+}
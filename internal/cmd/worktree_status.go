@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/git"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+// Worktree status command flags
+var (
+	worktreeStatusJSON bool
+)
+
+var worktreeStatusCmd = &cobra.Command{
+	Use:   "status [path]",
+	Short: "Show the git state of a worktree",
+	Long: `Show the git state of the worktree containing the current directory
+(or path): branch, staged, unstaged and untracked files, and how far the
+branch is ahead of or behind its upstream.
+
+With --json the state is printed as a JSON object for scripts, so they
+don't have to parse git's porcelain output. Ahead/behind counts are only
+present when the branch has an upstream.
+
+For the state of the town as a whole, see 'gt status'.
+
+Examples:
+  gt worktree status                  # Current worktree
+  gt worktree status --json           # As JSON
+  gt worktree status ~/gt/beads/crew/gastown-joe`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWorktreeStatus,
+}
+
+func init() {
+	worktreeStatusCmd.Flags().BoolVar(&worktreeStatusJSON, "json", false, "Output as JSON")
+	worktreeCmd.AddCommand(worktreeStatusCmd)
+}
+
+// WorktreeStatus is the git state of a worktree, as printed by
+// gt worktree status --json.
+type WorktreeStatus struct {
+	Path      string                `json:"path"`
+	Branch    string                `json:"branch"` // Empty when detached
+	Detached  bool                  `json:"detached"`
+	Clean     bool                  `json:"clean"`
+	Staged    []string              `json:"staged"`
+	Unstaged  []string              `json:"unstaged"`
+	Untracked []string              `json:"untracked"`
+	Renamed   []WorktreeRenamedFile `json:"renamed,omitempty"`
+	Upstream  string                `json:"upstream,omitempty"`
+	Ahead     *int                  `json:"ahead,omitempty"` // Nil without an upstream
+	Behind    *int                  `json:"behind,omitempty"`
+}
+
+// WorktreeRenamedFile is a staged rename or copy in a WorktreeStatus.
+type WorktreeRenamedFile struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Copied bool   `json:"copied,omitempty"`
+}
+
+func runWorktreeStatus(cmd *cobra.Command, args []string) error {
+	dir := ""
+	if len(args) > 0 {
+		dir = args[0]
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting current directory: %w", err)
+		}
+		dir = cwd
+	}
+
+	status, err := worktreeStatus(git.NewGit(dir))
+	if err != nil {
+		return err
+	}
+
+	if worktreeStatusJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+
+	branch := status.Branch
+	if status.Detached {
+		branch = style.Warning.Render("(detached HEAD)")
+	}
+	fmt.Printf("%s %s\n", style.Bold.Render(branch), style.Dim.Render(status.Path))
+	if status.Upstream != "" {
+		fmt.Printf("  Upstream:  %s (%d ahead, %d behind)\n", status.Upstream, *status.Ahead, *status.Behind)
+	}
+	if status.Clean {
+		fmt.Printf("  %s\n", style.Dim.Render("clean"))
+		return nil
+	}
+	for _, group := range []struct {
+		label string
+		paths []string
+	}{
+		{"Staged:   ", status.Staged},
+		{"Unstaged: ", status.Unstaged},
+		{"Untracked:", status.Untracked},
+	} {
+		if len(group.paths) > 0 {
+			fmt.Printf("  %s %s\n", group.label, strings.Join(group.paths, ", "))
+		}
+	}
+	return nil
+}
+
+// worktreeStatus reads the git state of g's worktree. The file lists are
+// never nil, so they encode as [] rather than null.
+func worktreeStatus(g *git.Git) (WorktreeStatus, error) {
+	root, err := g.RepoRoot()
+	if err != nil {
+		return WorktreeStatus{}, fmt.Errorf("not in a git worktree: %w", err)
+	}
+	gitStatus, err := g.Status()
+	if err != nil {
+		return WorktreeStatus{}, fmt.Errorf("reading git status: %w", err)
+	}
+
+	status := WorktreeStatus{
+		Path:      root,
+		Clean:     gitStatus.Clean,
+		Staged:    append([]string{}, gitStatus.Staged...),
+		Unstaged:  append([]string{}, gitStatus.Unstaged...),
+		Untracked: append([]string{}, gitStatus.Untracked...),
+	}
+	for _, r := range gitStatus.Renamed {
+		status.Renamed = append(status.Renamed, WorktreeRenamedFile{From: r.From, To: r.To, Copied: r.Copied})
+	}
+
+	branch, err := g.CurrentBranch()
+	switch {
+	case errors.Is(err, git.ErrDetachedHead):
+		status.Detached = true
+	case err != nil:
+		return WorktreeStatus{}, fmt.Errorf("reading current branch: %w", err)
+	default:
+		status.Branch = branch
+	}
+
+	// Ahead/behind is best-effort: no upstream just leaves it out
+	if upstream, err := g.Upstream(); err == nil {
+		if ahead, behind, err := g.AheadBehind(upstream); err == nil {
+			status.Upstream = upstream
+			status.Ahead, status.Behind = &ahead, &behind
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/git"
+)
+
+func TestWorktreeStatus(t *testing.T) {
+	remote := t.TempDir()
+	runGitIn(t, remote, "init", "-q", "--bare")
+	repo := initCommitTestRepo(t)
+	runGitIn(t, repo, "commit", "--allow-empty", "-q", "-m", "base")
+	runGitIn(t, repo, "branch", "-M", "main")
+
+	status, err := worktreeStatus(git.NewGit(repo))
+	if err != nil {
+		t.Fatalf("worktreeStatus: %v", err)
+	}
+	out, _ := json.Marshal(status)
+	// Empty lists encode as [], and counts are left out without an upstream
+	if want := `"staged":[],"unstaged":[],"untracked":[]}`; !strings.HasSuffix(string(out), want) {
+		t.Errorf("JSON = %s, want it to end with %s", out, want)
+	}
+
+	runGitIn(t, repo, "remote", "add", "origin", remote)
+	runGitIn(t, repo, "push", "-q", "-u", "origin", "main")
+	for _, name := range []string{"staged.txt", "both.txt"} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte("one\n"), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	runGitIn(t, repo, "add", "staged.txt", "both.txt")
+	runGitIn(t, repo, "commit", "-q", "-m", "ahead", "--", "both.txt")
+	if err := os.WriteFile(filepath.Join(repo, "both.txt"), []byte("two\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "new.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	status, err = worktreeStatus(git.NewGit(repo))
+	if err != nil {
+		t.Fatalf("worktreeStatus: %v", err)
+	}
+	one, zero := 1, 0
+	want := WorktreeStatus{
+		Path:      status.Path,
+		Branch:    "main",
+		Staged:    []string{"staged.txt"},
+		Unstaged:  []string{"both.txt"},
+		Untracked: []string{"new.txt"},
+		Upstream:  "origin/main",
+		Ahead:     &one,
+		Behind:    &zero,
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("worktreeStatus = %+v, want %+v", status, want)
+	}
+
+	runGitIn(t, repo, "checkout", "-q", "--detach")
+	if status, err = worktreeStatus(git.NewGit(repo)); err != nil || !status.Detached || status.Branch != "" {
+		t.Errorf("detached: worktreeStatus = %+v, %v", status, err)
+	}
+}
@@ -61,6 +61,56 @@ type TownSettings struct {
 	// Agent addresses like "gastown/crew/jack" become "gastown.crew.jack@{domain}".
 	// Default: "gastown.local"
 	AgentEmailDomain string `json:"agent_email_domain,omitempty"`
+
+	// Commit configures the behavior of `gt commit` for agents.
+	Commit *CommitSettings `json:"commit,omitempty"`
+}
+
+// CommitSettings configures `gt commit` town-wide.
+type CommitSettings struct {
+	// SubjectFormat seeds the commit subject from the pinned molecule when
+	// --seed-from-molecule is used. Supports {id} and {title} placeholders.
+	// Default: "{id}: {title}"
+	SubjectFormat string `json:"subject_format,omitempty"`
+
+	// VersionTrailer adds a "Generated-By: gastown/<version>" trailer to agent
+	// commits, as if --version-trailer were always passed.
+	VersionTrailer bool `json:"version_trailer,omitempty"`
+
+	// AuthorNameFormat and AuthorEmailFormat set the commit author for
+	// --author-from-identity. Placeholders: {identity}, {rig}, {role}, {name}.
+	// Defaults: "{identity}" and "{name}@{rig}.agents"
+	AuthorNameFormat  string `json:"author_name_format,omitempty"`
+	AuthorEmailFormat string `json:"author_email_format,omitempty"`
+
+	// TicketPattern extracts a ticket reference from the branch name for
+	// --ticket-from-branch. The first capture group is used if present,
+	// otherwise the whole match. Default: "[A-Z][A-Z0-9]+-[0-9]+"
+	TicketPattern string `json:"ticket_pattern,omitempty"`
+
+	// TicketTrailer is the trailer key for the extracted ticket.
+	// Default: "Refs"
+	TicketTrailer string `json:"ticket_trailer,omitempty"`
+
+	// MaxMessageBytes limits the size of agent commit messages (before
+	// trailers). Default: 65536
+	MaxMessageBytes int `json:"max_message_bytes,omitempty"`
+
+	// TruncateMessage truncates oversized messages, keeping the subject and
+	// trailers, instead of rejecting the commit.
+	TruncateMessage bool `json:"truncate_message,omitempty"`
+
+	// SecretAllowlist holds regexps for gt commit --scan-secrets; findings
+	// whose text or file path matches one are not reported.
+	SecretAllowlist []string `json:"secret_allowlist,omitempty"`
+
+	// Conventional rejects commits whose subject isn't a Conventional
+	// Commit ("type(scope): description"), as if --conventional were
+	// always passed. ConventionalTypes lists the allowed types.
+	// Default types: feat, fix, docs, style, refactor, perf, test, build,
+	// ci, chore, revert
+	Conventional      bool     `json:"conventional,omitempty"`
+	ConventionalTypes []string `json:"conventional_types,omitempty"`
 }
 
 // NewTownSettings creates a new TownSettings with defaults.
@@ -0,0 +1,104 @@
+package git
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Trailer keys for agent attribution, as written by AgentTrailers.
+const (
+	TrailerExecutedBy     = "Executed-By"
+	TrailerRig            = "Rig"
+	TrailerRole           = "Role"
+	TrailerMolecule       = "Molecule"
+	TrailerMoleculeStatus = "Molecule-Status"
+	TrailerBranch         = "Branch"
+	TrailerGeneratedBy    = "Generated-By"
+	TrailerHost           = "Host"
+	TrailerPID            = "PID"
+	TrailerSessionID      = "Session-Id"
+	TrailerCommittedAt    = "Committed-At"
+	TrailerCoAuthoredBy   = "Co-authored-by" // GitHub's casing, so it credits the co-author
+)
+
+// Attribution describes the agent that produced a commit. Empty fields
+// get no trailer.
+type Attribution struct {
+	Identity       string    // Agent address, e.g. "gastown/crew/jack"
+	Rig            string    // Rig the agent works in
+	Role           string    // Agent role, e.g. "polecat"
+	Molecules      []string  // Pinned molecules, a trailer each
+	MoleculeStatus string    // Molecule status, reduced to a token ("In Progress" → in-progress)
+	Branch         string    // Branch the commit was made on
+	Host           string    // Host the agent runs on
+	PID            int       // Agent process ID
+	SessionID      string    // Agent runtime session
+	GeneratedBy    string    // Tool and version, e.g. "gastown/v1.2.0"
+	CommittedAt    time.Time // Recorded in UTC
+}
+
+// AgentTrailers returns the "Key: value" trailers for a, identity first
+// so agent commits keep a stable trailer order. Pass them to
+// CommitWithTrailers to make an agent-attributed commit.
+func AgentTrailers(a Attribution) []string {
+	var trailers []string
+	add := func(key, value string) {
+		if value != "" {
+			trailers = append(trailers, key+": "+value)
+		}
+	}
+
+	add(TrailerExecutedBy, strings.TrimSuffix(a.Identity, "/"))
+	add(TrailerRig, a.Rig)
+	add(TrailerRole, a.Role)
+	for _, id := range a.Molecules {
+		add(TrailerMolecule, id)
+	}
+	if len(a.Molecules) > 0 {
+		add(TrailerMoleculeStatus, sanitizeTrailerToken(a.MoleculeStatus))
+	}
+	add(TrailerBranch, a.Branch)
+	add(TrailerHost, sanitizeTrailerValue(a.Host))
+	if a.PID > 0 {
+		add(TrailerPID, strconv.Itoa(a.PID))
+	}
+	add(TrailerSessionID, sanitizeTrailerValue(a.SessionID))
+	add(TrailerGeneratedBy, a.GeneratedBy)
+	if !a.CommittedAt.IsZero() {
+		add(TrailerCommittedAt, a.CommittedAt.UTC().Format(time.RFC3339))
+	}
+	return trailers
+}
+
+// sanitizeTrailerToken reduces a value to a single lowercase token that is
+// safe to use as a trailer value: "In Progress\n" → "in-progress".
+func sanitizeTrailerToken(value string) string {
+	var b strings.Builder
+	lastDash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(value)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '.':
+			b.WriteRune(r)
+			lastDash = false
+		case !lastDash && b.Len() > 0:
+			b.WriteRune('-')
+			lastDash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// sanitizeTrailerValue makes an arbitrary string safe as a single-line
+// trailer value: control characters become spaces and runs of whitespace
+// collapse to one space.
+func sanitizeTrailerValue(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, value)
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,83 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAgentTrailers(t *testing.T) {
+	got := AgentTrailers(Attribution{
+		Identity:       "gastown/polecats/toast/",
+		Rig:            "gastown",
+		Role:           "polecat",
+		Molecules:      []string{"gt-abc", "gt-def"},
+		MoleculeStatus: "In Progress",
+		Branch:         "polecat/toast",
+		Host:           "build\nhost",
+		PID:            42,
+		GeneratedBy:    "gastown/v1.2.0",
+		CommittedAt:    time.Date(2026, 1, 2, 10, 4, 5, 0, time.FixedZone("PST", -8*3600)),
+	})
+	want := []string{
+		"Executed-By: gastown/polecats/toast",
+		"Rig: gastown",
+		"Role: polecat",
+		"Molecule: gt-abc",
+		"Molecule: gt-def",
+		"Molecule-Status: in-progress",
+		"Branch: polecat/toast",
+		"Host: build host",
+		"PID: 42",
+		"Generated-By: gastown/v1.2.0",
+		"Committed-At: 2026-01-02T18:04:05Z",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AgentTrailers = %q, want %q", got, want)
+	}
+
+	// Empty fields are left out; a status needs a molecule
+	got = AgentTrailers(Attribution{Identity: "mayor/", MoleculeStatus: "open"})
+	if want := []string{"Executed-By: mayor"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AgentTrailers = %q, want %q", got, want)
+	}
+}
+
+func TestSanitizeTrailerToken(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"in_progress", "in_progress"},
+		{"Closed", "closed"},
+		{"  In Progress\n", "in-progress"},
+		{"hooked: yes!", "hooked-yes"},
+		{"", ""},
+		{"???", ""},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeTrailerToken(tt.value); got != tt.want {
+			t.Errorf("sanitizeTrailerToken(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeTrailerValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"build-7", "build-7"},
+		{"  padded  ", "padded"},
+		{"two\nlines", "two lines"},
+		{"tab\tand\x00nul", "tab and nul"},
+		{"\n\t", ""},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeTrailerValue(tt.value); got != tt.want {
+			t.Errorf("sanitizeTrailerValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -3,23 +3,36 @@ package git
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // GitError contains raw output from a git command for agent observation.
 // ZFC: Callers observe the raw output and decide what to do.
 // The error interface methods provide human-readable messages, but agents
 // should use Stdout/Stderr for programmatic observation.
+//
+// Recognized failures are returned as a sentinel wrapping the GitError
+// (e.g. ErrNothingToCommit), so errors.Is finds the category and errors.As
+// the GitError, or the *exec.ExitError it wraps, for the detail.
 type GitError struct {
-	Command string // The git command that failed (e.g., "merge", "push")
-	Args    []string
-	Stdout  string // Raw stdout output
-	Stderr  string // Raw stderr output
-	Err     error  // Underlying error (e.g., exit code)
+	Command  string // The git command that failed (e.g., "merge", "push")
+	Args     []string
+	ExitCode int    // Git's exit code; -1 if it didn't exit (not started, or killed)
+	Stdout   string // Raw stdout output
+	Stderr   string // Raw stderr output
+	Err      error  // Underlying error (e.g., *exec.ExitError)
 }
 
 func (e *GitError) Error() string {
@@ -33,17 +46,254 @@ func (e *GitError) Unwrap() error {
 	return e.Err
 }
 
+// Sentinel errors returned (possibly wrapped) by Git methods.
+var (
+	// ErrNoMergeBase is returned when two refs have unrelated histories.
+	ErrNoMergeBase = errors.New("no common ancestor (unrelated histories)")
+
+	// ErrNotFound is returned when a history query matches no commit.
+	ErrNotFound = errors.New("not found")
+
+	// ErrSigningKeyMissing is returned when signing fails because the
+	// signing key is not available.
+	ErrSigningKeyMissing = errors.New("signing key not available")
+
+	// ErrSigningPassphrase is returned when signing fails because the key's
+	// passphrase could not be obtained, typically in a headless environment.
+	ErrSigningPassphrase = errors.New("signing key passphrase unavailable")
+
+	// ErrNothingToStash is returned by Stash when there are no local
+	// changes to save.
+	ErrNothingToStash = errors.New("no local changes to stash")
+
+	// ErrNothingToCommit is returned by a commit that has no changes to
+	// record, e.g. Commit with nothing staged or CommitAll on a clean tree.
+	ErrNothingToCommit = errors.New("nothing to commit")
+
+	// ErrNoUpstream is returned when the current branch has no upstream
+	// (tracking) branch configured, or HEAD is detached.
+	ErrNoUpstream = errors.New("no upstream branch")
+
+	// ErrDetachedHead is returned by CurrentBranch when HEAD is detached,
+	// i.e. not on any branch.
+	ErrDetachedHead = errors.New("HEAD is detached")
+
+	// ErrDestinationExists is returned by a clone whose destination is an
+	// existing file or non-empty directory.
+	ErrDestinationExists = errors.New("clone destination already exists")
+
+	// ErrWorktreeDirty is returned by WorktreeRemove without force when the
+	// worktree has modified or untracked files.
+	ErrWorktreeDirty = errors.New("worktree has uncommitted changes")
+
+	// ErrMergeConflict is returned when Merge, Rebase or CherryPick stops
+	// on conflicts. The operation is left in progress: resolve the
+	// conflicts (see ConflictedPaths and ConflictedFiles) and continue it,
+	// or abort it (AbortMerge, AbortRebase, CherryPickAbort).
+	ErrMergeConflict = errors.New("merge conflict")
+
+	// ErrProtectedBranch is returned by a force push to a protected branch
+	// (see WithProtectedBranches) without PushOptions.AllowProtected.
+	ErrProtectedBranch = errors.New("refusing to force-push a protected branch")
+
+	// ErrNoSuchRemote is returned when a named remote isn't configured,
+	// e.g. by RemoteURL.
+	ErrNoSuchRemote = errors.New("no such remote")
+
+	// ErrBranchCheckedOut is returned by CommitToBranch when the branch is
+	// checked out in a worktree, whose index would no longer match it.
+	ErrBranchCheckedOut = errors.New("branch is checked out")
+)
+
 // Git wraps git operations for a working directory.
 type Git struct {
 	workDir string
 	gitDir  string // Optional: explicit git directory (for bare repos)
+	subdir  string // Optional: base for relative pathspecs (see WithSubdir)
+
+	ctx      context.Context // Optional: cancels running commands (see NewGitContext)
+	recorder *Recorder       // Optional: captures executed commands
+
+	retryAttempts int           // Optional: tries for transient network failures (see WithRetry)
+	retryBackoff  time.Duration // Wait before the first retry, doubled for each one after
+
+	protectedBranches []string // Optional: branch patterns never force-pushed; nil means DefaultProtectedBranches
 }
 
+// commandWaitDelay bounds how long a canceled command may take to release
+// its output pipes, e.g. when a remote helper outlives the killed git.
+const commandWaitDelay = 2 * time.Second
+
 // NewGit creates a new Git wrapper for the given directory.
 func NewGit(workDir string) *Git {
 	return &Git{workDir: workDir}
 }
 
+// NewGitContext creates a Git wrapper whose commands are bound to ctx:
+// when ctx is canceled or its deadline passes, the running git process is
+// killed and the method returns an error wrapping ctx.Err(), so callers
+// can check errors.Is(err, context.DeadlineExceeded). On Unix, git runs in
+// its own process group, killed as a whole, so it doesn't get the
+// terminal's Ctrl-C; cancel ctx instead.
+func NewGitContext(ctx context.Context, workDir string) *Git {
+	return &Git{workDir: workDir, ctx: ctx}
+}
+
+// WithContext returns a copy of g whose commands are bound to ctx, as with
+// NewGitContext, e.g. to put a timeout on a single Fetch or Pull.
+func (g *Git) WithContext(ctx context.Context) *Git {
+	clone := *g
+	clone.ctx = ctx
+	return &clone
+}
+
+// WithRetry returns a copy of g that retries network commands (fetch,
+// pull, push, ls-remote, submodule update and clone --recurse-submodules)
+// failing with transient network errors, such as an unresolvable host, a
+// timeout or a dropped connection, up to attempts
+// tries in all. It waits backoff before the first retry and doubles the
+// wait for each one after. Other failures (authentication, conflicts,
+// rejected pushes, local errors) are returned at once. A retry that would
+// outlast g's context deadline isn't attempted; the last error is returned.
+func (g *Git) WithRetry(attempts int, backoff time.Duration) *Git {
+	clone := *g
+	clone.retryAttempts = attempts
+	clone.retryBackoff = backoff
+	return &clone
+}
+
+// DefaultProtectedBranches are the branches a Git refuses to force-push
+// to unless WithProtectedBranches says otherwise.
+var DefaultProtectedBranches = []string{"main", "master"}
+
+// WithProtectedBranches returns a copy of g that refuses to force-push to
+// branches matching patterns (path.Match syntax, e.g. "release/*"),
+// instead of DefaultProtectedBranches. No patterns protects nothing.
+func (g *Git) WithProtectedBranches(patterns ...string) *Git {
+	clone := *g
+	clone.protectedBranches = append([]string{}, patterns...)
+	return &clone
+}
+
+// isProtectedBranch reports whether branch matches a protected pattern.
+func (g *Git) isProtectedBranch(branch string) bool {
+	patterns := g.protectedBranches
+	if patterns == nil {
+		patterns = DefaultProtectedBranches
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// networkCommands are the git commands WithRetry applies to. Of the
+// submodule subcommands only update reaches the network; see isRetryable.
+var networkCommands = map[string]bool{"fetch": true, "pull": true, "push": true, "ls-remote": true}
+
+// isRetryable reports whether WithRetry applies to the git command args.
+func isRetryable(args []string) bool {
+	i := commandIndex(args)
+	if i < 0 {
+		return false
+	}
+	if args[i] == "submodule" {
+		rest := args[i+1:]
+		j := commandIndex(rest)
+		return j >= 0 && rest[j] == "update"
+	}
+	return networkCommands[args[i]]
+}
+
+// transientErrors are stderr fragments (lowercased) of network failures
+// that may succeed when retried.
+var transientErrors = []string{
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"connection timed out",
+	"operation timed out",
+	"connection refused",
+	"connection reset",
+	"network is unreachable",
+	"rpc failed",
+	"early eof",
+	"the remote end hung up unexpectedly",
+	"unexpected disconnect",
+	"ssl_error_syscall",
+	"gnutls_handshake",
+	"returned error: 502",
+	"returned error: 503",
+	"returned error: 504",
+}
+
+// authErrors are stderr fragments (lowercased) of authentication failures,
+// which are never retried even alongside a transient-looking message.
+var authErrors = []string{
+	"authentication failed",
+	"permission denied",
+	"could not read username",
+	"could not read password",
+	"returned error: 401",
+	"returned error: 403",
+}
+
+// isTransientError reports whether err is a git failure worth retrying.
+func (g *Git) isTransientError(err error) bool {
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) || g.context().Err() != nil {
+		return false
+	}
+	stderr := strings.ToLower(gitErr.Stderr)
+	for _, s := range authErrors {
+		if strings.Contains(stderr, s) {
+			return false
+		}
+	}
+	for _, s := range transientErrors {
+		if strings.Contains(stderr, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitRetry waits d before a retry. It returns false, without waiting, if
+// g's context deadline is less than d away, or early if the context ends.
+func (g *Git) waitRetry(d time.Duration) bool {
+	ctx := g.context()
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// context returns the context commands run under; Background if unset.
+func (g *Git) context() context.Context {
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
+}
+
+// command builds a git command bound to g's context.
+func (g *Git) command(args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(g.context(), Binary(), args...)
+	if g.ctx != nil {
+		killProcessGroupOnCancel(cmd)
+	}
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
+
 // NewGitWithDir creates a Git wrapper with an explicit git directory.
 // This is used for bare repos where gitDir points to the .git directory
 // and workDir may be empty or point to a worktree.
@@ -56,93 +306,378 @@ func (g *Git) WorkDir() string {
 	return g.workDir
 }
 
+// WithSubdir returns a copy of g whose methods taking pathspecs (Add,
+// StagedDiff, LFSTrackedFiles, IntroducedBy, ...) resolve relative paths
+// against subdir, a directory relative to the repository root, instead of
+// the root. Absolute paths and top-anchored pathspecs (":/path",
+// ":(top)path") are left alone. Paths in results stay root-relative.
+func (g *Git) WithSubdir(subdir string) *Git {
+	clone := *g
+	clone.subdir = path.Clean(filepath.ToSlash(subdir))
+	if clone.subdir == "." {
+		clone.subdir = ""
+	}
+	return &clone
+}
+
+// pathspecs resolves caller paths against the subdir set by WithSubdir.
+func (g *Git) pathspecs(paths []string) []string {
+	if g.subdir == "" {
+		return paths
+	}
+	resolved := make([]string, len(paths))
+	for i, p := range paths {
+		resolved[i] = resolvePathspec(g.subdir, p)
+	}
+	return resolved
+}
+
+// resolvePathspec prefixes a relative pathspec with subdir, keeping any
+// pathspec magic (":!path", ":(glob)path") in front.
+func resolvePathspec(subdir, spec string) string {
+	if filepath.IsAbs(spec) || strings.HasPrefix(spec, "/") {
+		return spec
+	}
+	if !strings.HasPrefix(spec, ":") {
+		return path.Join(subdir, spec)
+	}
+
+	// Long form ":(magic,...)path"
+	if strings.HasPrefix(spec, ":(") {
+		end := strings.Index(spec, ")")
+		if end < 0 {
+			return spec // Malformed; let git report it
+		}
+		for _, magic := range strings.Split(spec[2:end], ",") {
+			if magic == "top" {
+				return spec
+			}
+		}
+		return spec[:end+1] + path.Join(subdir, spec[end+1:])
+	}
+
+	// Short form ":<magic chars>[:]path", where "/" means top-anchored
+	rest := spec[1:]
+	magicEnd := 0
+	for magicEnd < len(rest) && strings.ContainsRune("/!^", rune(rest[magicEnd])) {
+		if rest[magicEnd] == '/' {
+			return spec
+		}
+		magicEnd++
+	}
+	magic, p := rest[:magicEnd], rest[magicEnd:]
+	if strings.HasPrefix(p, ":") {
+		magic, p = magic+":", p[1:]
+	}
+	return ":" + magic + path.Join(subdir, p)
+}
+
 // IsRepo returns true if the workDir is a git repository.
 func (g *Git) IsRepo() bool {
 	_, err := g.run("rev-parse", "--git-dir")
 	return err == nil
 }
 
+// RepoRoot returns the top-level directory of the working tree.
+func (g *Git) RepoRoot() (string, error) {
+	return g.run("rev-parse", "--show-toplevel")
+}
+
 // run executes a git command and returns stdout.
 func (g *Git) run(args ...string) (string, error) {
+	out, err := g.runRaw(args...)
+	return strings.TrimSpace(out), err
+}
+
+// runRaw executes a git command and returns stdout untrimmed.
+// Use this for output where leading whitespace or NUL separators are
+// significant (e.g. porcelain -z formats).
+func (g *Git) runRaw(args ...string) (string, error) {
+	return g.runCmd(nil, nil, args...)
+}
+
+// runCmd is runRaw with extra environment variables (e.g. GIT_INDEX_FILE)
+// and stdin for the git process. Either may be nil.
+func (g *Git) runCmd(env []string, stdin io.Reader, args ...string) (string, error) {
+	stdout, _, err := g.runCmdStderr(env, stdin, args...)
+	return stdout, err
+}
+
+// runCmdStderr is runCmd that also returns stderr on success, for commands
+// that report there (e.g. verify-tag --raw). Network commands are retried
+// as configured by WithRetry.
+func (g *Git) runCmdStderr(env []string, stdin io.Reader, args ...string) (string, string, error) {
+	// Buffer stdin so the recorder keeps a copy for Replay, and fix the
+	// dates a replayed commit would otherwise take from the clock
+	if g.recorder != nil {
+		env = pinCommitDates(env, args, time.Now())
+	}
+	if stdin != nil && g.recorder != nil {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", "", fmt.Errorf("reading input for git %s: %w", commandName(args), err)
+		}
+		g.recorder.record(args, input, env)
+		stdin = bytes.NewReader(input)
+	} else {
+		g.recorder.record(args, nil, env)
+	}
+
+	stdout, stderr, err := g.runOnce(env, stdin, args...)
+	// stdin can't be replayed, so commands reading it aren't retried
+	if err == nil || stdin != nil || !isRetryable(args) {
+		return stdout, stderr, err
+	}
+	delay := g.retryBackoff
+	for attempt := 1; attempt < g.retryAttempts && g.isTransientError(err); attempt++ {
+		if !g.waitRetry(delay) {
+			break
+		}
+		delay *= 2
+		stdout, stderr, err = g.runOnce(env, stdin, args...)
+	}
+	return stdout, stderr, err
+}
+
+// runOnce runs a git command once; see runCmdStderr.
+func (g *Git) runOnce(env []string, stdin io.Reader, args ...string) (string, string, error) {
 	// If gitDir is set (bare repo), prepend --git-dir flag
 	if g.gitDir != "" {
 		args = append([]string{"--git-dir=" + g.gitDir}, args...)
 	}
 
-	cmd := exec.Command("git", args...)
+	cmd := g.command(args...)
 	if g.workDir != "" {
 		cmd.Dir = g.workDir
 	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdin = stdin
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", "", ErrGitNotFound
+	}
 	if err != nil {
-		return "", g.wrapError(err, stdout.String(), stderr.String(), args)
+		return "", "", g.wrapError(err, stdout.String(), stderr.String(), args)
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	return stdout.String(), stderr.String(), nil
 }
 
 // wrapError wraps git errors with context.
 // ZFC: Returns GitError with raw output for agent observation.
-// Does not detect or interpret error types - agents should observe and decide.
+// A few well-known failures are also classified with a sentinel:
+// ErrNothingToCommit, ErrDestinationExists, and ErrNoSuchRemote.
+// The GitError stays reachable through errors.As either way.
 func (g *Git) wrapError(err error, stdout, stderr string, args []string) error {
 	stdout = strings.TrimSpace(stdout)
 	stderr = strings.TrimSpace(stderr)
 
-	// Determine command name (first arg, or first non-flag arg)
-	command := ""
-	for _, arg := range args {
-		if !strings.HasPrefix(arg, "-") {
-			command = arg
-			break
-		}
+	command := commandName(args)
+
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	// A killed process only reports "signal: killed"; say why
+	if ctxErr := g.context().Err(); ctxErr != nil {
+		err = fmt.Errorf("%w (%v)", ctxErr, err)
 	}
-	if command == "" && len(args) > 0 {
-		command = args[0]
+
+	gitErr := &GitError{
+		Command:  command,
+		Args:     args,
+		ExitCode: exitCode,
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Err:      err,
+	}
+	switch {
+	case command == "commit" && exitCode == 1 && isNothingToCommit(stdout+"\n"+stderr):
+		return fmt.Errorf("%w: %w", ErrNothingToCommit, gitErr)
+	case command == "clone" && strings.Contains(stderr, "already exists and is not an empty directory"):
+		return fmt.Errorf("%w: %w", ErrDestinationExists, gitErr)
+	case command == "remote" && strings.Contains(stderr, "No such remote"):
+		return fmt.Errorf("%w: %w", ErrNoSuchRemote, gitErr)
+	}
+	return gitErr
+}
+
+// commandName returns the git command in args: the first non-flag arg
+// (skipping the values of -c and -C), or the first arg if all are flags.
+func commandName(args []string) string {
+	if i := commandIndex(args); i >= 0 {
+		return args[i]
+	}
+	if len(args) > 0 {
+		return args[0]
 	}
+	return ""
+}
 
-	return &GitError{
-		Command: command,
-		Args:    args,
-		Stdout:  stdout,
-		Stderr:  stderr,
-		Err:     err,
+// commandIndex returns the index of the first non-option arg, skipping
+// the values of -c and -C, or -1 if there is none.
+func commandIndex(args []string) int {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-c", arg == "-C":
+			i++
+		case !strings.HasPrefix(arg, "-"):
+			return i
+		}
 	}
+	return -1
+}
+
+// isNothingToCommit reports whether git commit output says there was
+// nothing to commit. Git prints this on stdout and exits 1.
+func isNothingToCommit(output string) bool {
+	return strings.Contains(output, "nothing to commit") ||
+		strings.Contains(output, "nothing added to commit") ||
+		strings.Contains(output, "no changes added to commit")
+}
+
+// CloneOptions configures CloneWithOptions.
+type CloneOptions struct {
+	Branch       string // Branch to check out instead of the remote's default
+	Depth        int    // Shallow clone of this many commits; 0 for full history. Local paths need a file:// URL
+	SingleBranch bool   // Fetch only Branch (or the remote's default), now and on later fetches
+	Bare         bool   // Bare repository, set up to fetch origin/* refs as CloneBare does
+
+	// RecurseSubmodules also initializes and clones the submodules,
+	// recursively, as SubmoduleUpdate(true, true) would. Ignored with Bare.
+	RecurseSubmodules bool
+
+	// Progress, if set, receives git's progress output (--progress) as the
+	// clone runs. It is still captured for the error if the clone fails.
+	Progress io.Writer
 }
 
 // Clone clones a repository to the destination.
 func (g *Git) Clone(url, dest string) error {
-	cmd := exec.Command("git", "clone", url, dest)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return g.wrapError(err, stdout.String(), stderr.String(), []string{"clone", url})
+	return g.CloneWithOptions(url, dest, CloneOptions{})
+}
+
+// CloneWithOptions clones a repository to the destination with options.
+func (g *Git) CloneWithOptions(url, dest string, opts CloneOptions) error {
+	args := []string{"clone"}
+	if opts.Branch != "" {
+		args = append(args, "--branch", opts.Branch)
+	}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if opts.Bare {
+		args = append(args, "--bare")
+	} else if opts.RecurseSubmodules {
+		args = append(args, "--recurse-submodules")
+	}
+	if opts.Progress != nil {
+		args = append(args, "--progress")
+	}
+	args = append(args, url, dest)
+	err := g.runClone(args, opts.Progress)
+	if err != nil && opts.RecurseSubmodules && !opts.Bare {
+		err = g.retryRecursiveClone(err, args, dest, opts.Progress)
+	}
+	if err != nil {
+		return err
+	}
+	if opts.Bare {
+		if opts.SingleBranch {
+			return nil // Keep the single-branch refspec
+		}
+		return configureRefspec(g.context(), dest)
 	}
 	// Configure hooks path for Gas Town clones
-	if err := configureHooksPath(dest); err != nil {
+	if err := configureHooksPath(g.context(), dest); err != nil {
 		return err
 	}
 	// Configure sparse checkout to exclude .claude/ from source repo
 	return ConfigureSparseCheckout(dest)
 }
 
+// SubmoduleUpdate checks out the commits the superproject records for its
+// submodules, cloning them as needed. init first registers submodules not
+// yet initialized (otherwise they are skipped); recursive does the same in
+// nested submodules.
+func (g *Git) SubmoduleUpdate(init, recursive bool) error {
+	args := []string{"submodule", "update"}
+	if init {
+		args = append(args, "--init")
+	}
+	if recursive {
+		args = append(args, "--recursive")
+	}
+	_, err := g.run(args...)
+	return err
+}
+
+// SubmoduleEntry is a submodule as reported by SubmoduleStatus.
+type SubmoduleEntry struct {
+	Path          string // Relative to the repository root
+	SHA           string // Checked-out commit, or the recorded one if uninitialized
+	Uninitialized bool   // Not initialized, so its directory is empty
+	Modified      bool   // Checked-out commit differs from the one the superproject records
+	Conflicted    bool   // Has merge conflicts
+}
+
+// SubmoduleStatus returns the repository's submodules (not nested ones),
+// or an empty slice if it has none.
+func (g *Git) SubmoduleStatus() ([]SubmoduleEntry, error) {
+	out, err := g.runRaw("submodule", "status")
+	if err != nil {
+		return nil, err
+	}
+	return parseSubmoduleStatus(out), nil
+}
+
+// parseSubmoduleStatus parses `git submodule status` lines: a state
+// character (' ', '-' uninitialized, '+' modified, 'U' conflicted), the
+// SHA, the path, and for checked-out submodules " (<describe>)".
+func parseSubmoduleStatus(out string) []SubmoduleEntry {
+	entries := []SubmoduleEntry{}
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		sha, path, ok := strings.Cut(line[1:], " ")
+		if !ok {
+			continue
+		}
+		if i := strings.LastIndex(path, " ("); i >= 0 && strings.HasSuffix(path, ")") {
+			path = path[:i]
+		}
+		entries = append(entries, SubmoduleEntry{
+			Path:          path,
+			SHA:           sha,
+			Uninitialized: line[0] == '-',
+			Modified:      line[0] == '+',
+			Conflicted:    line[0] == 'U',
+		})
+	}
+	return entries
+}
+
 // CloneWithReference clones a repository using a local repo as an object reference.
 // This saves disk by sharing objects without changing remotes.
 func (g *Git) CloneWithReference(url, dest, reference string) error {
-	cmd := exec.Command("git", "clone", "--reference-if-able", reference, url, dest)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return g.wrapError(err, stdout.String(), stderr.String(), []string{"clone", "--reference-if-able", url})
+	if err := g.runClone([]string{"clone", "--reference-if-able", reference, url, dest}, nil); err != nil {
+		return err
 	}
 	// Configure hooks path for Gas Town clones
-	if err := configureHooksPath(dest); err != nil {
+	if err := configureHooksPath(g.context(), dest); err != nil {
 		return err
 	}
 	// Configure sparse checkout to exclude .claude/ from source repo
@@ -152,31 +687,127 @@ func (g *Git) CloneWithReference(url, dest, reference string) error {
 // CloneBare clones a repository as a bare repo (no working directory).
 // This is used for the shared repo architecture where all worktrees share a single git database.
 func (g *Git) CloneBare(url, dest string) error {
-	cmd := exec.Command("git", "clone", "--bare", url, dest)
+	if err := g.runClone([]string{"clone", "--bare", url, dest}, nil); err != nil {
+		return err
+	}
+	// Configure refspec so worktrees can fetch and see origin/* refs
+	return configureRefspec(g.context(), dest)
+}
+
+// runAttached runs a git command with its output going to stdout and
+// stderr rather than captured, e.g. to a terminal, where git can open the
+// editor. A nil writer is captured, for the error. stdin is recorded
+// unless it is a file such as the terminal.
+func (g *Git) runAttached(env []string, stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+	if g.recorder != nil {
+		env = pinCommitDates(env, args, time.Now())
+	}
+	if _, isFile := stdin.(*os.File); stdin != nil && !isFile && g.recorder != nil {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("reading input for git %s: %w", commandName(args), err)
+		}
+		g.recorder.record(args, input, env)
+		stdin = bytes.NewReader(input)
+	} else {
+		g.recorder.record(args, nil, env)
+	}
+
+	if g.gitDir != "" {
+		args = append([]string{"--git-dir=" + g.gitDir}, args...)
+	}
+	cmd := g.command(args...)
+	if g.workDir != "" {
+		cmd.Dir = g.workDir
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdin = stdin
+
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &outBuf, &errBuf
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	if stderr != nil {
+		cmd.Stderr = stderr
+	}
+
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrGitNotFound
+	}
+	if err != nil {
+		return g.wrapError(err, outBuf.String(), errBuf.String(), args)
+	}
+	return nil
+}
+
+// retryRecursiveClone retries a clone --recurse-submodules that failed
+// with a transient network error, as configured by WithRetry. Git removes
+// the destination of a clone that fails before checkout, so that clone is
+// run again; once the superproject is checked out, only its submodules
+// are updated again.
+func (g *Git) retryRecursiveClone(err error, args []string, dest string, progress io.Writer) error {
+	repo := *g
+	repo.workDir, repo.gitDir, repo.subdir = dest, "", ""
+	repo.retryAttempts = 0 // Retried here
+	delay := g.retryBackoff
+	for attempt := 1; attempt < g.retryAttempts && g.isTransientError(err); attempt++ {
+		if !g.waitRetry(delay) {
+			break
+		}
+		delay *= 2
+		if _, statErr := os.Stat(filepath.Join(dest, ".git")); statErr == nil {
+			err = repo.SubmoduleUpdate(true, true)
+		} else {
+			err = g.runClone(args, progress)
+		}
+	}
+	return err
+}
+
+// runClone runs a git clone, which runs outside any repository, so not
+// through run. stderr is also copied to progress if set. Errors carry the
+// full args with credentials scrubbed from the URL, and a clone into an
+// existing destination is reported as ErrDestinationExists.
+func (g *Git) runClone(args []string, progress io.Writer) error {
+	g.recorder.record(args, nil, nil)
+	cmd := g.command(args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if progress != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, progress)
+	}
 	if err := cmd.Run(); err != nil {
-		return g.wrapError(err, stdout.String(), stderr.String(), []string{"clone", "--bare", url})
+		scrubbed := make([]string, len(args))
+		for i, arg := range args {
+			scrubbed[i] = scrubCredentials(arg)
+		}
+		return g.wrapError(err, stdout.String(), stderr.String(), scrubbed)
 	}
-	// Configure refspec so worktrees can fetch and see origin/* refs
-	return configureRefspec(dest)
+	return nil
 }
 
 // configureHooksPath sets core.hooksPath to use the repo's .githooks directory
 // if it exists. This ensures Gas Town agents use the pre-push hook that blocks
 // pushes to non-main branches (internal PRs are not allowed).
-func configureHooksPath(repoPath string) error {
+func configureHooksPath(ctx context.Context, repoPath string) error {
 	hooksDir := filepath.Join(repoPath, ".githooks")
 	if _, err := os.Stat(hooksDir); os.IsNotExist(err) {
 		// No .githooks directory, nothing to configure
 		return nil
 	}
 
-	cmd := exec.Command("git", "-C", repoPath, "config", "core.hooksPath", ".githooks")
+	cmd := exec.CommandContext(ctx, Binary(), "-C", repoPath, "config", "core.hooksPath", ".githooks")
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("configuring hooks path: %w", ctx.Err())
+		}
 		return fmt.Errorf("configuring hooks path: %s", strings.TrimSpace(stderr.String()))
 	}
 	return nil
@@ -187,17 +818,23 @@ func configureHooksPath(repoPath string) error {
 // fetch and see origin/* refs. Without this, `git fetch` only updates FETCH_HEAD
 // and origin/main never appears in refs/remotes/origin/main.
 // See: https://github.com/anthropics/gastown/issues/286
-func configureRefspec(repoPath string) error {
-	cmd := exec.Command("git", "-C", repoPath, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*")
+func configureRefspec(ctx context.Context, repoPath string) error {
+	cmd := exec.CommandContext(ctx, Binary(), "-C", repoPath, "config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*")
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("configuring refspec: %w", ctx.Err())
+		}
 		return fmt.Errorf("configuring refspec: %s", strings.TrimSpace(stderr.String()))
 	}
 	// Fetch to populate refs/remotes/origin/* so worktrees can use origin/main
-	fetchCmd := exec.Command("git", "-C", repoPath, "fetch", "origin")
+	fetchCmd := exec.CommandContext(ctx, Binary(), "-C", repoPath, "fetch", "origin")
 	fetchCmd.Stderr = &stderr
 	if err := fetchCmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("fetching origin: %w", ctx.Err())
+		}
 		return fmt.Errorf("fetching origin: %s", strings.TrimSpace(stderr.String()))
 	}
 	return nil
@@ -205,15 +842,11 @@ func configureRefspec(repoPath string) error {
 
 // CloneBareWithReference clones a bare repository using a local repo as an object reference.
 func (g *Git) CloneBareWithReference(url, dest, reference string) error {
-	cmd := exec.Command("git", "clone", "--bare", "--reference-if-able", reference, url, dest)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return g.wrapError(err, stdout.String(), stderr.String(), []string{"clone", "--bare", "--reference-if-able", url})
+	if err := g.runClone([]string{"clone", "--bare", "--reference-if-able", reference, url, dest}, nil); err != nil {
+		return err
 	}
 	// Configure refspec so worktrees can fetch and see origin/* refs
-	return configureRefspec(dest)
+	return configureRefspec(g.context(), dest)
 }
 
 // Checkout checks out the given ref.
@@ -222,9 +855,70 @@ func (g *Git) Checkout(ref string) error {
 	return err
 }
 
+// ResetMode is what Reset resets besides HEAD.
+type ResetMode string
+
+// Reset modes, as git reset --soft, --mixed and --hard.
+const (
+	ResetSoft  ResetMode = "soft"  // Move HEAD only; the index and working tree are kept
+	ResetMixed ResetMode = "mixed" // Also reset the index; working tree changes are kept, unstaged
+	ResetHard  ResetMode = "hard"  // Also reset the working tree: uncommitted changes are lost
+)
+
+// Reset moves the current branch (or detached HEAD) to ref, resetting the
+// index and working tree according to mode. There is no default mode: mode
+// must be given, so the destructive ResetHard is always an explicit choice.
+// A ref that doesn't name a commit is reported before anything is reset.
+func (g *Git) Reset(ref string, mode ResetMode) error {
+	switch mode {
+	case ResetSoft, ResetMixed, ResetHard:
+	default:
+		return fmt.Errorf("unknown reset mode %q", mode)
+	}
+	commit, err := g.run("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("reset: %q is not a commit: %w", ref, err)
+	}
+	_, err = g.run("reset", "--quiet", "--"+string(mode), commit)
+	return err
+}
+
 // Fetch fetches from the remote.
 func (g *Git) Fetch(remote string) error {
-	_, err := g.run("fetch", remote)
+	return g.FetchWithOptions(remote, FetchOptions{})
+}
+
+// FetchOptions configures FetchWithOptions.
+type FetchOptions struct {
+	Prune  bool // Delete remote-tracking refs whose branch is gone from the remote
+	Tags   bool // Fetch all tags, not just those pointing into fetched history
+	NoTags bool // Fetch no tags at all; can't be combined with Tags
+	All    bool // Fetch every configured remote; the remote argument must be empty
+}
+
+// FetchWithOptions fetches from the remote with options.
+func (g *Git) FetchWithOptions(remote string, opts FetchOptions) error {
+	args := []string{"fetch"}
+	if opts.Prune {
+		args = append(args, "--prune")
+	}
+	switch {
+	case opts.Tags && opts.NoTags:
+		return fmt.Errorf("fetch: Tags and NoTags are mutually exclusive")
+	case opts.Tags:
+		args = append(args, "--tags")
+	case opts.NoTags:
+		args = append(args, "--no-tags")
+	}
+	switch {
+	case opts.All && remote != "":
+		return fmt.Errorf("fetch: All fetches every remote, but remote %q was given", remote)
+	case opts.All:
+		args = append(args, "--all")
+	default:
+		args = append(args, remote)
+	}
+	_, err := g.run(args...)
 	return err
 }
 
@@ -240,868 +934,3816 @@ func (g *Git) Pull(remote, branch string) error {
 	return err
 }
 
-// Push pushes to the remote branch.
-func (g *Git) Push(remote, branch string, force bool) error {
-	args := []string{"push", remote, branch}
-	if force {
-		args = append(args, "--force")
-	}
-	_, err := g.run(args...)
-	return err
+// PullOptions configures PullWithOptions.
+type PullOptions struct {
+	FFOnly bool // Fail rather than create a merge commit if the branch diverged
 }
 
-// Add stages files for commit.
-func (g *Git) Add(paths ...string) error {
-	args := append([]string{"add"}, paths...)
-	_, err := g.run(args...)
+// PullWithOptions pulls from the remote branch with options.
+func (g *Git) PullWithOptions(remote, branch string, opts PullOptions) error {
+	args := []string{"pull"}
+	if opts.FFOnly {
+		args = append(args, "--ff-only")
+	}
+	_, err := g.run(append(args, remote, branch)...)
 	return err
 }
 
-// Commit creates a commit with the given message.
-func (g *Git) Commit(message string) error {
-	_, err := g.run("commit", "-m", message)
-	return err
-}
+// CloneOrUpdate makes dest an up-to-date clone of url, and is safe to call
+// repeatedly. If dest doesn't exist (or is empty), it is cloned. If it is
+// already a clone of url, origin is fetched and branch (the current branch
+// if empty) is checked out and fast-forwarded; a diverged branch is an
+// error rather than a merge. A dest left behind by an interrupted clone (a
+// .git directory without a valid HEAD) is removed and cloned again.
+//
+// Any other existing dest is refused: a directory with files but no
+// repository, or a clone of a different URL, is never deleted.
+func (g *Git) CloneOrUpdate(url, dest, branch string, opts CloneOptions) error {
+	if branch != "" {
+		opts.Branch = branch
+	}
 
-// CommitAll stages all changes and commits.
-func (g *Git) CommitAll(message string) error {
-	_, err := g.run("commit", "-am", message)
-	return err
-}
+	entries, err := os.ReadDir(dest)
+	if os.IsNotExist(err) || (err == nil && len(entries) == 0) {
+		return g.CloneWithOptions(url, dest, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", dest, err)
+	}
 
-// GitStatus represents the status of the working directory.
-type GitStatus struct {
-	Clean    bool
-	Modified []string
-	Added    []string
-	Deleted  []string
-	Untracked []string
-}
-
-// Status returns the current git status.
-func (g *Git) Status() (*GitStatus, error) {
-	out, err := g.run("status", "--porcelain")
-	if err != nil {
-		return nil, err
-	}
-
-	status := &GitStatus{Clean: true}
-	if out == "" {
-		return status, nil
+	if _, err := os.Stat(filepath.Join(dest, ".git")); err != nil {
+		return fmt.Errorf("%s exists and is not a git clone", dest)
 	}
-
-	status.Clean = false
-	for _, line := range strings.Split(out, "\n") {
-		if len(line) < 3 {
-			continue
+	repo := NewGit(dest)
+	repo.recorder = g.recorder
+	if _, err := repo.run("rev-parse", "--verify", "--quiet", "HEAD^{commit}"); err != nil {
+		// Interrupted clone: nothing worth keeping
+		if err := os.RemoveAll(dest); err != nil {
+			return fmt.Errorf("removing partial clone %s: %w", dest, err)
 		}
-		code := line[:2]
-		file := line[3:]
+		return g.CloneWithOptions(url, dest, opts)
+	}
 
-		switch {
-		case strings.Contains(code, "M"):
-			status.Modified = append(status.Modified, file)
-		case strings.Contains(code, "A"):
-			status.Added = append(status.Added, file)
-		case strings.Contains(code, "D"):
-			status.Deleted = append(status.Deleted, file)
-		case strings.Contains(code, "?"):
-			status.Untracked = append(status.Untracked, file)
+	if origin, err := repo.RemoteURL("origin"); err != nil || origin != url {
+		return fmt.Errorf("%s is a clone of %q, not %q", dest, origin, url)
+	}
+	if err := repo.Fetch("origin"); err != nil {
+		return err
+	}
+	if branch == "" {
+		if branch, err = repo.CurrentBranch(); errors.Is(err, ErrDetachedHead) {
+			return fmt.Errorf("%s has a detached HEAD; specify a branch", dest)
+		} else if err != nil {
+			return err
 		}
+	} else if err := repo.Checkout(branch); err != nil {
+		return err
 	}
-
-	return status, nil
+	return repo.PullWithOptions("origin", branch, PullOptions{FFOnly: true})
 }
 
-// CurrentBranch returns the current branch name.
-func (g *Git) CurrentBranch() (string, error) {
-	return g.run("rev-parse", "--abbrev-ref", "HEAD")
+// Push pushes to the remote branch. A force push to a protected branch
+// (see WithProtectedBranches) fails with ErrProtectedBranch; use
+// PushWithOptions with AllowProtected to override.
+func (g *Git) Push(remote, branch string, force bool) error {
+	_, err := g.PushWithOptions(remote, branch, PushOptions{Force: force})
+	return err
 }
 
-// DefaultBranch returns the default branch name (what HEAD points to).
-// This works for both regular and bare repositories.
-// Returns "main" as fallback if detection fails.
-func (g *Git) DefaultBranch() string {
-	// Try symbolic-ref first (works for bare repos)
-	branch, err := g.run("symbolic-ref", "--short", "HEAD")
-	if err == nil && branch != "" {
-		return branch
-	}
-	// Fallback to main
-	return "main"
+// RefAdvance describes how a ref moved during a fetch or pull.
+type RefAdvance struct {
+	Ref     string // e.g. "refs/remotes/origin/main"
+	OldSHA  string // Empty for refs that are new
+	NewSHA  string // Empty for refs that were pruned
+	Commits int    // Commits in OldSHA..NewSHA; 0 for new and pruned refs
+	Forced  bool   // The old value is not an ancestor of the new one
 }
 
-// RemoteDefaultBranch returns the default branch from the remote (origin).
-// This is useful in worktrees where HEAD may not reflect the repo's actual default.
-// Checks origin/HEAD first, then falls back to checking if master/main exists.
-// Returns "main" as final fallback.
-func (g *Git) RemoteDefaultBranch() string {
-	// Try to get from origin/HEAD symbolic ref
-	out, err := g.run("symbolic-ref", "refs/remotes/origin/HEAD")
-	if err == nil && out != "" {
-		// Returns refs/remotes/origin/main -> extract branch name
-		parts := strings.Split(out, "/")
-		if len(parts) > 0 {
-			return parts[len(parts)-1]
-		}
+// FetchWithResult fetches remote and reports which of its remote-tracking
+// refs changed. Changes are found by comparing the refs before and after the
+// fetch, rather than by parsing git's progress output.
+func (g *Git) FetchWithResult(remote string) ([]RefAdvance, error) {
+	before, err := g.refSnapshot("refs/remotes/" + remote + "/")
+	if err != nil {
+		return nil, err
 	}
-
-	// Fallback: check if origin/master exists
-	_, err = g.run("rev-parse", "--verify", "origin/master")
-	if err == nil {
-		return "master"
+	if err := g.Fetch(remote); err != nil {
+		return nil, err
 	}
-
-	// Fallback: check if origin/main exists
-	_, err = g.run("rev-parse", "--verify", "origin/main")
-	if err == nil {
-		return "main"
+	after, err := g.refSnapshot("refs/remotes/" + remote + "/")
+	if err != nil {
+		return nil, err
 	}
+	return g.refAdvances(before, after), nil
+}
 
-	return "main" // final fallback
+// PullResult reports what a pull fetched and how far the local branch moved.
+type PullResult struct {
+	Fetched []RefAdvance // Remote-tracking refs updated by the fetch
+	Head    *RefAdvance  // The local branch; nil if it didn't move
 }
 
-// HasUncommittedChanges returns true if there are uncommitted changes.
-func (g *Git) HasUncommittedChanges() (bool, error) {
-	status, err := g.Status()
+// PullWithResult pulls branch from remote and reports the refs that moved.
+func (g *Git) PullWithResult(remote, branch string) (*PullResult, error) {
+	before, err := g.refSnapshot("refs/remotes/" + remote + "/")
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	oldHead, err := g.Rev("HEAD")
+	if err != nil {
+		return nil, err
 	}
-	return !status.Clean, nil
-}
 
-// RemoteURL returns the URL for the given remote.
-func (g *Git) RemoteURL(remote string) (string, error) {
-	return g.run("remote", "get-url", remote)
-}
+	if err := g.Pull(remote, branch); err != nil {
+		return nil, err
+	}
 
-// Remotes returns the list of configured remote names.
-func (g *Git) Remotes() ([]string, error) {
-	out, err := g.run("remote")
+	after, err := g.refSnapshot("refs/remotes/" + remote + "/")
 	if err != nil {
 		return nil, err
 	}
-	if out == "" {
-		return nil, nil
+	newHead, err := g.Rev("HEAD")
+	if err != nil {
+		return nil, err
 	}
-	return strings.Split(out, "\n"), nil
-}
 
-// Merge merges the given branch into the current branch.
-func (g *Git) Merge(branch string) error {
-	_, err := g.run("merge", branch)
-	return err
+	result := &PullResult{Fetched: g.refAdvances(before, after)}
+	if newHead != oldHead {
+		current, _ := g.run("symbolic-ref", "-q", "HEAD")
+		if current == "" {
+			current = "HEAD"
+		}
+		advances := g.refAdvances(map[string]string{current: oldHead}, map[string]string{current: newHead})
+		result.Head = &advances[0]
+	}
+	return result, nil
 }
 
-// MergeNoFF merges the given branch with --no-ff flag and a custom message.
-func (g *Git) MergeNoFF(branch, message string) error {
-	_, err := g.run("merge", "--no-ff", "-m", message, branch)
-	return err
+// refSnapshot returns the values of the refs under prefix, keyed by name.
+func (g *Git) refSnapshot(prefix string) (map[string]string, error) {
+	out, err := g.run("for-each-ref", "--format=%(refname) %(objectname)", prefix)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if name, sha, ok := strings.Cut(line, " "); ok {
+			refs[name] = sha
+		}
+	}
+	return refs, nil
 }
 
-// DeleteRemoteBranch deletes a branch on the remote.
-func (g *Git) DeleteRemoteBranch(remote, branch string) error {
-	_, err := g.run("push", remote, "--delete", branch)
-	return err
+// refAdvances compares two ref snapshots and returns the refs that changed,
+// sorted by name.
+func (g *Git) refAdvances(before, after map[string]string) []RefAdvance {
+	var names []string
+	for name := range after {
+		names = append(names, name)
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var advances []RefAdvance
+	for _, name := range names {
+		oldSHA, newSHA := before[name], after[name]
+		if oldSHA == newSHA {
+			continue
+		}
+		advance := RefAdvance{Ref: name, OldSHA: oldSHA, NewSHA: newSHA}
+		if oldSHA != "" && newSHA != "" {
+			if n, err := g.CommitsAhead(oldSHA, newSHA); err == nil {
+				advance.Commits = n
+			}
+			if isAncestor, err := g.IsAncestor(oldSHA, newSHA); err == nil {
+				advance.Forced = !isAncestor
+			}
+		}
+		advances = append(advances, advance)
+	}
+	return advances
 }
 
-// Rebase rebases the current branch onto the given ref.
-func (g *Git) Rebase(onto string) error {
-	_, err := g.run("rebase", onto)
-	return err
+// PushOptions configures PushWithOptions.
+type PushOptions struct {
+	Force       bool // Overwrite the remote branch even if not a fast-forward
+	DryRun      bool // Report what would be updated without pushing anything
+	SetUpstream bool // Make the local branch track the remote one (-u), so a bare pull works
+	Tags        bool // Also push all local tags (--tags)
+
+	// ForceWithLease overwrites the remote branch like Force, but only if it
+	// is still where our remote-tracking branch says, i.e. nobody pushed
+	// since our last fetch; otherwise the push is rejected ("stale info").
+	// It takes precedence over Force.
+	ForceWithLease bool
+
+	// AllowProtected permits a force push (Force, ForceWithLease or a
+	// "+" refspec) to a protected branch, which otherwise fails with
+	// ErrProtectedBranch before anything is pushed.
+	AllowProtected bool
 }
 
-// AbortMerge aborts a merge in progress.
-func (g *Git) AbortMerge() error {
-	_, err := g.run("merge", "--abort")
-	return err
+// RefUpdate describes a remote ref that a push updated (or would update).
+type RefUpdate struct {
+	Ref      string // Remote ref, e.g. "refs/heads/main"
+	OldSHA   string // Previous remote value (abbreviated); empty for new refs
+	NewSHA   string // New value (abbreviated); empty for deletions and rejections
+	Forced   bool   // Non-fast-forward update that overwrites remote history
+	Rejected bool   // Remote (or git) refused the update
+	Summary  string // Git's summary, e.g. "[new branch]" or "[rejected] (non-fast-forward)"
 }
 
-// CheckConflicts performs a test merge to check if source can be merged into target
-// without conflicts. Returns a list of conflicting files, or empty slice if clean.
-// The merge is always aborted after checking - no actual changes are made.
-//
-// The caller must ensure the working directory is clean before calling this.
-// After return, the working directory is restored to the target branch.
-func (g *Git) CheckConflicts(source, target string) ([]string, error) {
-	// Checkout the target branch
-	if err := g.Checkout(target); err != nil {
-		return nil, fmt.Errorf("checkout target %s: %w", target, err)
+// PushWithOptions pushes branch to remote and returns the refs that changed.
+// With DryRun, nothing is pushed and the returned updates describe what a
+// real push would do, including forced and rejected (non-fast-forward) refs.
+// Up-to-date refs are omitted.
+func (g *Git) PushWithOptions(remote, branch string, opts PushOptions) ([]RefUpdate, error) {
+	forced := opts.Force || opts.ForceWithLease || strings.HasPrefix(branch, "+")
+	if forced && !opts.AllowProtected {
+		target, err := g.pushTarget(branch)
+		if err != nil {
+			return nil, err
+		}
+		if g.isProtectedBranch(target) {
+			return nil, fmt.Errorf("%w: %s (set AllowProtected to override)", ErrProtectedBranch, target)
+		}
 	}
 
-	// Attempt test merge with --no-commit --no-ff
-	// We need to capture both stdout and stderr to detect conflicts
-	_, mergeErr := g.runMergeCheck("merge", "--no-commit", "--no-ff", source)
+	args := []string{"push", "--porcelain", remote, branch}
+	switch {
+	case opts.ForceWithLease:
+		args = append(args, "--force-with-lease")
+	case opts.Force:
+		args = append(args, "--force")
+	}
+	if opts.DryRun {
+		args = append(args, "--dry-run")
+	}
+	if opts.SetUpstream {
+		args = append(args, "--set-upstream")
+	}
+	if opts.Tags {
+		args = append(args, "--tags")
+	}
 
-	if mergeErr != nil {
-		// ZFC: Use git's porcelain output to detect conflicts instead of parsing stderr.
-		// GetConflictingFiles() uses `git diff --diff-filter=U` which is the proper way.
-		conflicts, err := g.GetConflictingFiles()
-		if err == nil && len(conflicts) > 0 {
-			// Abort the test merge (best-effort cleanup)
-			_ = g.AbortMerge()
-			return conflicts, nil
+	out, err := g.run(args...)
+	if err != nil {
+		// Rejections exit non-zero but still report refs on stdout
+		var gitErr *GitError
+		if errors.As(err, &gitErr) {
+			return g.parsePushPorcelain(gitErr.Stdout), err
 		}
-
-		// No unmerged files detected - this is some other merge error
-		_ = g.AbortMerge()
-		return nil, mergeErr
+		return nil, err
 	}
-
-	// Merge succeeded (no conflicts) - abort the test merge
-	// Use reset since --abort won't work on successful merge (best-effort cleanup)
-	_, _ = g.run("reset", "--hard", "HEAD")
-	return nil, nil
+	return g.parsePushPorcelain(out), nil
 }
 
-// runMergeCheck runs a git merge command and returns error info from both stdout and stderr.
-// ZFC: Returns GitError with raw output for agent observation.
-func (g *Git) runMergeCheck(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = g.workDir
+// pushTarget returns the remote branch a push of refspec updates: the
+// destination of "src:dst", else the source, with HEAD (or an empty
+// refspec) meaning the current branch.
+func (g *Git) pushTarget(refspec string) (string, error) {
+	refspec = strings.TrimPrefix(refspec, "+")
+	if _, dst, ok := strings.Cut(refspec, ":"); ok {
+		refspec = dst
+	}
+	if refspec == "" || refspec == "HEAD" {
+		branch, err := g.CurrentBranch()
+		if err != nil {
+			return "", fmt.Errorf("resolving the branch to push: %w", err)
+		}
+		return branch, nil
+	}
+	return strings.TrimPrefix(refspec, "refs/heads/"), nil
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// parsePushPorcelain parses `git push --porcelain` ref lines:
+//
+//	<flag>\t<from>:<to>\t<summary>
+//
+// where flag is ' ' (fast-forward), '+' (forced), '-' (deleted),
+// '*' (new ref), '!' (rejected) or '=' (up to date).
+func (g *Git) parsePushPorcelain(out string) []RefUpdate {
+	var updates []RefUpdate
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || len(fields[0]) != 1 {
+			continue
+		}
+		flag := fields[0]
+		from, to, _ := strings.Cut(fields[1], ":")
+		if flag == "=" {
+			continue
+		}
 
-	err := cmd.Run()
-	if err != nil {
-		// ZFC: Return raw output for observation, don't interpret CONFLICT
-		return "", g.wrapError(err, stdout.String(), stderr.String(), args)
+		update := RefUpdate{
+			Ref:      to,
+			Forced:   flag == "+",
+			Rejected: flag == "!",
+			Summary:  fields[2],
+		}
+		// Summary is "old..new" for fast-forwards, "old...new" for forced updates
+		summary, _, _ := strings.Cut(fields[2], " ")
+		if oldSHA, newSHA, ok := strings.Cut(summary, "..."); ok {
+			update.OldSHA, update.NewSHA = oldSHA, newSHA
+		} else if oldSHA, newSHA, ok := strings.Cut(summary, ".."); ok {
+			update.OldSHA, update.NewSHA = oldSHA, newSHA
+		} else if flag == "*" {
+			update.NewSHA, _ = g.run("rev-parse", "--short", from)
+		}
+		updates = append(updates, update)
 	}
+	return updates
+}
 
-	return strings.TrimSpace(stdout.String()), nil
+// RefReject is a ref that a push failed to update.
+type RefReject struct {
+	Ref    string // Remote ref, e.g. "refs/heads/main"
+	Reason string // e.g. "non-fast-forward", "fetch first", "hook declined"
 }
 
-// GetConflictingFiles returns the list of files with merge conflicts.
-// ZFC: Uses git's porcelain output (diff --diff-filter=U) instead of parsing stderr.
-// This is the proper way to detect conflicts without violating ZFC.
-func (g *Git) GetConflictingFiles() ([]string, error) {
-	// git diff --name-only --diff-filter=U shows unmerged files
-	out, err := g.run("diff", "--name-only", "--diff-filter=U")
-	if err != nil {
-		return nil, err
-	}
+// PushResult summarizes a push for reporting.
+type PushResult struct {
+	Updated  []RefUpdate // Refs that were (or, with DryRun, would be) updated
+	Forced   bool        // At least one update overwrote remote history
+	Rejected []RefReject
+}
 
-	if out == "" {
-		return nil, nil
+// PushWithResult is PushWithOptions with the ref updates grouped into a
+// PushResult. On rejection, the result is returned along with the error.
+func (g *Git) PushWithResult(remote, branch string, opts PushOptions) (*PushResult, error) {
+	updates, err := g.PushWithOptions(remote, branch, opts)
+
+	result := &PushResult{}
+	for _, u := range updates {
+		if u.Rejected {
+			result.Rejected = append(result.Rejected, RefReject{Ref: u.Ref, Reason: rejectReason(u.Summary)})
+			continue
+		}
+		result.Updated = append(result.Updated, u)
+		result.Forced = result.Forced || u.Forced
 	}
+	return result, err
+}
 
-	files := strings.Split(out, "\n")
-	// Filter out empty strings
-	var result []string
-	for _, f := range files {
-		if f != "" {
-			result = append(result, f)
+// rejectReason extracts the parenthesized reason from a push summary such as
+// "[rejected] (non-fast-forward)", falling back to the whole summary.
+func rejectReason(summary string) string {
+	if _, rest, ok := strings.Cut(summary, "("); ok {
+		if reason, _, ok := strings.Cut(rest, ")"); ok {
+			return reason
 		}
 	}
-	return result, nil
+	return summary
 }
 
-// AbortRebase aborts a rebase in progress.
-func (g *Git) AbortRebase() error {
-	_, err := g.run("rebase", "--abort")
+// Add stages files for commit.
+func (g *Git) Add(paths ...string) error {
+	args := append([]string{"add"}, g.pathspecs(paths)...)
+	_, err := g.run(args...)
 	return err
 }
 
-// CreateBranch creates a new branch.
-func (g *Git) CreateBranch(name string) error {
-	_, err := g.run("branch", name)
-	return err
+// Commit creates a commit of the index with the given message and
+// optional "Key: value" trailers.
+func (g *Git) Commit(message string, trailers ...string) error {
+	return g.CommitWithTrailers(message, trailers, CommitOptions{})
 }
 
-// CreateBranchFrom creates a new branch from a specific ref.
-func (g *Git) CreateBranchFrom(name, ref string) error {
-	_, err := g.run("branch", name, ref)
-	return err
+// CommitWithTrailers commits the index with message and "Key: value"
+// trailers (e.g. from AgentTrailers), which replace opts.Trailers. Trailers
+// are added by git commit --trailer, so the repo's trailer.* config
+// applies. opts sets the identity, author and signing as for
+// CommitToBranch, plus any extra git commit args. An empty message leaves
+// it to opts.Args (-m, --no-edit, ...) or the editor.
+func (g *Git) CommitWithTrailers(message string, trailers []string, opts CommitOptions) error {
+	opts.Trailers = trailers
+	return g.commit(nil, message, opts)
 }
 
-// BranchExists checks if a branch exists locally.
-func (g *Git) BranchExists(name string) (bool, error) {
-	_, err := g.run("show-ref", "--verify", "--quiet", "refs/heads/"+name)
-	if err != nil {
-		// Exit code 1 means branch doesn't exist
-		if strings.Contains(err.Error(), "exit status 1") {
-			return false, nil
-		}
-		return false, err
+// commit runs git commit with flags and message as for CommitWithTrailers.
+func (g *Git) commit(flags []string, message string, opts CommitOptions) error {
+	args := append([]string{"commit"}, flags...)
+	var stdin io.Reader = opts.Stdin
+	if message != "" {
+		args = append(args, "-F", "-")
+		stdin = strings.NewReader(message)
 	}
-	return true, nil
-}
 
-// RemoteBranchExists checks if a branch exists on the remote.
-func (g *Git) RemoteBranchExists(remote, branch string) (bool, error) {
-	_, err := g.run("ls-remote", "--heads", remote, branch)
-	if err != nil {
-		return false, err
+	// Trailers are flags, so they go before any pathspecs
+	extra, paths := opts.Args, []string(nil)
+	if i := slices.Index(opts.Args, "--"); i >= 0 {
+		extra, paths = opts.Args[:i], opts.Args[i:]
+	}
+	args = append(args, extra...)
+	args = append(args, TrailerArgs(opts.Trailers)...)
+	args = append(opts.signArgs(args), paths...)
+
+	var config []string
+	for _, c := range opts.Config {
+		config = append(config, "-c", c)
+	}
+	args = append(config, args...)
+
+	env := append(opts.env(), opts.Env...)
+	var err error
+	if opts.Stdout != nil || opts.Stderr != nil {
+		err = g.runAttached(env, stdin, opts.Stdout, opts.Stderr, args...)
+	} else {
+		_, err = g.runCmd(env, stdin, args...)
 	}
-	// ls-remote returns empty if branch doesn't exist, need to check output
-	out, err := g.run("ls-remote", "--heads", remote, branch)
 	if err != nil {
-		return false, err
+		return g.commitSigningError(err, opts)
 	}
-	return out != "", nil
+	return nil
 }
 
-// DeleteBranch deletes a local branch.
-func (g *Git) DeleteBranch(name string, force bool) error {
-	flag := "-d"
-	if force {
-		flag = "-D"
+// TrailerArgs returns the git commit (or interpret-trailers) arguments that
+// add "Key: value" trailers: a --trailer flag for each.
+func TrailerArgs(trailers []string) []string {
+	var args []string
+	for _, t := range trailers {
+		args = append(args, "--trailer", t)
 	}
-	_, err := g.run("branch", flag, name)
+	return args
+}
+
+// CommitAll stages all changes and commits.
+func (g *Git) CommitAll(message string) error {
+	_, err := g.run("commit", "-am", message)
 	return err
 }
 
-// ListBranches returns all local branches matching a pattern.
-// Pattern uses git's pattern matching (e.g., "polecat/*" matches all polecat branches).
-// Returns branch names without the refs/heads/ prefix.
-func (g *Git) ListBranches(pattern string) ([]string, error) {
-	args := []string{"branch", "--list", "--format=%(refname:short)"}
-	if pattern != "" {
-		args = append(args, pattern)
-	}
-	out, err := g.run(args...)
+// CommitAllWithOptions stages all changes to tracked files and commits
+// them, with the trailers, identity, author and signing set by opts.
+func (g *Git) CommitAllWithOptions(message string, opts CommitOptions) error {
+	return g.commit([]string{"-a"}, message, opts)
+}
+
+// GitStatus represents the status of the working directory. Modified,
+// Added and Deleted don't say whether a change is staged; Staged and
+// Unstaged split tracked changes by porcelain column, so a path that is
+// partially staged (or conflicted) is in both.
+type GitStatus struct {
+	Clean    bool
+	Modified []string
+	Added    []string
+	Deleted  []string
+	Untracked []string
+	Staged   []string // Paths whose index differs from HEAD
+	Unstaged []string // Tracked paths whose working tree differs from the index
+	Renamed  []RenamedFile
+}
+
+// RenamedFile is a staged rename or copy reported by Status.
+type RenamedFile struct {
+	From   string // Source path
+	To     string // Destination path
+	Copied bool   // A copy (porcelain C); From is unchanged
+}
+
+// Status returns the current git status.
+func (g *Git) Status() (*GitStatus, error) {
+	// Not run: trimming would eat the leading space of " M file". With -z,
+	// paths are NUL-terminated and never quoted, so any filename is verbatim.
+	out, err := g.runRaw("status", "--porcelain", "-z")
 	if err != nil {
 		return nil, err
 	}
+
+	status := &GitStatus{Clean: true}
 	if out == "" {
-		return nil, nil
+		return status, nil
 	}
-	return strings.Split(out, "\n"), nil
+
+	status.Clean = false
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		code := entry[:2]
+		file := entry[3:]
+
+		// Renames and copies are followed by the source path
+		var renamed *RenamedFile
+		if (code[0] == 'R' || code[0] == 'C') && i+1 < len(entries) {
+			i++
+			renamed = &RenamedFile{From: entries[i], To: file, Copied: code[0] == 'C'}
+		}
+
+		if code[0] != ' ' && code[0] != '?' && code[0] != '!' {
+			status.Staged = append(status.Staged, file)
+		}
+		if code[1] != ' ' && code[1] != '?' && code[1] != '!' {
+			status.Unstaged = append(status.Unstaged, file)
+		}
+
+		switch {
+		case renamed != nil:
+			status.Renamed = append(status.Renamed, *renamed)
+		case strings.Contains(code, "M"):
+			status.Modified = append(status.Modified, file)
+		case strings.Contains(code, "A"):
+			status.Added = append(status.Added, file)
+		case strings.Contains(code, "D"):
+			status.Deleted = append(status.Deleted, file)
+		case strings.Contains(code, "?"):
+			status.Untracked = append(status.Untracked, file)
+		}
+	}
+
+	return status, nil
 }
 
-// ResetBranch force-updates a branch to point to a ref.
-// This is useful for resetting stale polecat branches to main.
-func (g *Git) ResetBranch(name, ref string) error {
-	_, err := g.run("branch", "-f", name, ref)
-	return err
+// FileState is the index and working tree state of a single path.
+type FileState string
+
+// File states reported by FileStatuses.
+const (
+	FileUnmodified       FileState = "unmodified"        // Tracked and clean
+	FileStagedModified   FileState = "staged-modified"   // Index differs from HEAD; working tree matches the index
+	FileUnstagedModified FileState = "unstaged-modified" // Working tree differs from the index; nothing staged
+	FilePartiallyStaged  FileState = "partially-staged"  // Staged changes plus further unstaged ones
+	FileUntracked        FileState = "untracked"
+	FileIgnored          FileState = "ignored"
+	FileConflicted       FileState = "conflicted" // Unmerged during a merge, rebase or cherry-pick
+	FileNotFound         FileState = "not-found"  // Neither tracked nor present
+)
+
+// FileStatuses returns the state of each of the given file paths, scoping
+// git status to them rather than scanning the whole tree. Every path gets
+// an entry: tracked clean files are FileUnmodified and unknown paths are
+// FileNotFound. Deletions count as modifications. Paths are relative to
+// the working directory (or the WithSubdir base); the map is keyed by the
+// paths as given.
+func (g *Git) FileStatuses(paths ...string) (map[string]FileState, error) {
+	states := make(map[string]FileState, len(paths))
+	if len(paths) == 0 {
+		return states, nil
+	}
+	specs := g.pathspecs(paths)
+
+	// Status and ls-files --full-name report root-relative paths
+	prefix, err := g.run("rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	out, err := g.runRaw(append([]string{"status", "--porcelain", "-z", "--untracked-files=all", "--ignored=matching", "--"}, specs...)...)
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[string]string)
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		codes[entry[3:]] = entry[:2]
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // Followed by the source path, which is staged as deleted (renames)
+			if entry[0] == 'R' && i < len(entries) {
+				codes[entries[i]] = "D "
+			}
+		}
+	}
+
+	out, err = g.runRaw(append([]string{"ls-files", "-z", "--full-name", "--"}, specs...)...)
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]bool)
+	for _, file := range strings.Split(out, "\x00") {
+		tracked[file] = true
+	}
+
+	for i, p := range paths {
+		full := path.Join(prefix, filepath.ToSlash(specs[i]))
+		code, changed := codes[full]
+		switch {
+		case changed:
+			states[p] = fileStateFromCode(code)
+		case tracked[full]:
+			states[p] = FileUnmodified
+		default:
+			states[p] = FileNotFound
+		}
+	}
+	return states, nil
 }
 
-// Rev returns the commit hash for the given ref.
-func (g *Git) Rev(ref string) (string, error) {
-	return g.run("rev-parse", ref)
+// fileStateFromCode maps a two-letter porcelain status code to a FileState.
+func fileStateFromCode(code string) FileState {
+	switch ConflictType(code) {
+	case ConflictBothDeleted, ConflictAddedByUs, ConflictDeletedByThem,
+		ConflictAddedByThem, ConflictDeletedByUs, ConflictBothAdded, ConflictBothModified:
+		return FileConflicted
+	}
+	switch {
+	case code == "??":
+		return FileUntracked
+	case code == "!!":
+		return FileIgnored
+	case code[0] != ' ' && code[1] != ' ':
+		return FilePartiallyStaged
+	case code[0] != ' ':
+		return FileStagedModified
+	case code[1] != ' ':
+		return FileUnstagedModified
+	}
+	return FileUnmodified
 }
 
-// IsAncestor checks if ancestor is an ancestor of descendant.
-func (g *Git) IsAncestor(ancestor, descendant string) (bool, error) {
-	_, err := g.run("merge-base", "--is-ancestor", ancestor, descendant)
+// StatusOptions configures StatusWithOptions.
+type StatusOptions struct {
+	// IgnoreEOL drops modified files whose only changes are CR/LF line
+	// endings, as seen with core.autocrlf on Windows checkouts.
+	IgnoreEOL bool
+
+	// IgnoreWhitespace drops modified files whose only changes are
+	// whitespace (including line endings).
+	IgnoreWhitespace bool
+}
+
+// StatusWithOptions is Status with line-ending or whitespace-only
+// modifications filtered out. Added, deleted and untracked files are kept.
+func (g *Git) StatusWithOptions(opts StatusOptions) (*GitStatus, error) {
+	status, err := g.Status()
+	if err != nil || len(status.Modified) == 0 || (!opts.IgnoreEOL && !opts.IgnoreWhitespace) {
+		return status, err
+	}
+
+	changed, err := g.contentChanges(opts.IgnoreWhitespace)
+	if err != nil {
+		return nil, err
+	}
+	var modified []string
+	for _, file := range status.Modified {
+		if changed[file] {
+			modified = append(modified, file)
+		}
+	}
+	status.Modified = modified
+	status.Clean = len(status.Modified)+len(status.Added)+len(status.Deleted)+len(status.Untracked)+len(status.Renamed) == 0
+	return status, nil
+}
+
+// EOLOnlyChanges returns the tracked files that show as modified but whose
+// only changes are CR/LF line endings: phantom modifications typical of
+// core.autocrlf or .gitattributes eol settings that disagree with the
+// committed files. Returns an empty slice when there are none.
+func (g *Git) EOLOnlyChanges() ([]string, error) {
+	status, err := g.Status()
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	if len(status.Modified) == 0 {
+		return files, nil
+	}
+	changed, err := g.contentChanges(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range status.Modified {
+		if !changed[file] {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// contentChanges returns the files whose working tree content differs from
+// HEAD beyond CR/LF line endings (or with ignoreWhitespace, beyond any
+// whitespace). git diff --numstat omits files whose changes are all ignored.
+func (g *Git) contentChanges(ignoreWhitespace bool) (map[string]bool, error) {
+	args := []string{"diff", "HEAD", "--numstat", "-z", "--ignore-cr-at-eol"}
+	if ignoreWhitespace {
+		args = append(args, "--ignore-all-space", "--ignore-blank-lines")
+	}
+	out, err := g.runRaw(args...)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool)
+	for _, f := range parseNumstat(out).Files {
+		changed[f.Path] = true
+	}
+	return changed, nil
+}
+
+// AutoCRLF returns the effective core.autocrlf setting ("true", "input" or
+// "false"); unset means "false".
+func (g *Git) AutoCRLF() string {
+	value, err := g.run("config", "--get", "core.autocrlf")
+	if err != nil || value == "" {
+		return "false"
+	}
+	return value
+}
+
+// ConfigGet returns the value of a git config key, or "" if it is unset.
+func (g *Git) ConfigGet(key string) (string, error) {
+	return g.configGet("--get", key)
+}
+
+// ConfigGetPath is like ConfigGet for path-valued keys (e.g.
+// commit.template): a leading "~/" is expanded to the home directory.
+func (g *Git) ConfigGetPath(key string) (string, error) {
+	return g.configGet("--path", "--get", key)
+}
+
+func (g *Git) configGet(args ...string) (string, error) {
+	value, err := g.run(append([]string{"config"}, args...)...)
+	if err != nil {
+		// Exit code 1 means the key is unset, not an error
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", err
+	}
+	return value, nil
+}
+
+// CurrentBranch returns the current branch name. Returns ErrDetachedHead
+// if HEAD is detached.
+func (g *Git) CurrentBranch() (string, error) {
+	branch, err := g.run("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if branch == "HEAD" {
+		return "", ErrDetachedHead
+	}
+	return branch, nil
+}
+
+// Upstream returns the current branch's upstream (tracking) branch, e.g.
+// "origin/main". Returns ErrNoUpstream if none is configured or HEAD is
+// detached.
+func (g *Git) Upstream() (string, error) {
+	upstream, err := g.run("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	if err != nil {
+		var gitErr *GitError
+		if errors.As(err, &gitErr) && (strings.Contains(gitErr.Stderr, "no upstream") || strings.Contains(gitErr.Stderr, "does not point to a branch")) {
+			return "", fmt.Errorf("%w: %w", ErrNoUpstream, err)
+		}
+		return "", err
+	}
+	return upstream, nil
+}
+
+// AheadBehind returns how many commits HEAD has that upstream doesn't
+// (ahead) and upstream has that HEAD doesn't (behind). An empty upstream
+// means the configured one (see Upstream). A detached HEAD is compared by
+// its commit, so it works with an explicit upstream.
+func (g *Git) AheadBehind(upstream string) (ahead, behind int, err error) {
+	if upstream == "" {
+		if upstream, err = g.Upstream(); err != nil {
+			return 0, 0, err
+		}
+	}
+	out, err := g.run("rev-list", "--left-right", "--count", "HEAD..."+upstream, "--")
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := fmt.Sscanf(out, "%d\t%d", &ahead, &behind); err != nil {
+		return 0, 0, fmt.Errorf("parsing rev-list counts %q: %w", out, err)
+	}
+	return ahead, behind, nil
+}
+
+// DefaultBranch returns the default branch name (what HEAD points to).
+// This works for both regular and bare repositories.
+// Returns "main" as fallback if detection fails.
+func (g *Git) DefaultBranch() string {
+	// Try symbolic-ref first (works for bare repos)
+	branch, err := g.run("symbolic-ref", "--short", "HEAD")
+	if err == nil && branch != "" {
+		return branch
+	}
+	// Fallback to main
+	return "main"
+}
+
+// RemoteDefaultBranch returns the default branch from the remote (origin).
+// This is useful in worktrees where HEAD may not reflect the repo's actual default.
+// Checks origin/HEAD first, then falls back to checking if master/main exists.
+// Returns "main" as final fallback.
+func (g *Git) RemoteDefaultBranch() string {
+	// Try to get from origin/HEAD symbolic ref
+	out, err := g.run("symbolic-ref", "refs/remotes/origin/HEAD")
+	if err == nil && out != "" {
+		// Returns refs/remotes/origin/main -> extract branch name
+		parts := strings.Split(out, "/")
+		if len(parts) > 0 {
+			return parts[len(parts)-1]
+		}
+	}
+
+	// Fallback: check if origin/master exists
+	_, err = g.run("rev-parse", "--verify", "origin/master")
+	if err == nil {
+		return "master"
+	}
+
+	// Fallback: check if origin/main exists
+	_, err = g.run("rev-parse", "--verify", "origin/main")
+	if err == nil {
+		return "main"
+	}
+
+	return "main" // final fallback
+}
+
+// WorktreeMatches reports whether the tracked files in the working tree are
+// identical to ref's tree, e.g. to skip re-applying a change that is already
+// present. Untracked files are ignored by this check.
+func (g *Git) WorktreeMatches(ref string) (bool, error) {
+	_, err := g.run("diff", "--quiet", ref, "--")
+	if err != nil {
+		// Exit code 1 means the tree differs, not an error
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// HasUncommittedChanges returns true if there are uncommitted changes.
+func (g *Git) HasUncommittedChanges() (bool, error) {
+	status, err := g.Status()
+	if err != nil {
+		return false, err
+	}
+	return !status.Clean, nil
+}
+
+// IsClean reports whether the work tree has no staged, unstaged or
+// untracked changes. Ignored files don't count.
+func (g *Git) IsClean() (bool, error) {
+	status, err := g.Status()
+	if err != nil {
+		return false, err
+	}
+	return status.Clean, nil
+}
+
+// RemoteURL returns the URL for the given remote, or ErrNoSuchRemote if it
+// isn't configured.
+func (g *Git) RemoteURL(remote string) (string, error) {
+	return g.run("remote", "get-url", remote)
+}
+
+// Remote is a configured remote.
+type Remote struct {
+	Name     string
+	FetchURL string
+	PushURL  string // Same as FetchURL unless a separate pushurl is configured
+}
+
+// Remotes returns the configured remotes, in git's order.
+func (g *Git) Remotes() ([]Remote, error) {
+	out, err := g.run("remote", "-v")
+	if err != nil {
+		return nil, err
+	}
+	return parseRemotes(out), nil
+}
+
+// parseRemotes parses `git remote -v` lines: "<name>\t<url> (fetch|push)".
+func parseRemotes(out string) []Remote {
+	var remotes []Remote
+	index := make(map[string]int)
+	for _, line := range strings.Split(out, "\n") {
+		name, rest, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		i, seen := index[name]
+		if !seen {
+			i = len(remotes)
+			index[name] = i
+			remotes = append(remotes, Remote{Name: name})
+		}
+		if url, ok := strings.CutSuffix(rest, " (fetch)"); ok {
+			remotes[i].FetchURL = url
+		} else if url, ok := strings.CutSuffix(rest, " (push)"); ok {
+			remotes[i].PushURL = url
+		}
+	}
+	return remotes
+}
+
+// HasRemote reports whether a remote called name is configured.
+func (g *Git) HasRemote(name string) (bool, error) {
+	remotes, err := g.Remotes()
+	if err != nil {
+		return false, err
+	}
+	for _, r := range remotes {
+		if r.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PrunedRemotes returns the remote-tracking refs under remote that no longer
+// exist on the remote and would be removed by PruneRemotes (e.g. "origin/foo").
+// Unlike fetch --prune, this only compares existing refs and fetches nothing.
+func (g *Git) PrunedRemotes(remote string) ([]string, error) {
+	out, err := g.run("remote", "prune", remote, "--dry-run")
+	if err != nil {
+		return nil, err
+	}
+
+	// Output format: " * [would prune] origin/foo"
+	var refs []string
+	for _, line := range strings.Split(out, "\n") {
+		_, ref, found := strings.Cut(line, "[would prune]")
+		if !found {
+			continue
+		}
+		if ref = strings.TrimSpace(ref); ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// PruneRemotes deletes stale remote-tracking refs under remote.
+func (g *Git) PruneRemotes(remote string) error {
+	_, err := g.run("remote", "prune", remote)
+	return err
+}
+
+// Merge merges the given branch into the current branch. On conflict it
+// returns ErrMergeConflict and leaves the merge in progress.
+func (g *Git) Merge(branch string) error {
+	_, err := g.run("merge", branch)
+	return g.conflictError(err)
+}
+
+// MergeNoFF merges the given branch with --no-ff flag and a custom message.
+// On conflict it returns ErrMergeConflict and leaves the merge in progress.
+func (g *Git) MergeNoFF(branch, message string) error {
+	_, err := g.run("merge", "--no-ff", "-m", message, branch)
+	return g.conflictError(err)
+}
+
+// DeleteRemoteBranch deletes a branch on the remote.
+func (g *Git) DeleteRemoteBranch(remote, branch string) error {
+	_, err := g.run("push", remote, "--delete", branch)
+	return err
+}
+
+// Rebase rebases the current branch onto the given ref. On conflict it
+// returns ErrMergeConflict and leaves the rebase in progress.
+func (g *Git) Rebase(onto string) error {
+	_, err := g.run("rebase", onto)
+	return g.conflictError(err)
+}
+
+// RebaseOptions configures RebaseWithOptions.
+type RebaseOptions struct {
+	Autostash bool   // Stash local changes before rebasing and reapply them after
+	Exec      string // Shell command to run after each replayed commit (--exec)
+}
+
+// RebaseWithOptions rebases the current branch onto the given ref.
+// On conflict it returns ErrMergeConflict and the rebase is left in
+// progress; use ConflictedFiles to inspect it, then ContinueRebase or
+// AbortRebase.
+func (g *Git) RebaseWithOptions(onto string, opts RebaseOptions) error {
+	args := []string{"rebase"}
+	if opts.Autostash {
+		args = append(args, "--autostash")
+	}
+	if opts.Exec != "" {
+		args = append(args, "--exec", opts.Exec)
+	}
+	_, err := g.run(append(args, onto)...)
+	return g.conflictError(err)
+}
+
+// ContinueRebase continues a rebase after conflicts have been resolved and
+// staged. The commit messages are kept as-is (no editor is opened).
+func (g *Git) ContinueRebase() error {
+	_, err := g.run("-c", "core.editor=true", "rebase", "--continue")
+	return err
+}
+
+// AbortMerge aborts a merge in progress.
+func (g *Git) AbortMerge() error {
+	_, err := g.run("merge", "--abort")
+	return err
+}
+
+// CheckConflicts performs a test merge to check if source can be merged into target
+// without conflicts. Returns a list of conflicting files, or empty slice if clean.
+// The merge is always aborted after checking - no actual changes are made.
+//
+// The caller must ensure the working directory is clean before calling this.
+// After return, the working directory is restored to the target branch.
+func (g *Git) CheckConflicts(source, target string) ([]string, error) {
+	// Checkout the target branch
+	if err := g.Checkout(target); err != nil {
+		return nil, fmt.Errorf("checkout target %s: %w", target, err)
+	}
+
+	// Attempt test merge with --no-commit --no-ff
+	// We need to capture both stdout and stderr to detect conflicts
+	_, mergeErr := g.runMergeCheck("merge", "--no-commit", "--no-ff", source)
+
+	if mergeErr != nil {
+		// ZFC: Use git's porcelain output to detect conflicts instead of parsing stderr.
+		// GetConflictingFiles() uses `git diff --diff-filter=U` which is the proper way.
+		conflicts, err := g.GetConflictingFiles()
+		if err == nil && len(conflicts) > 0 {
+			// Abort the test merge (best-effort cleanup)
+			_ = g.AbortMerge()
+			return conflicts, nil
+		}
+
+		// No unmerged files detected - this is some other merge error
+		_ = g.AbortMerge()
+		return nil, mergeErr
+	}
+
+	// Merge succeeded (no conflicts) - abort the test merge
+	// Use reset since --abort won't work on successful merge (best-effort cleanup)
+	_, _ = g.run("reset", "--hard", "HEAD")
+	return nil, nil
+}
+
+// CanMergeCleanly reports whether branch would merge into HEAD without
+// conflicts, and if not, which paths would conflict. The merge is computed
+// with `git merge-tree --write-tree` (git 2.38+), which touches neither the
+// working tree nor the index. On older git, a test merge is done in a
+// temporary detached worktree instead.
+func (g *Git) CanMergeCleanly(branch string) (bool, []string, error) {
+	out, err := g.runRaw("merge-tree", "--write-tree", "--name-only", "--no-messages", "-z", "HEAD", branch)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return false, nil, err
+		}
+		switch exitErr.ExitCode() {
+		case 1: // Conflicts: the paths are on stdout
+			var gitErr *GitError
+			if errors.As(err, &gitErr) {
+				out = gitErr.Stdout
+			}
+		case 129: // Usage error: git predates --write-tree
+			return g.canMergeInWorktree(branch)
+		default:
+			return false, nil, err
+		}
+	}
+
+	// Output is "<tree>\0<path>\0<path>\0..." with a path listed per stage
+	var conflicts []string
+	seen := make(map[string]bool)
+	fields := strings.Split(out, "\x00")
+	for _, path := range fields[1:] {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			conflicts = append(conflicts, path)
+		}
+	}
+	return len(conflicts) == 0, conflicts, nil
+}
+
+// canMergeInWorktree is the CanMergeCleanly fallback for git without
+// merge-tree --write-tree: it test-merges in a throwaway detached worktree.
+func (g *Git) canMergeInWorktree(branch string) (bool, []string, error) {
+	tmpDir, err := os.MkdirTemp("", "gt-merge-check-")
+	if err != nil {
+		return false, nil, fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	wtPath := filepath.Join(tmpDir, "worktree")
+	if _, err := g.run("worktree", "add", "--detach", wtPath, "HEAD"); err != nil {
+		return false, nil, err
+	}
+	defer func() { _, _ = g.run("worktree", "remove", "--force", wtPath) }()
+
+	wt := NewGit(wtPath)
+	if _, mergeErr := wt.runMergeCheck("merge", "--no-commit", "--no-ff", branch); mergeErr != nil {
+		conflicts, err := wt.GetConflictingFiles()
+		if err == nil && len(conflicts) > 0 {
+			return false, conflicts, nil
+		}
+		return false, nil, mergeErr
+	}
+	return true, nil, nil
+}
+
+// runMergeCheck runs a git merge command and returns error info from both stdout and stderr.
+// ZFC: Returns GitError with raw output for agent observation.
+func (g *Git) runMergeCheck(args ...string) (string, error) {
+	g.recorder.record(args, nil, nil)
+	cmd := g.command(args...)
+	cmd.Dir = g.workDir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		// ZFC: Return raw output for observation, don't interpret CONFLICT
+		return "", g.wrapError(err, stdout.String(), stderr.String(), args)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// GetConflictingFiles returns the list of files with merge conflicts, or
+// nil if there are none.
+// ZFC: Uses git's porcelain output (diff --diff-filter=U) instead of parsing stderr.
+//
+// Deprecated: Use ConflictedPaths, which this wraps.
+func (g *Git) GetConflictingFiles() ([]string, error) {
+	paths, err := g.ConflictedPaths()
+	if len(paths) == 0 {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// ConflictType identifies how a path conflicted, using git's two-letter
+// porcelain status code for unmerged entries.
+type ConflictType string
+
+// Conflict types reported by `git status --porcelain` for unmerged paths.
+const (
+	ConflictBothDeleted   ConflictType = "DD"
+	ConflictAddedByUs     ConflictType = "AU"
+	ConflictDeletedByThem ConflictType = "UD"
+	ConflictAddedByThem   ConflictType = "UA"
+	ConflictDeletedByUs   ConflictType = "DU"
+	ConflictBothAdded     ConflictType = "AA"
+	ConflictBothModified  ConflictType = "UU"
+)
+
+// ConflictedFile is an unmerged path and the kind of conflict it has.
+type ConflictedFile struct {
+	Path string
+	Type ConflictType
+}
+
+// ConflictedFiles returns the unmerged paths of an in-progress merge, rebase,
+// or cherry-pick along with their conflict types, so callers can pick a
+// resolution strategy per type (e.g. deleted-by-them vs both-modified).
+func (g *Git) ConflictedFiles() ([]ConflictedFile, error) {
+	out, err := g.runRaw("status", "--porcelain", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []ConflictedFile
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		code := entry[:2]
+		if code[0] == 'R' || code[0] == 'C' {
+			i++ // Renames and copies are followed by the original path
+			continue
+		}
+		switch ConflictType(code) {
+		case ConflictBothDeleted, ConflictAddedByUs, ConflictDeletedByThem,
+			ConflictAddedByThem, ConflictDeletedByUs, ConflictBothAdded, ConflictBothModified:
+			files = append(files, ConflictedFile{Path: entry[3:], Type: ConflictType(code)})
+		}
+	}
+	return files, nil
+}
+
+// ConflictedPaths returns the unmerged paths of an in-progress merge,
+// rebase, or cherry-pick, or an empty slice if there are none. Use
+// ConflictedFiles to also get each path's conflict type.
+func (g *Git) ConflictedPaths() ([]string, error) {
+	out, err := g.runRaw("diff", "--name-only", "--diff-filter=U", "-z")
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// conflictError wraps a failed merge, rebase or cherry-pick in
+// ErrMergeConflict if it stopped on conflicts, leaving other errors as they
+// are.
+func (g *Git) conflictError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if paths, cErr := g.ConflictedPaths(); cErr == nil && len(paths) > 0 {
+		return fmt.Errorf("%w: %w", ErrMergeConflict, err)
+	}
+	return err
+}
+
+// ConflictStages holds the blob IDs of an unmerged path's index stages.
+// A stage is empty when that side doesn't have the file (e.g. it was added
+// on only one side, or deleted on one side).
+type ConflictStages struct {
+	Path   string
+	Base   string // Stage 1: common ancestor
+	Ours   string // Stage 2: the branch being merged into (upstream, during a rebase)
+	Theirs string // Stage 3: the branch being merged in (the commit being replayed)
+}
+
+// ConflictVersions returns the base, ours, and theirs blob IDs of an
+// unmerged path. Use `git cat-file blob <id>` (or Show) to read the contents.
+func (g *Git) ConflictVersions(path string) (*ConflictStages, error) {
+	out, err := g.runRaw("ls-files", "-u", "-z", "--", path)
+	if err != nil {
+		return nil, err
+	}
+
+	stages := &ConflictStages{Path: path}
+	found := false
+	// Each entry is "<mode> <object> <stage>\t<path>"
+	for _, entry := range strings.Split(out, "\x00") {
+		info, _, ok := strings.Cut(entry, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 3 {
+			continue
+		}
+		found = true
+		switch fields[2] {
+		case "1":
+			stages.Base = fields[1]
+		case "2":
+			stages.Ours = fields[1]
+		case "3":
+			stages.Theirs = fields[1]
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not conflicted", path)
+	}
+	return stages, nil
+}
+
+// AutoResolveTrivial resolves conflicts where only one side actually changed
+// the file: if ours matches the base, theirs is taken (and vice versa), and
+// if both sides made the same change either is taken. Resolved files are
+// staged and returned. Genuine conflicts, and those involving a deletion,
+// are left untouched; call ConflictedFiles to see what remains.
+func (g *Git) AutoResolveTrivial() ([]string, error) {
+	files, err := g.ConflictedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved []string
+	for _, f := range files {
+		if f.Type != ConflictBothModified && f.Type != ConflictBothAdded {
+			continue
+		}
+		v, err := g.ConflictVersions(f.Path)
+		if err != nil {
+			return resolved, err
+		}
+
+		var side string
+		switch {
+		case v.Ours == v.Theirs:
+			side = "--ours"
+		case v.Base != "" && v.Ours == v.Base:
+			side = "--theirs"
+		case v.Base != "" && v.Theirs == v.Base:
+			side = "--ours"
+		default:
+			continue
+		}
+
+		if _, err := g.run("checkout", side, "--", f.Path); err != nil {
+			return resolved, err
+		}
+		if _, err := g.run("add", "--", f.Path); err != nil {
+			return resolved, err
+		}
+		resolved = append(resolved, f.Path)
+	}
+	return resolved, nil
+}
+
+// ResolveStrategy is how ResolveConflict settles a conflicted path.
+type ResolveStrategy string
+
+// Conflict resolution strategies.
+const (
+	ResolveOurs   ResolveStrategy = "ours"   // Take our side (upstream, during a rebase)
+	ResolveTheirs ResolveStrategy = "theirs" // Take their side (the commit being replayed)
+	ResolveUnion  ResolveStrategy = "union"  // Keep both sides' lines, without markers
+)
+
+// ResolveConflict resolves a conflicted path with strategy and stages the
+// result. Taking a side that deleted the file deletes it; a union needs the
+// file on both sides.
+func (g *Git) ResolveConflict(path string, strategy ResolveStrategy) error {
+	v, err := g.ConflictVersions(path)
+	if err != nil {
+		return err
+	}
+
+	switch strategy {
+	case ResolveOurs, ResolveTheirs:
+		blob := v.Ours
+		if strategy == ResolveTheirs {
+			blob = v.Theirs
+		}
+		if blob == "" {
+			_, err := g.run("rm", "--quiet", "--", path)
+			return err
+		}
+		if _, err := g.run("checkout", "--"+string(strategy), "--", path); err != nil {
+			return err
+		}
+	case ResolveUnion:
+		if v.Ours == "" || v.Theirs == "" {
+			return fmt.Errorf("%s was deleted on one side; union needs both", path)
+		}
+		merged, err := g.unionMerge(v)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(g.workDir, path), []byte(merged), 0644); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown resolve strategy %q", strategy)
+	}
+	return g.MarkResolved(path)
+}
+
+// unionMerge merges the stages of a conflicted path with git merge-file
+// --union, which keeps the lines of both sides instead of conflict markers.
+func (g *Git) unionMerge(v *ConflictStages) (string, error) {
+	dir, err := os.MkdirTemp("", "gt-union-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	files := make([]string, 3)
+	for i, blob := range []string{v.Ours, v.Base, v.Theirs} {
+		content := ""
+		if blob != "" { // No base when both sides added the file
+			if content, err = g.runRaw("cat-file", "blob", blob); err != nil {
+				return "", err
+			}
+		}
+		files[i] = filepath.Join(dir, fmt.Sprintf("stage%d", i))
+		if err := os.WriteFile(files[i], []byte(content), 0644); err != nil {
+			return "", err
+		}
+	}
+	return g.runRaw("merge-file", "-p", "--union", files[0], files[1], files[2])
+}
+
+// MarkResolved stages path as resolved: its working tree content is added,
+// or if it no longer exists, its removal is staged.
+func (g *Git) MarkResolved(path string) error {
+	if _, err := os.Lstat(filepath.Join(g.workDir, path)); os.IsNotExist(err) {
+		_, err := g.run("rm", "--cached", "--quiet", "--", path)
+		return err
+	}
+	_, err := g.run("add", "--", path)
+	return err
+}
+
+// InProgressOperation returns the operation that stopped for conflicts:
+// "rebase", "merge", "cherry-pick" or "revert", or "" if none.
+func (g *Git) InProgressOperation() (string, error) {
+	gitDir, err := g.run("rev-parse", "--git-dir")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(g.workDir, gitDir)
+	}
+	for _, marker := range []struct{ path, op string }{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+	} {
+		if _, err := os.Stat(filepath.Join(gitDir, marker.path)); err == nil {
+			return marker.op, nil
+		}
+	}
+	return "", nil
+}
+
+// Continue continues the in-progress rebase, merge, cherry-pick or revert
+// once its conflicts are resolved, keeping the prepared commit message.
+func (g *Git) Continue() error {
+	op, err := g.InProgressOperation()
+	if err != nil {
+		return err
+	}
+	switch op {
+	case "":
+		return fmt.Errorf("no merge, rebase, cherry-pick or revert in progress")
+	case "merge":
+		_, err = g.run("commit", "--no-edit")
+	default:
+		_, err = g.run("-c", "core.editor=true", op, "--continue")
+	}
+	return err
+}
+
+// AbortRebase aborts a rebase in progress.
+func (g *Git) AbortRebase() error {
+	_, err := g.run("rebase", "--abort")
+	return err
+}
+
+// CherryPickOptions configures CherryPickWithOptions. By default a pick
+// that would change nothing, because its changes are already present,
+// stops with ErrNothingToCommit.
+type CherryPickOptions struct {
+	AllowEmpty bool // Record such picks as empty commits
+	SkipEmpty  bool // Drop such picks and go on with the rest
+}
+
+// CherryPick applies the commits named by refs, in order, onto HEAD.
+// A ref may also be a range such as "main..feature". See
+// CherryPickWithOptions for how it stops.
+func (g *Git) CherryPick(refs ...string) error {
+	return g.CherryPickWithOptions(CherryPickOptions{}, refs...)
+}
+
+// CherryPickWithOptions applies the commits named by refs onto HEAD. When
+// a pick conflicts it returns ErrMergeConflict, and when a pick would
+// change nothing (unless opts allow or skip it) ErrNothingToCommit; either
+// way the cherry-pick is left in progress, to be resolved and continued
+// (Continue) or abandoned (CherryPickAbort). Picks before it are kept.
+func (g *Git) CherryPickWithOptions(opts CherryPickOptions, refs ...string) error {
+	if len(refs) == 0 {
+		return fmt.Errorf("cherry-pick: no commits given")
+	}
+	if opts.AllowEmpty && opts.SkipEmpty {
+		return fmt.Errorf("cherry-pick: AllowEmpty and SkipEmpty are exclusive")
+	}
+	args := []string{"cherry-pick"}
+	if opts.AllowEmpty {
+		// --allow-empty for commits that were empty, the other for ones that became empty
+		args = append(args, "--allow-empty", "--keep-redundant-commits")
+	}
+	_, err := g.run(append(args, refs...)...)
+	// Skipping resumes the sequence, which may stop on the next empty pick
+	for opts.SkipEmpty && isEmptyCherryPick(err) {
+		_, err = g.run("cherry-pick", "--skip")
+	}
+	return g.cherryPickError(err)
+}
+
+// isEmptyCherryPick reports whether err is a cherry-pick that stopped
+// because the picked commit would change nothing.
+func isEmptyCherryPick(err error) bool {
+	var gitErr *GitError
+	return errors.As(err, &gitErr) &&
+		(strings.Contains(gitErr.Stderr, "cherry-pick is now empty") || isNothingToCommit(gitErr.Stdout))
+}
+
+// cherryPickError classifies a failed cherry-pick as a conflict or an
+// empty pick, leaving other errors as they are.
+func (g *Git) cherryPickError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if isEmptyCherryPick(err) {
+		return fmt.Errorf("%w: %w", ErrNothingToCommit, err)
+	}
+	return g.conflictError(err)
+}
+
+// CherryPickAbort abandons a cherry-pick in progress, restoring HEAD to
+// where it was before CherryPick, including any picks already made.
+func (g *Git) CherryPickAbort() error {
+	_, err := g.run("cherry-pick", "--abort")
+	return err
+}
+
+// CreateBranch creates a new branch.
+func (g *Git) CreateBranch(name string) error {
+	_, err := g.run("branch", name)
+	return err
+}
+
+// CreateBranchFrom creates a new branch from a specific ref.
+func (g *Git) CreateBranchFrom(name, ref string) error {
+	_, err := g.run("branch", name, ref)
+	return err
+}
+
+// BranchExists checks if a branch exists locally.
+func (g *Git) BranchExists(name string) (bool, error) {
+	_, err := g.run("show-ref", "--verify", "--quiet", "refs/heads/"+name)
+	if err != nil {
+		// Exit code 1 means branch doesn't exist
+		var gitErr *GitError
+		if errors.As(err, &gitErr) && gitErr.ExitCode == 1 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoteBranchExists checks if a branch exists on the remote.
+func (g *Git) RemoteBranchExists(remote, branch string) (bool, error) {
+	_, err := g.run("ls-remote", "--heads", remote, branch)
+	if err != nil {
+		return false, err
+	}
+	// ls-remote returns empty if branch doesn't exist, need to check output
+	out, err := g.run("ls-remote", "--heads", remote, branch)
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// DeleteBranch deletes a local branch.
+func (g *Git) DeleteBranch(name string, force bool) error {
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+	_, err := g.run("branch", flag, name)
+	return err
+}
+
+// ListBranches returns all local branches matching a pattern.
+// Pattern uses git's pattern matching (e.g., "polecat/*" matches all polecat branches).
+// Returns branch names without the refs/heads/ prefix.
+func (g *Git) ListBranches(pattern string) ([]string, error) {
+	args := []string{"branch", "--list", "--format=%(refname:short)"}
+	if pattern != "" {
+		args = append(args, pattern)
+	}
+	out, err := g.run(args...)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// BranchesMergedInto returns the local branches whose tips are reachable
+// from target, i.e. fully merged into it. target itself is included if it
+// is a local branch. Returns an empty slice if none are merged.
+func (g *Git) BranchesMergedInto(target string) ([]string, error) {
+	out, err := g.run("for-each-ref", "--merged="+target, "--format=%(refname:short)", "refs/heads/")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return []string{}, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// BranchesWithoutUpstream returns the local branches that have no upstream
+// (tracking) branch configured, so a plain `git push` from them doesn't go
+// where expected. A configured upstream whose remote branch is gone still
+// counts as configured. Returns an empty slice if every branch tracks one.
+func (g *Git) BranchesWithoutUpstream() ([]string, error) {
+	out, err := g.run("for-each-ref", "--format=%(refname:short)%00%(upstream)", "refs/heads/")
+	if err != nil {
+		return nil, err
+	}
+	branches := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		name, upstream, ok := strings.Cut(line, "\x00")
+		if ok && upstream == "" {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
+// ResetBranch force-updates a branch to point to a ref.
+// This is useful for resetting stale polecat branches to main.
+func (g *Git) ResetBranch(name, ref string) error {
+	_, err := g.run("branch", "-f", name, ref)
+	return err
+}
+
+// Rev returns the commit hash for the given ref.
+func (g *Git) Rev(ref string) (string, error) {
+	return g.run("rev-parse", ref)
+}
+
+// MergeBase returns the best common ancestor of two refs.
+// Returns ErrNoMergeBase if the refs share no history.
+func (g *Git) MergeBase(a, b string) (string, error) {
+	out, err := g.run("merge-base", a, b)
+	if err != nil {
+		// Exit code 1 with no output means the histories are unrelated
+		var gitErr *GitError
+		if errors.As(err, &gitErr) && gitErr.ExitCode == 1 {
+			return "", fmt.Errorf("%s and %s: %w: %w", a, b, ErrNoMergeBase, err)
+		}
+		return "", err
+	}
+	return out, nil
+}
+
+// BranchBase returns the commit where branch diverged from baseBranch
+// (their merge-base). Commits in BranchBase(b, base)..b are unique to b,
+// even when the branch's original starting point was never recorded.
+func (g *Git) BranchBase(branch, baseBranch string) (string, error) {
+	base, err := g.MergeBase(baseBranch, branch)
+	if err != nil {
+		return "", fmt.Errorf("finding base of %s: %w", branch, err)
+	}
+	return base, nil
+}
+
+// IsAncestor checks if ancestor is an ancestor of descendant.
+func (g *Git) IsAncestor(ancestor, descendant string) (bool, error) {
+	_, err := g.run("merge-base", "--is-ancestor", ancestor, descendant)
+	if err != nil {
+		// Exit code 1 means not an ancestor; anything else (e.g. 128 for
+		// an unknown ref) is an error
+		var gitErr *GitError
+		if errors.As(err, &gitErr) && gitErr.ExitCode == 1 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CommitTrailers returns the trailers of the commit at ref, keyed by trailer
+// key. A key may appear more than once (e.g. Co-authored-by), so each maps to
+// its values in message order. Commits without trailers yield an empty map.
+// Folded values (continuation lines indented under a trailer) are unfolded
+// into one value. ref may name an annotated tag; its commit is read.
+func (g *Git) CommitTrailers(ref string) (map[string][]string, error) {
+	out, err := g.run("log", "-1", "--format=%(trailers:only,unfold)", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	return parseTrailerLines(out), nil
+}
+
+// parseTrailerLines parses unfolded "Key: value" trailer lines.
+func parseTrailerLines(out string) map[string][]string {
+	trailers := make(map[string][]string)
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		trailers[key] = append(trailers[key], strings.TrimSpace(value))
+	}
+	return trailers
+}
+
+// RemoteBranchesContaining returns the remote-tracking branches (e.g.
+// "origin/main") whose history includes ref. An empty result means the
+// commit has not been pushed, as far as the last fetch knows.
+func (g *Git) RemoteBranchesContaining(ref string) ([]string, error) {
+	out, err := g.run("branch", "-r", "--contains", ref, "--format=%(refname:short)")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// WorktreeAdd creates a new worktree at the given path with a new branch.
+// The new branch is created from the current HEAD.
+// Sparse checkout is enabled to exclude .claude/ from source repos.
+func (g *Git) WorktreeAdd(path, branch string) error {
+	if _, err := g.run("worktree", "add", "-b", branch, path); err != nil {
+		return err
+	}
+	return ConfigureSparseCheckout(path)
+}
+
+// WorktreeAddFromRef creates a new worktree at the given path with a new branch
+// starting from the specified ref (e.g., "origin/main").
+// Sparse checkout is enabled to exclude .claude/ from source repos.
+func (g *Git) WorktreeAddFromRef(path, branch, startPoint string) error {
+	if _, err := g.run("worktree", "add", "-b", branch, path, startPoint); err != nil {
+		return err
+	}
+	return ConfigureSparseCheckout(path)
+}
+
+// WorktreeAddDetached creates a new worktree at the given path with a detached HEAD.
+// Sparse checkout is enabled to exclude .claude/ from source repos.
+func (g *Git) WorktreeAddDetached(path, ref string) error {
+	if _, err := g.run("worktree", "add", "--detach", path, ref); err != nil {
+		return err
+	}
+	return ConfigureSparseCheckout(path)
+}
+
+// WorktreeAddExisting creates a new worktree at the given path for an existing branch.
+// Sparse checkout is enabled to exclude .claude/ from source repos.
+func (g *Git) WorktreeAddExisting(path, branch string) error {
+	if _, err := g.run("worktree", "add", path, branch); err != nil {
+		return err
+	}
+	return ConfigureSparseCheckout(path)
+}
+
+// WorktreeAddExistingForce creates a new worktree even if the branch is already checked out elsewhere.
+// This is useful for cross-rig worktrees where multiple clones need to be on main.
+// Sparse checkout is enabled to exclude .claude/ from source repos.
+func (g *Git) WorktreeAddExistingForce(path, branch string) error {
+	if _, err := g.run("worktree", "add", "--force", path, branch); err != nil {
+		return err
+	}
+	return ConfigureSparseCheckout(path)
+}
+
+// ConfigureSparseCheckout sets up sparse checkout for a clone or worktree to exclude .claude/.
+// This ensures source repo settings don't override Gas Town agent settings.
+// Exported for use by doctor checks.
+func ConfigureSparseCheckout(repoPath string) error {
+	// Enable sparse checkout
+	cmd := exec.Command(Binary(), "-C", repoPath, "config", "core.sparseCheckout", "true")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("enabling sparse checkout: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	// Get git dir for this repo/worktree
+	cmd = exec.Command(Binary(), "-C", repoPath, "rev-parse", "--git-dir")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	stderr.Reset()
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("getting git dir: %s", strings.TrimSpace(stderr.String()))
+	}
+	gitDir := strings.TrimSpace(stdout.String())
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repoPath, gitDir)
+	}
+
+	// Write patterns directly to sparse-checkout file
+	// (git sparse-checkout set --stdin escapes the ! character incorrectly)
+	// Exclude all Claude Code context files to prevent source repo instructions
+	// from interfering with Gas Town agent context:
+	// - .claude/      : settings, rules, agents, commands
+	// - CLAUDE.md     : primary context file
+	// - CLAUDE.local.md : personal context file
+	// - .mcp.json     : MCP server configuration
+	infoDir := filepath.Join(gitDir, "info")
+	if err := os.MkdirAll(infoDir, 0755); err != nil {
+		return fmt.Errorf("creating info dir: %w", err)
+	}
+	sparseFile := filepath.Join(infoDir, "sparse-checkout")
+	sparsePatterns := "/*\n!/.claude/\n!/CLAUDE.md\n!/CLAUDE.local.md\n!/.mcp.json\n"
+	if err := os.WriteFile(sparseFile, []byte(sparsePatterns), 0644); err != nil {
+		return fmt.Errorf("writing sparse-checkout: %w", err)
+	}
+
+	// Check if HEAD exists (repo has commits) before running read-tree
+	// Empty repos (no commits) don't need read-tree and it would fail
+	checkHead := exec.Command(Binary(), "-C", repoPath, "rev-parse", "--verify", "HEAD")
+	if err := checkHead.Run(); err != nil {
+		// No commits yet, sparse checkout config is set up for future use
+		return nil
+	}
+
+	// Reapply to remove excluded files
+	cmd = exec.Command(Binary(), "-C", repoPath, "read-tree", "-mu", "HEAD")
+	stderr.Reset()
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("applying sparse checkout: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// ExcludedContextFiles lists all Claude context files that should be excluded by sparse checkout.
+var ExcludedContextFiles = []string{
+	".claude",
+	"CLAUDE.md",
+	"CLAUDE.local.md",
+	".mcp.json",
+}
+
+// CheckExcludedFilesExist checks if any Claude context files still exist in the repo
+// after sparse checkout was configured. These files should have been removed by
+// git read-tree, but may remain if they were untracked or modified.
+// Returns a list of files that still exist and should be manually removed.
+func CheckExcludedFilesExist(repoPath string) []string {
+	var remaining []string
+	for _, file := range ExcludedContextFiles {
+		path := filepath.Join(repoPath, file)
+		if _, err := os.Stat(path); err == nil {
+			remaining = append(remaining, file)
+		}
+	}
+	return remaining
+}
+
+// IsSparseCheckoutConfigured checks if sparse checkout is enabled and configured
+// to exclude Claude Code context files for a given repo/worktree.
+// Returns true only if both core.sparseCheckout is true AND the sparse-checkout
+// file contains all required exclusion patterns.
+func IsSparseCheckoutConfigured(repoPath string) bool {
+	// Check if core.sparseCheckout is true
+	cmd := exec.Command(Binary(), "-C", repoPath, "config", "core.sparseCheckout")
+	output, err := cmd.Output()
+	if err != nil || strings.TrimSpace(string(output)) != "true" {
+		return false
+	}
+
+	// Get git dir for this repo/worktree
+	cmd = exec.Command(Binary(), "-C", repoPath, "rev-parse", "--git-dir")
+	output, err = cmd.Output()
+	if err != nil {
+		return false
+	}
+	gitDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repoPath, gitDir)
+	}
+
+	// Check if sparse-checkout file exists and excludes Claude context files
+	sparseFile := filepath.Join(gitDir, "info", "sparse-checkout")
+	content, err := os.ReadFile(sparseFile)
+	if err != nil {
+		return false
+	}
+
+	// Check for all required exclusion patterns
+	contentStr := string(content)
+	requiredPatterns := []string{
+		"!/.claude/",  // or legacy "!.claude/"
+		"!/CLAUDE.md", // or legacy without leading slash
+	}
+	for _, pattern := range requiredPatterns {
+		// Accept both with and without leading slash for backwards compatibility
+		legacyPattern := strings.TrimPrefix(pattern, "/")
+		if !strings.Contains(contentStr, pattern) && !strings.Contains(contentStr, legacyPattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// WorktreeRemove removes a worktree. Without force, a worktree with
+// modified or untracked files is kept and ErrWorktreeDirty returned.
+func (g *Git) WorktreeRemove(path string, force bool) error {
+	args := []string{"worktree", "remove", path}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err := g.run(args...)
+	var gitErr *GitError
+	if errors.As(err, &gitErr) && strings.Contains(gitErr.Stderr, "contains modified or untracked files") {
+		return fmt.Errorf("%w: %w", ErrWorktreeDirty, err)
+	}
+	return err
+}
+
+// WorktreePrune removes worktree entries for deleted paths.
+func (g *Git) WorktreePrune() error {
+	_, err := g.run("worktree", "prune")
+	return err
+}
+
+// Worktree represents a git worktree.
+type Worktree struct {
+	Path     string
+	Branch   string // Checked-out branch; empty when detached or bare
+	Commit   string // HEAD commit
+	Locked   bool
+	Detached bool // HEAD is detached
+}
+
+// WorktreeList returns all worktrees for this repository.
+func (g *Git) WorktreeList() ([]Worktree, error) {
+	out, err := g.run("worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	var worktrees []Worktree
+	var current Worktree
+
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			if current.Path != "" {
+				worktrees = append(worktrees, current)
+				current = Worktree{}
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			current.Path = strings.TrimPrefix(line, "worktree ")
+		case strings.HasPrefix(line, "HEAD "):
+			current.Commit = strings.TrimPrefix(line, "HEAD ")
+		case strings.HasPrefix(line, "branch "):
+			current.Branch = strings.TrimPrefix(line, "branch refs/heads/")
+		case line == "locked" || strings.HasPrefix(line, "locked "):
+			current.Locked = true
+		case line == "detached":
+			current.Detached = true
+		}
+	}
+
+	// Don't forget the last one
+	if current.Path != "" {
+		worktrees = append(worktrees, current)
+	}
+
+	return worktrees, nil
+}
+
+// WorktreeForBranch returns the path of the worktree that has branch checked
+// out, or "" if no worktree does.
+func (g *Git) WorktreeForBranch(branch string) (string, error) {
+	worktrees, err := g.WorktreeList()
+	if err != nil {
+		return "", err
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == branch {
+			return wt.Path, nil
+		}
+	}
+	return "", nil
+}
+
+// WorktreeLock locks a worktree so it is not pruned or removed, e.g. while
+// it lives on a removable disk. The reason is recorded for `git worktree list`.
+func (g *Git) WorktreeLock(path, reason string) error {
+	args := []string{"worktree", "lock"}
+	if reason != "" {
+		args = append(args, "--reason", reason)
+	}
+	_, err := g.run(append(args, path)...)
+	return err
+}
+
+// WorktreeUnlock unlocks a worktree locked with WorktreeLock.
+func (g *Git) WorktreeUnlock(path string) error {
+	_, err := g.run("worktree", "unlock", path)
+	return err
+}
+
+// CommitDates returns the author and committer dates of a commit in strict
+// ISO 8601 format, suitable for git's --date and GIT_COMMITTER_DATE.
+func (g *Git) CommitDates(ref string) (author, committer string, err error) {
+	out, err := g.run("log", "-1", "--format=%aI%x00%cI", ref, "--")
+	if err != nil {
+		return "", "", err
+	}
+	author, committer, _ = strings.Cut(out, "\x00")
+	return author, committer, nil
+}
+
+// BranchCreatedDate returns the date when a branch was created.
+// This uses the committer date of the first commit on the branch.
+// Returns date in YYYY-MM-DD format.
+func (g *Git) BranchCreatedDate(branch string) (string, error) {
+	// Get the date of the first commit on the branch that's not on main
+	// Use merge-base to find where the branch diverged from main
+	mergeBase, err := g.run("merge-base", "main", branch)
+	if err != nil {
+		// If merge-base fails, fall back to the branch tip's date
+		out, err := g.run("log", "-1", "--format=%cs", branch)
+		if err != nil {
+			return "", err
+		}
+		return out, nil
+	}
+
+	// Get the first commit after the merge base on this branch
+	out, err := g.run("log", "--format=%cs", "--reverse", mergeBase+".."+branch)
+	if err != nil {
+		return "", err
+	}
+
+	// Get the first line (first commit's date)
+	lines := strings.Split(out, "\n")
+	if len(lines) > 0 && lines[0] != "" {
+		return lines[0], nil
+	}
+
+	// If no commits after merge-base, the branch points to merge-base
+	// Return the merge-base commit date
+	out, err = g.run("log", "-1", "--format=%cs", mergeBase)
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// FileStat is the number of lines changed in one file of a diff.
+type FileStat struct {
+	Path       string
+	Insertions int
+	Deletions  int
+	Binary     bool // Binary files have no line counts
+}
+
+// DiffStat summarizes the changes between two commits.
+type DiffStat struct {
+	Files      []FileStat
+	Insertions int
+	Deletions  int
+}
+
+// DiffStat returns per-file line counts for the changes from one commit to
+// another, parsed from `git diff --numstat -z`. Renamed files are reported
+// under their new path.
+func (g *Git) DiffStat(from, to string) (*DiffStat, error) {
+	out, err := g.runRaw("diff", "--numstat", "-z", from, to)
+	if err != nil {
+		return nil, err
+	}
+	return parseNumstat(out), nil
+}
+
+// ChangeType is how a file changed in a diff.
+type ChangeType string
+
+// Change types, from git's --name-status letters.
+const (
+	ChangeAdded       ChangeType = "added"
+	ChangeModified    ChangeType = "modified"
+	ChangeDeleted     ChangeType = "deleted"
+	ChangeRenamed     ChangeType = "renamed"
+	ChangeCopied      ChangeType = "copied"
+	ChangeTypeChanged ChangeType = "type-changed" // e.g. file became a symlink
+)
+
+// changeTypes maps --name-status letters to change types.
+var changeTypes = map[byte]ChangeType{
+	'A': ChangeAdded, 'M': ChangeModified, 'D': ChangeDeleted,
+	'R': ChangeRenamed, 'C': ChangeCopied, 'T': ChangeTypeChanged,
+}
+
+// FileChange is one file changed in a diff.
+type FileChange struct {
+	Path    string
+	OldPath string // Source path for renames and copies
+	Type    ChangeType
+}
+
+// DiffOptions configures Diff, DiffNameOnly and StagedDiff.
+type DiffOptions struct {
+	Staged bool     // Diff the index instead of the work tree (--cached)
+	Ref    string   // Revision or range to diff against, e.g. "HEAD~3" or "main...HEAD"
+	Paths  []string // Limit the diff to these paths
+	Stat   bool     // Return a --stat summary instead of the patch
+
+	IgnoreEOL        bool // Ignore CR/LF-only line differences
+	IgnoreWhitespace bool // Ignore whitespace-only changes (and blank lines)
+}
+
+// args returns the git diff arguments for the options, before any paths.
+func (o DiffOptions) args() []string {
+	var args []string
+	if o.Stat {
+		args = append(args, "--stat")
+	}
+	if o.IgnoreEOL {
+		args = append(args, "--ignore-cr-at-eol")
+	}
+	if o.IgnoreWhitespace {
+		args = append(args, "--ignore-all-space", "--ignore-blank-lines")
+	}
+	return args
+}
+
+// Diff returns the unified diff selected by opts: by default the unstaged
+// changes, with Staged the changes in the index, and with Ref the changes
+// since that revision (or within that range). StagedDiff ignores Staged
+// and Ref.
+func (g *Git) Diff(opts DiffOptions) (string, error) {
+	return g.runRaw(g.diffArgs(opts, opts.args()...)...)
+}
+
+// DiffNameOnly returns the paths Diff would show for opts. Returns an
+// empty slice when nothing changed.
+func (g *Git) DiffNameOnly(opts DiffOptions) ([]string, error) {
+	out, err := g.runRaw(g.diffArgs(opts, "--name-only", "-z")...)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, path := range strings.Split(out, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// diffArgs returns the git diff command line for opts with flags inserted
+// before the revisions and paths.
+func (g *Git) diffArgs(opts DiffOptions, flags ...string) []string {
+	args := append([]string{"diff"}, flags...)
+	if opts.Staged {
+		args = append(args, "--cached")
+	}
+	if opts.Ref != "" {
+		args = append(args, opts.Ref)
+	}
+	args = append(args, "--")
+	return append(args, g.pathspecs(opts.Paths)...)
+}
+
+// StagedDiff returns the diff between HEAD and the index, i.e. exactly what
+// the next commit will contain, regardless of unstaged changes.
+func (g *Git) StagedDiff(opts DiffOptions) (string, error) {
+	args := append([]string{"diff", "--cached"}, opts.args()...)
+	if len(opts.Paths) > 0 {
+		args = append(append(args, "--"), g.pathspecs(opts.Paths)...)
+	}
+	return g.runRaw(args...)
+}
+
+// StagedFiles returns the files changed between HEAD and the index, with
+// renames detected. Returns an empty slice when nothing is staged.
+func (g *Git) StagedFiles() ([]FileChange, error) {
+	out, err := g.runRaw("diff", "--cached", "--name-status", "-M", "-z")
+	if err != nil {
+		return nil, err
+	}
+	return parseNameStatus(out), nil
+}
+
+// parseNameStatus parses `--name-status -z` output. Each entry is
+// "<status>\0<path>\0", or for renames and copies
+// "<status><score>\0<old path>\0<new path>\0".
+func parseNameStatus(out string) []FileChange {
+	changes := []FileChange{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == "" {
+			continue
+		}
+		change := FileChange{Path: fields[i+1], Type: changeTypes[fields[i][0]]}
+		if change.Type == ChangeRenamed || change.Type == ChangeCopied {
+			if i+2 >= len(fields) {
+				break
+			}
+			change.OldPath, change.Path = fields[i+1], fields[i+2]
+			i++
+		}
+		if change.Type == "" {
+			change.Type = ChangeModified // Unmerged or unknown: treat as modified
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// BinaryFilesInDiff returns the paths of binary files changed in the diff
+// for ref, a revision or range as accepted by git diff (e.g. "HEAD~3..HEAD").
+// An empty ref means the staged changes. Returns an empty slice when no
+// binary files changed.
+func (g *Git) BinaryFilesInDiff(ref string) ([]string, error) {
+	args := []string{"diff", "--numstat", "-z"}
+	if ref == "" {
+		args = append(args, "--cached")
+	} else {
+		args = append(args, ref)
+	}
+	out, err := g.runRaw(args...)
+	if err != nil {
+		return nil, err
+	}
+	binaries := []string{}
+	for _, file := range parseNumstat(out).Files {
+		if file.Binary {
+			binaries = append(binaries, file.Path)
+		}
+	}
+	return binaries, nil
+}
+
+// parseNumstat parses `--numstat -z` output. Each entry is
+// "<added>\t<deleted>\t<path>\0", or for renames
+// "<added>\t<deleted>\t\0<old path>\0<new path>\0".
+// Binary files report "-" for both counts.
+func parseNumstat(out string) *DiffStat {
+	stat := &DiffStat{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		file := FileStat{Path: parts[2]}
+		if file.Path == "" && i+2 < len(fields) {
+			file.Path = fields[i+2]
+			i += 2
+		}
+		if parts[0] == "-" && parts[1] == "-" {
+			file.Binary = true
+		} else {
+			file.Insertions, _ = strconv.Atoi(parts[0])
+			file.Deletions, _ = strconv.Atoi(parts[1])
+		}
+		stat.Files = append(stat.Files, file)
+		stat.Insertions += file.Insertions
+		stat.Deletions += file.Deletions
+	}
+	return stat
+}
+
+// FormatPatch writes one mbox-formatted patch per commit in revRange (e.g.
+// "main..HEAD") to outputDir and returns the file paths in series order.
+// Commit messages (including trailers), authorship and author dates are
+// preserved. With coverLetter, a 0000-cover-letter.patch with a shortlog and
+// diffstat is written first; its subject and blurb are placeholders
+// ("*** SUBJECT HERE ***", "*** BLURB HERE ***") for the caller to fill in.
+func (g *Git) FormatPatch(revRange, outputDir string, coverLetter bool) ([]string, error) {
+	args := []string{"format-patch", "--output-directory", outputDir}
+	if coverLetter {
+		args = append(args, "--cover-letter")
+	}
+	out, err := g.run(append(args, revRange)...)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// FormatPatchMbox is FormatPatch that returns the whole series as a single
+// mbox stream instead of writing files.
+func (g *Git) FormatPatchMbox(revRange string, coverLetter bool) (string, error) {
+	args := []string{"format-patch", "--stdout"}
+	if coverLetter {
+		args = append(args, "--cover-letter")
+	}
+	return g.runRaw(append(args, revRange)...)
+}
+
+// CommitsAhead returns the number of commits that branch has ahead of base.
+// For example, CommitsAhead("main", "feature") returns how many commits
+// are on feature that are not on main.
+func (g *Git) CommitsAhead(base, branch string) (int, error) {
+	out, err := g.run("rev-list", "--count", base+".."+branch)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	_, err = fmt.Sscanf(out, "%d", &count)
+	if err != nil {
+		return 0, fmt.Errorf("parsing commit count: %w", err)
+	}
+
+	return count, nil
+}
+
+// CountCommitsBehind returns the number of commits that HEAD is behind the given ref.
+// For example, CountCommitsBehind("origin/main") returns how many commits
+// are on origin/main that are not on the current HEAD.
+func (g *Git) CountCommitsBehind(ref string) (int, error) {
+	out, err := g.run("rev-list", "--count", "HEAD.."+ref)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	_, err = fmt.Sscanf(out, "%d", &count)
+	if err != nil {
+		return 0, fmt.Errorf("parsing commit count: %w", err)
+	}
+
+	return count, nil
+}
+
+// StashCount returns the number of stashes in the repository.
+func (g *Git) StashCount() (int, error) {
+	out, err := g.run("stash", "list")
+	if err != nil {
+		return 0, err
+	}
+
+	if out == "" {
+		return 0, nil
+	}
+
+	// Count lines in the stash list
+	lines := strings.Split(out, "\n")
+	count := 0
+	for _, line := range lines {
+		if line != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// StashEntry is a stash from StashList.
+type StashEntry struct {
+	Ref     string // e.g. "stash@{0}"
+	Branch  string // Branch the stash was made on; "(no branch)" if detached
+	Message string // The stash message, or "<hash> <subject>" of its base
+}
+
+// Stash saves the local changes to tracked files, with message if not
+// empty, and reverts them. Returns ErrNothingToStash if there are none.
+func (g *Git) Stash(message string) error {
+	args := []string{"stash", "push"}
+	if message != "" {
+		args = append(args, "-m", message)
+	}
+	out, err := g.run(args...)
+	if err != nil {
+		return err
+	}
+	// git exits 0 in this case
+	if strings.Contains(out, "No local changes to save") {
+		return ErrNothingToStash
+	}
+	return nil
+}
+
+// StashPop reapplies the most recent stash and drops it. On conflict the
+// stash is kept and the conflicts are left in the working tree.
+func (g *Git) StashPop() error {
+	_, err := g.run("stash", "pop")
+	return err
+}
+
+// StashList returns the stashes, most recent first.
+func (g *Git) StashList() ([]StashEntry, error) {
+	out, err := g.runRaw("stash", "list", "-z", "--format=%gd%x00%gs")
+	if err != nil {
+		return nil, err
+	}
+	entries := []StashEntry{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		entries = append(entries, parseStashEntry(fields[i], fields[i+1]))
+	}
+	return entries, nil
+}
+
+// parseStashEntry parses a stash's reflog subject: "On <branch>: <message>"
+// for a stash with a message, "WIP on <branch>: <hash> <subject>" without.
+func parseStashEntry(ref, subject string) StashEntry {
+	entry := StashEntry{Ref: ref, Message: subject}
+	rest, ok := strings.CutPrefix(subject, "WIP on ")
+	if !ok {
+		rest, ok = strings.CutPrefix(subject, "On ")
+	}
+	if ok {
+		if branch, message, found := strings.Cut(rest, ": "); found {
+			entry.Branch, entry.Message = branch, message
+		}
+	}
+	return entry
+}
+
+// UnpushedCommits returns the number of commits that are not pushed to the remote.
+// It checks if the current branch has an upstream and counts commits ahead.
+// Returns 0 if there is no upstream configured.
+func (g *Git) UnpushedCommits() (int, error) {
+	// Get the upstream branch
+	upstream, err := g.run("rev-parse", "--abbrev-ref", "@{u}")
+	if err != nil {
+		// No upstream configured - this is common for polecat branches
+		// Check if we can compare against origin/main instead
+		// If we can't get any reference, return 0 (benefit of the doubt)
+		return 0, nil
+	}
+
+	// Count commits between upstream and HEAD
+	out, err := g.run("rev-list", "--count", upstream+"..HEAD")
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	_, err = fmt.Sscanf(out, "%d", &count)
+	if err != nil {
+		return 0, fmt.Errorf("parsing unpushed count: %w", err)
+	}
+
+	return count, nil
+}
+
+// UncommittedWorkStatus contains information about uncommitted work in a repo.
+type UncommittedWorkStatus struct {
+	HasUncommittedChanges bool
+	StashCount            int
+	UnpushedCommits       int
+	// Details for error messages
+	ModifiedFiles   []string
+	UntrackedFiles  []string
+}
+
+// Clean returns true if there is no uncommitted work.
+func (s *UncommittedWorkStatus) Clean() bool {
+	return !s.HasUncommittedChanges && s.StashCount == 0 && s.UnpushedCommits == 0
+}
+
+// String returns a human-readable summary of uncommitted work.
+func (s *UncommittedWorkStatus) String() string {
+	var issues []string
+	if s.HasUncommittedChanges {
+		issues = append(issues, fmt.Sprintf("%d uncommitted change(s)", len(s.ModifiedFiles)+len(s.UntrackedFiles)))
+	}
+	if s.StashCount > 0 {
+		issues = append(issues, fmt.Sprintf("%d stash(es)", s.StashCount))
+	}
+	if s.UnpushedCommits > 0 {
+		issues = append(issues, fmt.Sprintf("%d unpushed commit(s)", s.UnpushedCommits))
+	}
+	if len(issues) == 0 {
+		return "clean"
+	}
+	return strings.Join(issues, ", ")
+}
+
+// CheckUncommittedWork performs a comprehensive check for uncommitted work.
+func (g *Git) CheckUncommittedWork() (*UncommittedWorkStatus, error) {
+	status := &UncommittedWorkStatus{}
+
+	// Check git status
+	gitStatus, err := g.Status()
+	if err != nil {
+		return nil, fmt.Errorf("checking git status: %w", err)
+	}
+	status.HasUncommittedChanges = !gitStatus.Clean
+	status.ModifiedFiles = append(gitStatus.Modified, gitStatus.Added...)
+	status.ModifiedFiles = append(status.ModifiedFiles, gitStatus.Deleted...)
+	status.UntrackedFiles = gitStatus.Untracked
+
+	// Check stashes
+	stashCount, err := g.StashCount()
+	if err != nil {
+		return nil, fmt.Errorf("checking stashes: %w", err)
+	}
+	status.StashCount = stashCount
+
+	// Check unpushed commits
+	unpushed, err := g.UnpushedCommits()
+	if err != nil {
+		return nil, fmt.Errorf("checking unpushed commits: %w", err)
+	}
+	status.UnpushedCommits = unpushed
+
+	return status, nil
+}
+
+// BranchPushedToRemote checks if a branch has been pushed to the remote.
+// Returns (pushed bool, unpushedCount int, err).
+// This handles polecat branches that don't have upstream tracking configured.
+func (g *Git) BranchPushedToRemote(localBranch, remote string) (bool, int, error) {
+	remoteBranch := remote + "/" + localBranch
+
+	// First check if the remote branch exists
+	exists, err := g.RemoteBranchExists(remote, localBranch)
+	if err != nil {
+		return false, 0, fmt.Errorf("checking remote branch: %w", err)
+	}
+
+	if !exists {
+		// Remote branch doesn't exist - count commits since origin/main (or HEAD if that fails)
+		count, err := g.run("rev-list", "--count", "origin/main..HEAD")
+		if err != nil {
+			// Fallback: just count all commits on HEAD
+			count, err = g.run("rev-list", "--count", "HEAD")
+			if err != nil {
+				return false, 0, fmt.Errorf("counting commits: %w", err)
+			}
+		}
+		var n int
+		_, err = fmt.Sscanf(count, "%d", &n)
+		if err != nil {
+			return false, 0, fmt.Errorf("parsing commit count: %w", err)
+		}
+		// If there are any commits since main, branch is not pushed
+		return n == 0, n, nil
+	}
+
+	// Remote branch exists - fetch to ensure we have the local tracking ref
+	// This handles the case where we just pushed and origin/branch doesn't exist locally yet
+	_, fetchErr := g.run("fetch", remote, localBranch)
+
+	// In worktrees, the fetch may not update refs/remotes/origin/<branch> due to
+	// missing refspecs. If the remote ref doesn't exist locally, create it from FETCH_HEAD.
+	// See: gt-cehl8 (gt done fails in worktrees due to missing origin tracking ref)
+	remoteRef := "refs/remotes/" + remoteBranch
+	if _, err := g.run("rev-parse", "--verify", remoteRef); err != nil {
+		// Remote ref doesn't exist locally - update it from FETCH_HEAD if fetch succeeded.
+		// Best-effort: if this fails, the code below falls back to ls-remote.
+		if fetchErr == nil {
+			_, _ = g.run("update-ref", remoteRef, "FETCH_HEAD")
+		}
+	}
+
+	// Check if local is ahead
+	count, err := g.run("rev-list", "--count", remoteBranch+"..HEAD")
+	if err != nil {
+		// Fallback: If we can't use the tracking ref (possibly missing remote.origin.fetch),
+		// get the remote commit SHA directly via ls-remote and compare.
+		// See: gt-0eh3r (gt done fails in worktree with missing remote.origin.fetch config)
+		remoteSHA, lsErr := g.run("ls-remote", remote, "refs/heads/"+localBranch)
+		if lsErr != nil {
+			return false, 0, fmt.Errorf("counting unpushed commits: %w (fallback also failed: %v)", err, lsErr)
+		}
+		// Parse SHA from ls-remote output (format: "<sha>\trefs/heads/<branch>")
+		remoteSHA = strings.TrimSpace(remoteSHA)
+		if remoteSHA == "" {
+			return false, 0, fmt.Errorf("counting unpushed commits: %w (remote branch not found)", err)
+		}
+		parts := strings.Fields(remoteSHA)
+		if len(parts) == 0 {
+			return false, 0, fmt.Errorf("counting unpushed commits: %w (invalid ls-remote output)", err)
+		}
+		remoteSHA = parts[0]
+
+		// Count commits from remote SHA to HEAD
+		count, err = g.run("rev-list", "--count", remoteSHA+"..HEAD")
+		if err != nil {
+			return false, 0, fmt.Errorf("counting unpushed commits (fallback): %w", err)
+		}
+	}
+
+	var n int
+	_, err = fmt.Sscanf(count, "%d", &n)
+	if err != nil {
+		return false, 0, fmt.Errorf("parsing unpushed count: %w", err)
+	}
+
+	return n == 0, n, nil
+}
+
+// LFSTrackedPatterns returns the patterns in the repository's root
+// .gitattributes that use the LFS filter (filter=lfs), in file order.
+// A missing .gitattributes yields no patterns.
+func (g *Git) LFSTrackedPatterns() ([]string, error) {
+	root, err := g.run("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, ".gitattributes"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading .gitattributes: %w", err)
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			if attr == "filter=lfs" {
+				patterns = append(patterns, fields[0])
+				break
+			}
+		}
+	}
+	return patterns, nil
+}
+
+// IsLFSRepo returns true if the repository's .gitattributes routes any
+// paths through the LFS filter.
+func (g *Git) IsLFSRepo() (bool, error) {
+	patterns, err := g.LFSTrackedPatterns()
+	if err != nil {
+		return false, err
+	}
+	return len(patterns) > 0, nil
+}
+
+// LFSTrackedFiles returns the subset of paths that git attributes route
+// through the LFS filter. Unlike matching LFSTrackedPatterns by hand, this
+// applies git's full attribute rules (nested .gitattributes, info/attributes).
+func (g *Git) LFSTrackedFiles(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	out, err := g.runRaw(append([]string{"check-attr", "-z", "filter", "--"}, g.pathspecs(paths)...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// -z output is "<path>\0<attribute>\0<value>\0" per path
+	var tracked []string
+	fields := strings.Split(out, "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		if fields[i+2] == "lfs" {
+			tracked = append(tracked, fields[i])
+		}
+	}
+	return tracked, nil
+}
+
+// IsLFSInstalled returns true if the git-lfs extension is available, i.e.
+// LFS-filtered files will be stored as pointers rather than full blobs.
+func IsLFSInstalled() bool {
+	_, err := exec.LookPath("git-lfs")
+	return err == nil
+}
+
+// RepoSize describes the object database, as reported by count-objects.
+type RepoSize struct {
+	LooseObjects  int   // Objects stored individually
+	LooseBytes    int64 // Disk used by loose objects
+	PackedObjects int   // Objects stored in packs
+	Packs         int   // Number of pack files; many suggests a repack
+	PackBytes     int64 // Disk used by packs
+	PrunePackable int   // Loose objects also present in packs
+	Garbage       int   // Files in the object directory that aren't objects
+	GarbageBytes  int64
+}
+
+// RepoSize returns object counts and sizes for the repository. It parses
+// `git count-objects -v`, whose sizes are exact KiB values (rather than the
+// rounded, human-readable ones of -H), and converts them to bytes.
+func (g *Git) RepoSize() (*RepoSize, error) {
+	out, err := g.run("count-objects", "-v")
+	if err != nil {
+		return nil, err
+	}
+
+	size := &RepoSize{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing count-objects %s: %w", key, err)
+		}
+		switch key {
+		case "count":
+			size.LooseObjects = int(n)
+		case "size":
+			size.LooseBytes = n * 1024
+		case "in-pack":
+			size.PackedObjects = int(n)
+		case "packs":
+			size.Packs = int(n)
+		case "size-pack":
+			size.PackBytes = n * 1024
+		case "prune-packable":
+			size.PrunePackable = int(n)
+		case "garbage":
+			size.Garbage = int(n)
+		case "size-garbage":
+			size.GarbageBytes = n * 1024
+		}
+	}
+	return size, nil
+}
+
+// Commit is a commit parsed from git log.
+type Commit struct {
+	Hash           string
+	ShortHash      string
+	Parents        []string // Parent hashes, first parent first; none for a root commit
+	Author         string
+	AuthorEmail    string
+	Date           time.Time // Author date
+	Committer      string
+	CommitterEmail string
+	Subject        string
+	Body           string              // Message after the subject, including trailers
+	Trailers       map[string][]string // Parsed trailers, as from CommitTrailers
+}
+
+// commitFormat prints the Commit fields NUL-separated; with -z each record is
+// NUL-terminated too, so subjects and bodies may contain any text.
+const commitFormat = "--format=%H%x00%h%x00%an%x00%ae%x00%aI%x00%cn%x00%ce%x00%s%x00%b%x00%(trailers:only,unfold)%x00%P"
+
+// commitFormatFields is the number of NUL-separated fields in commitFormat.
+const commitFormatFields = 11
+
+// LogOptions selects the commits returned by Log.
+type LogOptions struct {
+	Range    string    // Revision range, e.g. "main..HEAD"; default HEAD
+	MaxCount int       // Maximum number of commits; 0 for no limit
+	Since    time.Time // Only commits after this (committer) time; zero for no limit
+	Paths    []string  // Only commits touching these paths; none for all
+}
+
+// Log returns the commits selected by opts, newest first. A repository
+// with no commits yet has an empty log rather than an error.
+func (g *Git) Log(opts LogOptions) ([]Commit, error) {
+	var args []string
+	if opts.MaxCount > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", opts.MaxCount))
+	}
+	if !opts.Since.IsZero() {
+		args = append(args, "--since="+opts.Since.Format(time.RFC3339))
+	}
+	if opts.Range != "" {
+		args = append(args, opts.Range)
+	} else {
+		if _, err := g.run("rev-parse", "--verify", "--quiet", "HEAD^{commit}"); err != nil {
+			return []Commit{}, nil
+		}
+	}
+	if len(opts.Paths) > 0 {
+		args = append(append(args, "--"), g.pathspecs(opts.Paths)...)
+	}
+	return g.logCommits(args...)
+}
+
+// Show returns the commit ref names, with its parents and full message.
+// Returns ErrNotFound if ref doesn't name a commit.
+func (g *Git) Show(ref string) (*Commit, error) {
+	hash, err := g.run("rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		var gitErr *GitError
+		if errors.As(err, &gitErr) && gitErr.ExitCode == 1 {
+			return nil, fmt.Errorf("commit %q: %w", ref, ErrNotFound)
+		}
+		return nil, err
+	}
+	commits, err := g.logCommits("--no-walk", hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) != 1 {
+		return nil, fmt.Errorf("git log %s returned %d commits, want 1", hash, len(commits))
+	}
+	return &commits[0], nil
+}
+
+// logCommits runs git log with commitFormat and the given args.
+func (g *Git) logCommits(args ...string) ([]Commit, error) {
+	out, err := g.runRaw(append([]string{"log", "-z", commitFormat}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseCommits(out)
+}
+
+// CommitsForMolecule returns the commits reachable from ref (HEAD if empty)
+// with a Molecule trailer for molID, newest first. A commit that credits
+// several molecules matches any of them. Returns an empty slice when no
+// commit matches.
+func (g *Git) CommitsForMolecule(molID, ref string) ([]Commit, error) {
+	return g.commitsWithTrailer(ref, "Molecule", molID)
+}
+
+// commitsWithTrailer returns the commits reachable from ref with a trailer
+// key (matched case-insensitively, as git does) whose value is exactly value.
+func (g *Git) commitsWithTrailer(ref, key, value string) ([]Commit, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	// --grep narrows the walk cheaply; the trailers decide the match
+	candidates, err := g.logCommits("--fixed-strings", "--regexp-ignore-case", "--grep="+value, ref)
+	if err != nil {
+		return nil, err
+	}
+	commits := []Commit{}
+	for _, c := range candidates {
+		if hasTrailerValue(c.Trailers, key, value) {
+			commits = append(commits, c)
+		}
+	}
+	return commits, nil
+}
+
+// hasTrailerValue reports whether trailers has key (case-insensitively)
+// with the given value.
+func hasTrailerValue(trailers map[string][]string, key, value string) bool {
+	for k, values := range trailers {
+		if !strings.EqualFold(k, key) {
+			continue
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseCommits parses logCommits output.
+func parseCommits(out string) ([]Commit, error) {
+	commits := []Commit{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i+commitFormatFields <= len(fields); i += commitFormatFields {
+		f := fields[i : i+commitFormatFields]
+		date, err := time.Parse(time.RFC3339, f[4])
+		if err != nil {
+			return nil, fmt.Errorf("parsing date of %s: %w", f[0], err)
+		}
+		commits = append(commits, Commit{
+			Hash:           f[0],
+			ShortHash:      f[1],
+			Author:         f[2],
+			AuthorEmail:    f[3],
+			Date:           date,
+			Committer:      f[5],
+			CommitterEmail: f[6],
+			Subject:        f[7],
+			Body:           strings.TrimSpace(f[8]),
+			Trailers:       parseTrailerLines(f[9]),
+			Parents:        strings.Fields(f[10]),
+		})
+	}
+	return commits, nil
+}
+
+// OwnershipHuman is the Ownership key for lines from commits without an
+// Executed-By trailer, i.e. commits not made by an agent.
+const OwnershipHuman = "(human)"
+
+// Ownership counts the lines at HEAD owned by each agent: every line is
+// blamed to the commit that last changed it, and attributed to that
+// commit's Executed-By trailer (OwnershipHuman if it has none). Paths
+// (files or directories) scope the count; none means the whole tree,
+// which runs blame on every file and can be slow in large repos.
+func (g *Git) Ownership(paths ...string) (map[string]int, error) {
+	out, err := g.runRaw(append([]string{"ls-tree", "-r", "-z", "--name-only", "HEAD", "--"}, g.pathspecs(paths)...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]int)
+	identities := make(map[string]string) // Commit hash → Executed-By, parsed once per commit
+	for _, file := range strings.Split(out, "\x00") {
+		if file == "" {
+			continue
+		}
+		lines, err := g.blameLineCounts(file)
+		if err != nil {
+			return nil, err
+		}
+		for hash, n := range lines {
+			identity, ok := identities[hash]
+			if !ok {
+				trailers, err := g.CommitTrailers(hash)
+				if err != nil {
+					return nil, err
+				}
+				identity = OwnershipHuman
+				if values := trailers["Executed-By"]; len(values) > 0 && values[0] != "" {
+					identity = values[0]
+				}
+				identities[hash] = identity
+			}
+			owners[identity] += n
+		}
+	}
+	return owners, nil
+}
+
+// blameLineCounts returns how many of file's lines at HEAD each commit owns.
+func (g *Git) blameLineCounts(file string) (map[string]int, error) {
+	out, err := g.runRaw("blame", "--porcelain", "HEAD", "--", file)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := parseBlamePorcelain(out)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, line := range lines {
+		counts[line.Commit]++
+	}
+	return counts, nil
+}
+
+// BlameLine is one line of a file as reported by Blame.
+type BlameLine struct {
+	Commit      string // Commit that last changed the line; all zeros if uncommitted
+	Author      string
+	AuthorEmail string
+	AuthorTime  time.Time
+	LineNo      int    // 1-based line number in the blamed file
+	Content     string // Line text, without the newline
+}
+
+// Blame returns who last changed each line of path at rev, in file order.
+// An empty rev blames the working tree file, so uncommitted lines are
+// attributed to the zero commit.
+func (g *Git) Blame(path, rev string) ([]BlameLine, error) {
+	args := []string{"blame", "--porcelain"}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	out, err := g.runRaw(append(append(args, "--"), g.pathspecs([]string{path})...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlamePorcelain(out)
+}
+
+// parseBlamePorcelain parses `git blame --porcelain` output. Each line is a
+// "<hash> <orig-line> <final-line>[ <group-size>]" header, the commit's
+// "author ..." info lines only the first time the hash appears, then the
+// tab-prefixed text.
+func parseBlamePorcelain(out string) ([]BlameLine, error) {
+	type author struct {
+		name, email string
+		time        time.Time
+	}
+	authors := make(map[string]*author)
+	lines := []BlameLine{}
+	var current BlameLine
+	for _, line := range strings.Split(out, "\n") {
+		if content, ok := strings.CutPrefix(line, "\t"); ok {
+			// File content, which could look like a header
+			if a := authors[current.Commit]; a != nil {
+				current.Author, current.AuthorEmail, current.AuthorTime = a.name, a.email, a.time
+			}
+			current.Content = content
+			lines = append(lines, current)
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if (len(fields) == 3 || len(fields) == 4) && isHexHash(fields[0]) {
+			lineNo, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("parsing blame header %q: %w", line, err)
+			}
+			current = BlameLine{Commit: fields[0], LineNo: lineNo}
+			if authors[current.Commit] == nil {
+				authors[current.Commit] = &author{}
+			}
+			continue
+		}
+
+		a := authors[current.Commit]
+		if a == nil {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			a.name = value
+		case "author-mail":
+			a.email = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+		case "author-time":
+			secs, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing blame author-time %q: %w", value, err)
+			}
+			a.time = time.Unix(secs, 0)
+		}
+	}
+	return lines, nil
+}
+
+// isHexHash reports whether s is a full SHA-1 or SHA-256 object name.
+func isHexHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// IntroducedBy returns the oldest commit that changed the number of
+// occurrences of pattern in path (git's -S "pickaxe"), i.e. the commit that
+// introduced it. An empty path searches the whole tree. Returns ErrNotFound
+// if no commit matches.
+func (g *Git) IntroducedBy(path, pattern string) (*Commit, error) {
+	return g.pickaxe("-S"+pattern, path)
+}
+
+// IntroducedByRegexp is IntroducedBy for a regular expression, matched
+// against added or removed lines (git's -G).
+func (g *Git) IntroducedByRegexp(path, pattern string) (*Commit, error) {
+	return g.pickaxe("-G"+pattern, path)
+}
+
+// pickaxe returns the oldest commit matching a -S or -G search.
+func (g *Git) pickaxe(search, path string) (*Commit, error) {
+	args := []string{search}
+	if path != "" {
+		args = append(args, "--", g.pathspecs([]string{path})[0])
+	}
+	commits, err := g.logCommits(args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("%s in %q: %w", search, path, ErrNotFound)
+	}
+	// Log lists newest first; the introducing commit is the oldest match
+	return &commits[len(commits)-1], nil
+}
+
+// WithRollback runs fn with all-or-nothing semantics for local repository
+// state. Uncommitted changes (including untracked files) are stashed first,
+// so fn starts from a clean tree; they are restored afterwards either way.
+// If fn returns an error, the repository is rolled back: any merge, rebase or
+// cherry-pick in progress is aborted, the original branch is checked out and
+// hard-reset to its snapshot, untracked files fn created are removed, and
+// local branches are restored (branches fn created are deleted, branches it
+// moved are reset). fn's error is returned.
+//
+// Rollback is destructive: anything fn wrote to the working tree is
+// discarded. It cannot undo effects outside the local repository, such as
+// pushes, remote branch deletions, tags, or stashes fn itself created.
+func (g *Git) WithRollback(fn func(tx *Git) error) error {
+	head, err := g.Rev("HEAD")
+	if err != nil {
+		return fmt.Errorf("snapshotting HEAD: %w", err)
+	}
+	branch, _ := g.run("symbolic-ref", "-q", "--short", "HEAD") // Empty when detached
+	branches, err := g.refSnapshot("refs/heads/")
+	if err != nil {
+		return fmt.Errorf("snapshotting branches: %w", err)
+	}
+
+	status, err := g.Status()
 	if err != nil {
-		// Exit code 1 means not an ancestor, not an error
-		if strings.Contains(err.Error(), "exit status 1") {
-			return false, nil
+		return fmt.Errorf("snapshotting working tree: %w", err)
+	}
+	// Remember the snapshot by hash: fn may push stashes of its own
+	var snapshot string
+	if !status.Clean {
+		if _, err := g.run("stash", "push", "--include-untracked", "-m", "gt: WithRollback snapshot"); err != nil {
+			return fmt.Errorf("stashing uncommitted changes: %w", err)
+		}
+		if snapshot, err = g.Rev("refs/stash"); err != nil {
+			return fmt.Errorf("snapshotting uncommitted changes: %w", err)
 		}
-		return false, err
 	}
-	return true, nil
-}
 
-// WorktreeAdd creates a new worktree at the given path with a new branch.
-// The new branch is created from the current HEAD.
-// Sparse checkout is enabled to exclude .claude/ from source repos.
-func (g *Git) WorktreeAdd(path, branch string) error {
-	if _, err := g.run("worktree", "add", "-b", branch, path); err != nil {
-		return err
+	fnErr := fn(g)
+	if fnErr != nil {
+		if err := g.rollback(head, branch, branches); err != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", fnErr, err)
+		}
 	}
-	return ConfigureSparseCheckout(path)
-}
 
-// WorktreeAddFromRef creates a new worktree at the given path with a new branch
-// starting from the specified ref (e.g., "origin/main").
-// Sparse checkout is enabled to exclude .claude/ from source repos.
-func (g *Git) WorktreeAddFromRef(path, branch, startPoint string) error {
-	if _, err := g.run("worktree", "add", "-b", branch, path, startPoint); err != nil {
-		return err
+	if snapshot != "" {
+		if err := g.popStash(snapshot); err != nil {
+			restoreErr := fmt.Errorf("restoring uncommitted changes (they remain in the stash): %w", err)
+			if fnErr != nil {
+				return fmt.Errorf("%w (%v)", fnErr, restoreErr)
+			}
+			return restoreErr
+		}
 	}
-	return ConfigureSparseCheckout(path)
+	return fnErr
 }
 
-// WorktreeAddDetached creates a new worktree at the given path with a detached HEAD.
-// Sparse checkout is enabled to exclude .claude/ from source repos.
-func (g *Git) WorktreeAddDetached(path, ref string) error {
-	if _, err := g.run("worktree", "add", "--detach", path, ref); err != nil {
+// popStash pops the stash entry with the given commit hash, wherever it now
+// is in the stash list.
+func (g *Git) popStash(hash string) error {
+	out, err := g.run("stash", "list", "--format=%H")
+	if err != nil {
 		return err
 	}
-	return ConfigureSparseCheckout(path)
-}
-
-// WorktreeAddExisting creates a new worktree at the given path for an existing branch.
-// Sparse checkout is enabled to exclude .claude/ from source repos.
-func (g *Git) WorktreeAddExisting(path, branch string) error {
-	if _, err := g.run("worktree", "add", path, branch); err != nil {
-		return err
+	for i, h := range strings.Split(out, "\n") {
+		if h == hash {
+			_, err := g.run("stash", "pop", "--index", fmt.Sprintf("stash@{%d}", i))
+			return err
+		}
 	}
-	return ConfigureSparseCheckout(path)
+	return fmt.Errorf("stash %s: %w", hash, ErrNotFound)
 }
 
-// WorktreeAddExistingForce creates a new worktree even if the branch is already checked out elsewhere.
-// This is useful for cross-rig worktrees where multiple clones need to be on main.
-// Sparse checkout is enabled to exclude .claude/ from source repos.
-func (g *Git) WorktreeAddExistingForce(path, branch string) error {
-	if _, err := g.run("worktree", "add", "--force", path, branch); err != nil {
+// rollback restores the state snapshotted by WithRollback.
+func (g *Git) rollback(head, branch string, branches map[string]string) error {
+	// Best-effort: at most one of these is in progress
+	_, _ = g.run("merge", "--abort")
+	_, _ = g.run("rebase", "--abort")
+	_, _ = g.run("cherry-pick", "--abort")
+
+	// Put branches back first so the original branch checks out at its snapshot
+	current, err := g.refSnapshot("refs/heads/")
+	if err != nil {
 		return err
 	}
-	return ConfigureSparseCheckout(path)
-}
-
-// ConfigureSparseCheckout sets up sparse checkout for a clone or worktree to exclude .claude/.
-// This ensures source repo settings don't override Gas Town agent settings.
-// Exported for use by doctor checks.
-func ConfigureSparseCheckout(repoPath string) error {
-	// Enable sparse checkout
-	cmd := exec.Command("git", "-C", repoPath, "config", "core.sparseCheckout", "true")
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("enabling sparse checkout: %s", strings.TrimSpace(stderr.String()))
+	if branch != "" {
+		if _, err := g.run("checkout", "--force", "--detach", head); err != nil {
+			return err
+		}
 	}
-
-	// Get git dir for this repo/worktree
-	cmd = exec.Command("git", "-C", repoPath, "rev-parse", "--git-dir")
-	var stdout bytes.Buffer
-	cmd.Stdout = &stdout
-	stderr.Reset()
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("getting git dir: %s", strings.TrimSpace(stderr.String()))
+	for ref, sha := range current {
+		if old, ok := branches[ref]; !ok {
+			if _, err := g.run("update-ref", "-d", ref, sha); err != nil {
+				return err
+			}
+		} else if old != sha {
+			if _, err := g.run("update-ref", ref, old, sha); err != nil {
+				return err
+			}
+		}
 	}
-	gitDir := strings.TrimSpace(stdout.String())
-	if !filepath.IsAbs(gitDir) {
-		gitDir = filepath.Join(repoPath, gitDir)
+	for ref, sha := range branches {
+		if _, ok := current[ref]; !ok {
+			if _, err := g.run("update-ref", ref, sha, ""); err != nil {
+				return err
+			}
+		}
 	}
 
-	// Write patterns directly to sparse-checkout file
-	// (git sparse-checkout set --stdin escapes the ! character incorrectly)
-	// Exclude all Claude Code context files to prevent source repo instructions
-	// from interfering with Gas Town agent context:
-	// - .claude/      : settings, rules, agents, commands
-	// - CLAUDE.md     : primary context file
-	// - CLAUDE.local.md : personal context file
-	// - .mcp.json     : MCP server configuration
-	infoDir := filepath.Join(gitDir, "info")
-	if err := os.MkdirAll(infoDir, 0755); err != nil {
-		return fmt.Errorf("creating info dir: %w", err)
+	if branch != "" {
+		if _, err := g.run("checkout", "--force", branch); err != nil {
+			return err
+		}
 	}
-	sparseFile := filepath.Join(infoDir, "sparse-checkout")
-	sparsePatterns := "/*\n!/.claude/\n!/CLAUDE.md\n!/CLAUDE.local.md\n!/.mcp.json\n"
-	if err := os.WriteFile(sparseFile, []byte(sparsePatterns), 0644); err != nil {
-		return fmt.Errorf("writing sparse-checkout: %w", err)
+	if _, err := g.run("reset", "--hard", head); err != nil {
+		return err
 	}
+	_, err = g.run("clean", "-fd")
+	return err
+}
 
-	// Check if HEAD exists (repo has commits) before running read-tree
-	// Empty repos (no commits) don't need read-tree and it would fail
-	checkHead := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "HEAD")
-	if err := checkHead.Run(); err != nil {
-		// No commits yet, sparse checkout config is set up for future use
+// Identity is the name and email of a commit author or committer.
+type Identity struct {
+	Name  string
+	Email string
+}
+
+// Env returns the environment variables that make id both the author and
+// the committer of a commit, overriding user.name and user.email and any
+// GIT_AUTHOR_* or GIT_COMMITTER_* already set. Returns nil if either
+// field is empty.
+func (id Identity) Env() []string {
+	if id.Name == "" || id.Email == "" {
 		return nil
 	}
-
-	// Reapply to remove excluded files
-	cmd = exec.Command("git", "-C", repoPath, "read-tree", "-mu", "HEAD")
-	stderr.Reset()
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("applying sparse checkout: %s", strings.TrimSpace(stderr.String()))
+	return []string{
+		"GIT_AUTHOR_NAME=" + id.Name, "GIT_AUTHOR_EMAIL=" + id.Email,
+		"GIT_COMMITTER_NAME=" + id.Name, "GIT_COMMITTER_EMAIL=" + id.Email,
 	}
-	return nil
 }
 
-// ExcludedContextFiles lists all Claude context files that should be excluded by sparse checkout.
-var ExcludedContextFiles = []string{
-	".claude",
-	"CLAUDE.md",
-	"CLAUDE.local.md",
-	".mcp.json",
+// CommitOptions configures commits made by the Git wrapper.
+type CommitOptions struct {
+	Trailers    []string // "Key: value" trailers added to the message
+	Identity    Identity // Author and committer, e.g. an agent's identity
+	AuthorName  string   // Overrides the author (with AuthorEmail), even with Identity
+	AuthorEmail string
+
+	// Args are extra git commit arguments, e.g. passed through from a
+	// command line; pathspecs follow a "--". Config entries ("key=value")
+	// are passed as inline -c config, and Env is added to git's
+	// environment. These are for CommitWithTrailers only.
+	Args   []string
+	Config []string
+	Env    []string
+
+	// Stdin, Stdout and Stderr attach git to the caller's streams instead
+	// of capturing its output, e.g. to a terminal so git can open the
+	// editor. Setting Stdout or Stderr attaches. Stdin is ignored when
+	// the message is given.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// SignFormat signs the commit in the given format, "openpgp" or "ssh"
+	// (gpg.format), with SigningKey: a key ID for openpgp, or for ssh the
+	// path to a key file or a literal "key::<public key>". Either one alone
+	// also signs, using the configured value for the other.
+	SignFormat string
+	SigningKey string
 }
 
-// CheckExcludedFilesExist checks if any Claude context files still exist in the repo
-// after sparse checkout was configured. These files should have been removed by
-// git read-tree, but may remain if they were untracked or modified.
-// Returns a list of files that still exist and should be manually removed.
-func CheckExcludedFilesExist(repoPath string) []string {
-	var remaining []string
-	for _, file := range ExcludedContextFiles {
-		path := filepath.Join(repoPath, file)
-		if _, err := os.Stat(path); err == nil {
-			remaining = append(remaining, file)
+// commitSigningError classifies a failure of a commit signed per opts.
+func (g *Git) commitSigningError(err error, opts CommitOptions) error {
+	switch {
+	case opts.SignFormat == "" && opts.SigningKey == "":
+		return err
+	case opts.SignFormat != "ssh":
+		return g.classifySigningError(err, opts.SigningKey)
+	case opts.SigningKey != "" && !strings.HasPrefix(opts.SigningKey, "key::"):
+		if _, statErr := os.Stat(opts.SigningKey); statErr != nil {
+			return fmt.Errorf("%w (%s): %w", ErrSigningKeyMissing, opts.SigningKey, err)
 		}
 	}
-	return remaining
+	return err
 }
 
-// IsSparseCheckoutConfigured checks if sparse checkout is enabled and configured
-// to exclude Claude Code context files for a given repo/worktree.
-// Returns true only if both core.sparseCheckout is true AND the sparse-checkout
-// file contains all required exclusion patterns.
-func IsSparseCheckoutConfigured(repoPath string) bool {
-	// Check if core.sparseCheckout is true
-	cmd := exec.Command("git", "-C", repoPath, "config", "core.sparseCheckout")
-	output, err := cmd.Output()
-	if err != nil || strings.TrimSpace(string(output)) != "true" {
-		return false
-	}
-
-	// Get git dir for this repo/worktree
-	cmd = exec.Command("git", "-C", repoPath, "rev-parse", "--git-dir")
-	output, err = cmd.Output()
-	if err != nil {
-		return false
+// signArgs returns the git arguments that sign a commit made with args,
+// as inline config ("-c gpg.format=ssh") before the subcommand and -S
+// after it. Returns args unchanged when not signing.
+func (o CommitOptions) signArgs(args []string) []string {
+	if o.SignFormat == "" && o.SigningKey == "" {
+		return args
 	}
-	gitDir := strings.TrimSpace(string(output))
-	if !filepath.IsAbs(gitDir) {
-		gitDir = filepath.Join(repoPath, gitDir)
+	var signed []string
+	if o.SignFormat != "" {
+		signed = append(signed, "-c", "gpg.format="+o.SignFormat)
 	}
-
-	// Check if sparse-checkout file exists and excludes Claude context files
-	sparseFile := filepath.Join(gitDir, "info", "sparse-checkout")
-	content, err := os.ReadFile(sparseFile)
-	if err != nil {
-		return false
+	if o.SigningKey != "" {
+		signed = append(signed, "-c", "user.signingkey="+o.SigningKey)
 	}
+	return append(append(signed, args[0], "-S"), args[1:]...)
+}
 
-	// Check for all required exclusion patterns
-	contentStr := string(content)
-	requiredPatterns := []string{
-		"!/.claude/",  // or legacy "!.claude/"
-		"!/CLAUDE.md", // or legacy without leading slash
-	}
-	for _, pattern := range requiredPatterns {
-		// Accept both with and without leading slash for backwards compatibility
-		legacyPattern := strings.TrimPrefix(pattern, "/")
-		if !strings.Contains(contentStr, pattern) && !strings.Contains(contentStr, legacyPattern) {
-			return false
-		}
+// env returns the environment overrides for the options.
+func (o CommitOptions) env() []string {
+	env := o.Identity.Env()
+	if o.AuthorName == "" || o.AuthorEmail == "" {
+		return env
 	}
-	return true
+	// Later entries win, so the author overrides Identity's
+	return append(env, "GIT_AUTHOR_NAME="+o.AuthorName, "GIT_AUTHOR_EMAIL="+o.AuthorEmail)
 }
 
-// WorktreeRemove removes a worktree.
-func (g *Git) WorktreeRemove(path string, force bool) error {
-	args := []string{"worktree", "remove", path}
-	if force {
-		args = append(args, "--force")
+// applyTrailers adds "Key: value" trailers to message; see
+// ApplyTrailersBatch.
+func (g *Git) applyTrailers(message string, trailers []string) (string, error) {
+	batch := make([]Trailer, len(trailers))
+	for i, t := range trailers {
+		key, value, _ := strings.Cut(t, ":")
+		batch[i] = Trailer{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)}
 	}
-	_, err := g.run(args...)
-	return err
+	return g.ApplyTrailersBatch(message, batch)
 }
 
-// WorktreePrune removes worktree entries for deleted paths.
-func (g *Git) WorktreePrune() error {
-	_, err := g.run("worktree", "prune")
-	return err
-}
+// Actions for Trailer.IfExists and Trailer.IfMissing, as understood by
+// git interpret-trailers.
+const (
+	TrailerAddIfDifferentNeighbor = "addIfDifferentNeighbor" // git's default for IfExists
+	TrailerAddIfDifferent         = "addIfDifferent"
+	TrailerAdd                    = "add" // git's default for IfMissing
+	TrailerReplace                = "replace"
+	TrailerDoNothing              = "doNothing"
+)
 
-// Worktree represents a git worktree.
-type Worktree struct {
-	Path   string
-	Branch string
-	Commit string
-}
+// Trailer is a trailer to apply with ApplyTrailersBatch.
+type Trailer struct {
+	Key   string
+	Value string
 
-// WorktreeList returns all worktrees for this repository.
-func (g *Git) WorktreeList() ([]Worktree, error) {
-	out, err := g.run("worktree", "list", "--porcelain")
-	if err != nil {
-		return nil, err
-	}
+	// IfExists is what to do when the message already has a trailer with
+	// this key: TrailerReplace, TrailerAddIfDifferent, TrailerDoNothing, ...
+	// Empty uses git's configured default (trailer.ifexists).
+	IfExists string
 
-	var worktrees []Worktree
-	var current Worktree
+	// IfMissing is what to do when the message has no trailer with this
+	// key: TrailerAdd or TrailerDoNothing (useful with TrailerReplace to
+	// only update existing trailers). Empty uses git's default.
+	IfMissing string
+}
 
-	for _, line := range strings.Split(out, "\n") {
-		if line == "" {
-			if current.Path != "" {
-				worktrees = append(worktrees, current)
-				current = Worktree{}
-			}
-			continue
+// ApplyTrailersBatch adds trailers to message's trailer block (after a
+// blank line if needed) in a single git interpret-trailers call. Each
+// trailer's IfExists/IfMissing applies to it alone.
+func (g *Git) ApplyTrailersBatch(message string, trailers []Trailer) (string, error) {
+	if len(trailers) == 0 {
+		return message, nil
+	}
+	args := []string{"interpret-trailers"}
+	for _, t := range trailers {
+		// The placement options apply to every following --trailer, so
+		// each trailer sets or resets both
+		if t.IfExists != "" {
+			args = append(args, "--if-exists", t.IfExists)
+		} else {
+			args = append(args, "--no-if-exists")
 		}
-
-		switch {
-		case strings.HasPrefix(line, "worktree "):
-			current.Path = strings.TrimPrefix(line, "worktree ")
-		case strings.HasPrefix(line, "HEAD "):
-			current.Commit = strings.TrimPrefix(line, "HEAD ")
-		case strings.HasPrefix(line, "branch "):
-			current.Branch = strings.TrimPrefix(line, "branch refs/heads/")
+		if t.IfMissing != "" {
+			args = append(args, "--if-missing", t.IfMissing)
+		} else {
+			args = append(args, "--no-if-missing")
 		}
+		args = append(args, "--trailer", t.Key+": "+t.Value)
 	}
-
-	// Don't forget the last one
-	if current.Path != "" {
-		worktrees = append(worktrees, current)
+	// Without a final newline, the subject would be taken as part of the
+	// trailer block and no blank line inserted
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
 	}
-
-	return worktrees, nil
+	return g.runCmd(nil, strings.NewReader(message), args...)
 }
 
-// BranchCreatedDate returns the date when a branch was created.
-// This uses the committer date of the first commit on the branch.
-// Returns date in YYYY-MM-DD format.
-func (g *Git) BranchCreatedDate(branch string) (string, error) {
-	// Get the date of the first commit on the branch that's not on main
-	// Use merge-base to find where the branch diverged from main
-	mergeBase, err := g.run("merge-base", "main", branch)
+// CommitToBranch creates a commit on branch from in-memory file contents,
+// without touching the working tree, the index, or the current checkout.
+// paths maps repository paths to their new contents (as regular 0644 files);
+// a nil value deletes the path. All other files are carried over from the
+// branch tip. If branch doesn't exist, it is created with a root commit
+// containing only paths. Returns the new commit's hash.
+//
+// The branch is updated with a compare-and-swap, so a concurrent update of
+// the branch makes this fail rather than lose commits. A branch checked out
+// in any worktree is refused with ErrBranchCheckedOut: moving it would leave
+// that worktree's index and files staging a revert of the new commit.
+func (g *Git) CommitToBranch(branch string, paths map[string][]byte, message string, opts CommitOptions) (string, error) {
+	wtPath, err := g.WorktreeForBranch(branch)
 	if err != nil {
-		// If merge-base fails, fall back to the branch tip's date
-		out, err := g.run("log", "-1", "--format=%cs", branch)
-		if err != nil {
-			return "", err
-		}
-		return out, nil
+		return "", err
+	}
+	if wtPath != "" {
+		return "", fmt.Errorf("%w: %s in %s", ErrBranchCheckedOut, branch, wtPath)
 	}
 
-	// Get the first commit after the merge base on this branch
-	out, err := g.run("log", "--format=%cs", "--reverse", mergeBase+".."+branch)
+	ref := "refs/heads/" + branch
+	parent, _ := g.run("rev-parse", "--verify", "--quiet", ref+"^{commit}") // Empty for a new branch
+
+	// Build the tree in a throwaway index so the real one is untouched
+	tmpDir, err := os.MkdirTemp("", "gt-commit-to-branch-")
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("creating temp dir: %w", err)
 	}
+	defer os.RemoveAll(tmpDir)
+	indexEnv := []string{"GIT_INDEX_FILE=" + filepath.Join(tmpDir, "index")}
 
-	// Get the first line (first commit's date)
-	lines := strings.Split(out, "\n")
-	if len(lines) > 0 && lines[0] != "" {
-		return lines[0], nil
+	if parent != "" {
+		if _, err := g.runCmd(indexEnv, nil, "read-tree", parent); err != nil {
+			return "", err
+		}
 	}
 
-	// If no commits after merge-base, the branch points to merge-base
-	// Return the merge-base commit date
-	out, err = g.run("log", "-1", "--format=%cs", mergeBase)
-	if err != nil {
-		return "", err
+	names := make([]string, 0, len(paths))
+	for path := range paths {
+		names = append(names, path)
 	}
-	return out, nil
-}
-
-// CommitsAhead returns the number of commits that branch has ahead of base.
-// For example, CommitsAhead("main", "feature") returns how many commits
-// are on feature that are not on main.
-func (g *Git) CommitsAhead(base, branch string) (int, error) {
-	out, err := g.run("rev-list", "--count", base+".."+branch)
-	if err != nil {
-		return 0, err
+	sort.Strings(names)
+	for _, path := range names {
+		content := paths[path]
+		if content == nil {
+			if _, err := g.runCmd(indexEnv, nil, "update-index", "--force-remove", "--", path); err != nil {
+				return "", err
+			}
+			continue
+		}
+		blob, err := g.runCmd(nil, bytes.NewReader(content), "hash-object", "-w", "--stdin")
+		if err != nil {
+			return "", err
+		}
+		cacheInfo := "100644," + strings.TrimSpace(blob) + "," + path
+		if _, err := g.runCmd(indexEnv, nil, "update-index", "--add", "--cacheinfo", cacheInfo); err != nil {
+			return "", err
+		}
 	}
 
-	var count int
-	_, err = fmt.Sscanf(out, "%d", &count)
+	tree, err := g.runCmd(indexEnv, nil, "write-tree")
 	if err != nil {
-		return 0, fmt.Errorf("parsing commit count: %w", err)
+		return "", err
 	}
 
-	return count, nil
-}
-
-// CountCommitsBehind returns the number of commits that HEAD is behind the given ref.
-// For example, CountCommitsBehind("origin/main") returns how many commits
-// are on origin/main that are not on the current HEAD.
-func (g *Git) CountCommitsBehind(ref string) (int, error) {
-	out, err := g.run("rev-list", "--count", "HEAD.."+ref)
+	message, err = g.applyTrailers(message, opts.Trailers)
 	if err != nil {
-		return 0, err
+		return "", err
 	}
-
-	var count int
-	_, err = fmt.Sscanf(out, "%d", &count)
+	args := []string{"commit-tree", strings.TrimSpace(tree), "-F", "-"}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	hash, err := g.runCmd(opts.env(), strings.NewReader(message), opts.signArgs(args)...)
 	if err != nil {
-		return 0, fmt.Errorf("parsing commit count: %w", err)
+		return "", g.commitSigningError(err, opts)
 	}
+	hash = strings.TrimSpace(hash)
 
-	return count, nil
+	// An empty old value requires that the ref not exist yet
+	if _, err := g.run("update-ref", ref, hash, parent); err != nil {
+		return "", err
+	}
+	return hash, nil
 }
 
-// StashCount returns the number of stashes in the repository.
-func (g *Git) StashCount() (int, error) {
-	out, err := g.run("stash", "list")
-	if err != nil {
-		return 0, err
+// BranchDivergence is how a local branch relates to its upstream.
+type BranchDivergence struct {
+	Branch   string // Local branch, e.g. "polecat/nux"
+	Upstream string // Tracking ref, e.g. "origin/polecat/nux"
+	Ahead    int    // Local commits not on the upstream
+	Behind   int    // Upstream commits not on the local branch
+	Gone     bool   // The upstream branch was deleted on the remote
+}
+
+// DivergenceOptions configures DivergenceReport.
+type DivergenceOptions struct {
+	Fetch bool // Fetch (with --prune) first, to compare against fresh remote state
+}
+
+// DivergenceReport returns, for each local branch tracking a branch on
+// remote, how far ahead and behind its upstream it is, and whether the
+// upstream is gone. All branches are computed in a single git call.
+// Counts are relative to the last fetch unless opts.Fetch is set.
+func (g *Git) DivergenceReport(remote string, opts DivergenceOptions) ([]BranchDivergence, error) {
+	if opts.Fetch {
+		if _, err := g.run("fetch", "--prune", remote); err != nil {
+			return nil, err
+		}
 	}
 
-	if out == "" {
-		return 0, nil
+	out, err := g.run("for-each-ref",
+		"--format=%(refname:short)%00%(upstream)%00%(upstream:short)%00%(upstream:track,nobracket)",
+		"refs/heads/")
+	if err != nil {
+		return nil, err
 	}
 
-	// Count lines in the stash list
-	lines := strings.Split(out, "\n")
-	count := 0
-	for _, line := range lines {
-		if line != "" {
-			count++
+	var report []BranchDivergence
+	prefix := "refs/remotes/" + remote + "/"
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 4 || !strings.HasPrefix(fields[1], prefix) {
+			continue
 		}
+		d := BranchDivergence{Branch: fields[0], Upstream: fields[2]}
+		// Track is "", "gone", "ahead N", "behind N" or "ahead N, behind M"
+		for _, part := range strings.Split(fields[3], ", ") {
+			switch {
+			case part == "gone":
+				d.Gone = true
+			case strings.HasPrefix(part, "ahead "):
+				d.Ahead, _ = strconv.Atoi(strings.TrimPrefix(part, "ahead "))
+			case strings.HasPrefix(part, "behind "):
+				d.Behind, _ = strconv.Atoi(strings.TrimPrefix(part, "behind "))
+			}
+		}
+		report = append(report, d)
 	}
-	return count, nil
+	return report, nil
 }
 
-// UnpushedCommits returns the number of commits that are not pushed to the remote.
-// It checks if the current branch has an upstream and counts commits ahead.
-// Returns 0 if there is no upstream configured.
-func (g *Git) UnpushedCommits() (int, error) {
-	// Get the upstream branch
-	upstream, err := g.run("rev-parse", "--abbrev-ref", "@{u}")
+// SignatureStatus is the result of checking a commit's signature.
+type SignatureStatus struct {
+	// Code is git's %G? status: G (good), B (bad), U (good, unknown
+	// validity), X (good, expired), Y (good, expired key), R (good, revoked
+	// key), E (cannot be checked, e.g. missing key) or N (unsigned).
+	Code   string
+	Signer string // Signer identity, if known
+	Key    string // Signing key fingerprint or ID, if known
+}
+
+// Signed returns true if the commit carries a signature of any validity.
+func (s SignatureStatus) Signed() bool {
+	return s.Code != "" && s.Code != "N"
+}
+
+// Valid returns true if the signature is good and trusted (code G).
+func (s SignatureStatus) Valid() bool {
+	return s.Code == "G"
+}
+
+// VerifyCommit checks the signature of the commit at ref. An unsigned commit
+// is not an error; its Code is "N".
+func (g *Git) VerifyCommit(ref string) (*SignatureStatus, error) {
+	out, err := g.run("show", "--no-patch", "--format=%G?%x00%GS%x00%GK", ref)
 	if err != nil {
-		// No upstream configured - this is common for polecat branches
-		// Check if we can compare against origin/main instead
-		// If we can't get any reference, return 0 (benefit of the doubt)
-		return 0, nil
+		return nil, err
 	}
+	fields := strings.SplitN(out, "\x00", 3)
+	for len(fields) < 3 {
+		fields = append(fields, "")
+	}
+	return &SignatureStatus{Code: fields[0], Signer: fields[1], Key: fields[2]}, nil
+}
 
-	// Count commits between upstream and HEAD
-	out, err := g.run("rev-list", "--count", upstream+"..HEAD")
+// BranchesContaining returns the local and remote-tracking branches (e.g.
+// "main", "origin/main") whose history includes ref. Symbolic refs such as
+// origin/HEAD are omitted.
+func (g *Git) BranchesContaining(ref string) ([]string, error) {
+	out, err := g.run("branch", "--all", "--contains", ref, "--format=%(refname:short)%00%(symref)")
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	var branches []string
+	for _, line := range strings.Split(out, "\n") {
+		name, symref, _ := strings.Cut(line, "\x00")
+		if name != "" && symref == "" {
+			branches = append(branches, name)
+		}
 	}
+	return branches, nil
+}
 
-	var count int
-	_, err = fmt.Sscanf(out, "%d", &count)
+// TagsAtCommit returns the tags that point directly at ref.
+func (g *Git) TagsAtCommit(ref string) ([]string, error) {
+	return g.lines("tag", "--points-at", ref)
+}
+
+// TagsContaining returns the tags whose history includes ref, i.e. the
+// releases that shipped it.
+func (g *Git) TagsContaining(ref string) ([]string, error) {
+	return g.lines("tag", "--contains", ref)
+}
+
+// CreateSignedTag creates an annotated tag signed with signKey, or with the
+// default signing key (user.signingKey, else the committer identity) if
+// signKey is empty. git never prompts on a terminal here; a key that needs
+// a passphrase must be unlocked in gpg-agent beforehand (or gpg configured
+// for loopback pinentry). Failures wrap ErrSigningKeyMissing or
+// ErrSigningPassphrase when the cause can be determined.
+func (g *Git) CreateSignedTag(name, message, signKey string) error {
+	args := []string{"tag", "-s"}
+	if signKey != "" {
+		args = []string{"tag", "-u", signKey}
+	}
+	_, err := g.runCmd(nil, strings.NewReader(message), append(args, "-F", "-", name)...)
 	if err != nil {
-		return 0, fmt.Errorf("parsing unpushed count: %w", err)
+		return fmt.Errorf("signing tag %s: %w", name, g.classifySigningError(err, signKey))
 	}
+	return nil
+}
 
-	return count, nil
+// CreateTag creates a tag at HEAD: an annotated tag with message if
+// annotated, else a lightweight tag (message is ignored). Push doesn't
+// push tags on its own; push one with Push(remote, "refs/tags/"+name, false),
+// or all of them with PushOptions.Tags.
+func (g *Git) CreateTag(name, message string, annotated bool) error {
+	if !annotated {
+		_, err := g.run("tag", name)
+		return err
+	}
+	_, err := g.run("tag", "-a", "-m", message, name)
+	return err
 }
 
-// UncommittedWorkStatus contains information about uncommitted work in a repo.
-type UncommittedWorkStatus struct {
-	HasUncommittedChanges bool
-	StashCount            int
-	UnpushedCommits       int
-	// Details for error messages
-	ModifiedFiles   []string
-	UntrackedFiles  []string
+// ListTags returns the tags matching pattern (a glob such as "v1.*"; all
+// tags if empty), sorted by name.
+func (g *Git) ListTags(pattern string) ([]string, error) {
+	args := []string{"tag", "--list", "--sort=refname"}
+	if pattern != "" {
+		args = append(args, pattern)
+	}
+	return g.lines(args...)
 }
 
-// Clean returns true if there is no uncommitted work.
-func (s *UncommittedWorkStatus) Clean() bool {
-	return !s.HasUncommittedChanges && s.StashCount == 0 && s.UnpushedCommits == 0
+// DeleteTag deletes a local tag.
+func (g *Git) DeleteTag(name string) error {
+	_, err := g.run("tag", "-d", name)
+	return err
 }
 
-// String returns a human-readable summary of uncommitted work.
-func (s *UncommittedWorkStatus) String() string {
-	var issues []string
-	if s.HasUncommittedChanges {
-		issues = append(issues, fmt.Sprintf("%d uncommitted change(s)", len(s.ModifiedFiles)+len(s.UntrackedFiles)))
+// classifySigningError wraps a signing failure with ErrSigningKeyMissing or
+// ErrSigningPassphrase, keeping the original error for detail. Older gits
+// don't pass on gpg's messages, so for OpenPGP signing the key is looked up
+// directly: a key that exists but couldn't sign needed its passphrase.
+func (g *Git) classifySigningError(err error, signKey string) error {
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) {
+		return err
 	}
-	if s.StashCount > 0 {
-		issues = append(issues, fmt.Sprintf("%d stash(es)", s.StashCount))
+	stderr := strings.ToLower(gitErr.Stderr)
+	switch {
+	case strings.Contains(stderr, "no secret key"),
+		strings.Contains(stderr, "couldn't load public key"):
+		return fmt.Errorf("%w: %w", ErrSigningKeyMissing, err)
+	case strings.Contains(stderr, "inappropriate ioctl"),
+		strings.Contains(stderr, "pinentry"),
+		strings.Contains(stderr, "passphrase"):
+		return fmt.Errorf("%w (unlock the key in gpg-agent or allow loopback pinentry): %w", ErrSigningPassphrase, err)
 	}
-	if s.UnpushedCommits > 0 {
-		issues = append(issues, fmt.Sprintf("%d unpushed commit(s)", s.UnpushedCommits))
+
+	if format, _ := g.run("config", "gpg.format"); format != "" && format != "openpgp" {
+		return err
 	}
-	if len(issues) == 0 {
-		return "clean"
+	if signKey == "" {
+		signKey, _ = g.run("config", "user.signingKey")
 	}
-	return strings.Join(issues, ", ")
+	if signKey == "" {
+		ident, _ := g.run("var", "GIT_COMMITTER_IDENT")
+		if start, end := strings.Index(ident, "<"), strings.Index(ident, ">"); start >= 0 && end > start {
+			signKey = ident[start+1 : end]
+		}
+	}
+	if signKey == "" {
+		return err
+	}
+	program, _ := g.run("config", "gpg.program")
+	if program == "" {
+		program = "gpg"
+	}
+	if exec.Command(program, "--batch", "--list-secret-keys", signKey).Run() != nil {
+		return fmt.Errorf("%w (%s): %w", ErrSigningKeyMissing, signKey, err)
+	}
+	return fmt.Errorf("%w (unlock the key in gpg-agent or allow loopback pinentry): %w", ErrSigningPassphrase, err)
 }
 
-// CheckUncommittedWork performs a comprehensive check for uncommitted work.
-func (g *Git) CheckUncommittedWork() (*UncommittedWorkStatus, error) {
-	status := &UncommittedWorkStatus{}
-
-	// Check git status
-	gitStatus, err := g.Status()
+// VerifyTag checks the signature of an annotated tag. An unsigned or
+// lightweight tag gets Code "N"; a bad signature gets Code "B" rather than
+// an error. Errors are returned only when the tag can't be read.
+func (g *Git) VerifyTag(name string) (*SignatureStatus, error) {
+	objType, err := g.run("cat-file", "-t", name)
 	if err != nil {
-		return nil, fmt.Errorf("checking git status: %w", err)
+		return nil, err
+	}
+	if objType != "tag" {
+		return &SignatureStatus{Code: "N"}, nil
 	}
-	status.HasUncommittedChanges = !gitStatus.Clean
-	status.ModifiedFiles = append(gitStatus.Modified, gitStatus.Added...)
-	status.ModifiedFiles = append(status.ModifiedFiles, gitStatus.Deleted...)
-	status.UntrackedFiles = gitStatus.Untracked
 
-	// Check stashes
-	stashCount, err := g.StashCount()
+	_, stderr, err := g.runCmdStderr(nil, nil, "verify-tag", "--raw", name)
 	if err != nil {
-		return nil, fmt.Errorf("checking stashes: %w", err)
+		var gitErr *GitError
+		if !errors.As(err, &gitErr) {
+			return nil, err
+		}
+		stderr = gitErr.Stderr
 	}
-	status.StashCount = stashCount
+	return parseGPGStatus(stderr), nil
+}
 
-	// Check unpushed commits
-	unpushed, err := g.UnpushedCommits()
-	if err != nil {
-		return nil, fmt.Errorf("checking unpushed commits: %w", err)
+// parseGPGStatus maps gpg --status-fd lines (as printed by verify-tag --raw)
+// to a SignatureStatus using the same codes as git's %G?.
+func parseGPGStatus(out string) *SignatureStatus {
+	status := &SignatureStatus{Code: "N"}
+	trusted := false
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(strings.TrimPrefix(line, "[GNUPG:] "))
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "GOODSIG", "BADSIG", "EXPSIG", "EXPKEYSIG", "REVKEYSIG":
+			status.Code = map[string]string{"GOODSIG": "U", "BADSIG": "B", "EXPSIG": "X", "EXPKEYSIG": "Y", "REVKEYSIG": "R"}[fields[0]]
+			if len(fields) > 1 {
+				status.Key = fields[1]
+			}
+			if len(fields) > 2 {
+				status.Signer = strings.Join(fields[2:], " ")
+			}
+		case "ERRSIG":
+			status.Code = "E"
+			if len(fields) > 1 {
+				status.Key = fields[1]
+			}
+		case "VALIDSIG":
+			if len(fields) > 1 {
+				status.Key = fields[1] // Prefer the full fingerprint
+			}
+		case "TRUST_FULLY", "TRUST_ULTIMATE":
+			trusted = true
+		}
 	}
-	status.UnpushedCommits = unpushed
+	if status.Code == "U" && trusted {
+		status.Code = "G"
+	}
+	return status
+}
 
-	return status, nil
+// lines runs a git command and splits its output into non-empty lines.
+func (g *Git) lines(args ...string) ([]string, error) {
+	out, err := g.run(args...)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
 }
 
-// BranchPushedToRemote checks if a branch has been pushed to the remote.
-// Returns (pushed bool, unpushedCount int, err).
-// This handles polecat branches that don't have upstream tracking configured.
-func (g *Git) BranchPushedToRemote(localBranch, remote string) (bool, int, error) {
-	remoteBranch := remote + "/" + localBranch
+// SquashOptions configures Squash.
+type SquashOptions struct {
+	// Message for the squashed commit; default is the oldest commit's message.
+	Message string
+
+	// PreserveTrailers lists trailer keys whose values are collected from
+	// every squashed commit into the new commit's trailer block, so that
+	// e.g. all contributing agents stay credited. Values are collapsed when
+	// the key matches case-insensitively and the value matches exactly; the
+	// first occurrence (oldest commit first) determines the order.
+	PreserveTrailers []string
+}
 
-	// First check if the remote branch exists
-	exists, err := g.RemoteBranchExists(remote, localBranch)
+// Squash replaces the commits in base..HEAD on the current branch with a
+// single commit having HEAD's tree and the oldest commit's author. The
+// working tree and index are untouched, so staged changes are refused
+// rather than silently dropped. Returns the new commit's hash.
+func (g *Git) Squash(base string, opts SquashOptions) (string, error) {
+	commits, err := g.Log(LogOptions{Range: base + "..HEAD"})
 	if err != nil {
-		return false, 0, fmt.Errorf("checking remote branch: %w", err)
+		return "", err
 	}
-
-	if !exists {
-		// Remote branch doesn't exist - count commits since origin/main (or HEAD if that fails)
-		count, err := g.run("rev-list", "--count", "origin/main..HEAD")
-		if err != nil {
-			// Fallback: just count all commits on HEAD
-			count, err = g.run("rev-list", "--count", "HEAD")
-			if err != nil {
-				return false, 0, fmt.Errorf("counting commits: %w", err)
-			}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("nothing to squash: no commits in %s..HEAD", base)
+	}
+	if _, err := g.run("diff", "--cached", "--quiet"); err != nil {
+		return "", fmt.Errorf("staged changes present; commit or unstage them before squashing")
+	}
+	head := commits[0].Hash
+	oldest := commits[len(commits)-1]
+
+	message := opts.Message
+	if message == "" {
+		message = oldest.Subject
+		if oldest.Body != "" {
+			message += "\n\n" + oldest.Body
 		}
-		var n int
-		_, err = fmt.Sscanf(count, "%d", &n)
-		if err != nil {
-			return false, 0, fmt.Errorf("parsing commit count: %w", err)
+	}
+	if len(opts.PreserveTrailers) > 0 {
+		message = stripTrailers(message, opts.PreserveTrailers)
+		if message, err = g.applyTrailers(message, unionTrailers(commits, opts.PreserveTrailers)); err != nil {
+			return "", err
 		}
-		// If there are any commits since main, branch is not pushed
-		return n == 0, n, nil
 	}
 
-	// Remote branch exists - fetch to ensure we have the local tracking ref
-	// This handles the case where we just pushed and origin/branch doesn't exist locally yet
-	_, fetchErr := g.run("fetch", remote, localBranch)
+	baseHash, err := g.Rev(base + "^{commit}")
+	if err != nil {
+		return "", err
+	}
+	env := CommitOptions{AuthorName: oldest.Author, AuthorEmail: oldest.AuthorEmail}.env()
+	hash, err := g.runCmd(env, strings.NewReader(message), "commit-tree", head+"^{tree}", "-p", baseHash, "-F", "-")
+	if err != nil {
+		return "", err
+	}
+	hash = strings.TrimSpace(hash)
+	if _, err := g.run("update-ref", "-m", "gt: squash "+base+"..HEAD", "HEAD", hash, head); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
 
-	// In worktrees, the fetch may not update refs/remotes/origin/<branch> due to
-	// missing refspecs. If the remote ref doesn't exist locally, create it from FETCH_HEAD.
-	// See: gt-cehl8 (gt done fails in worktrees due to missing origin tracking ref)
-	remoteRef := "refs/remotes/" + remoteBranch
-	if _, err := g.run("rev-parse", "--verify", remoteRef); err != nil {
-		// Remote ref doesn't exist locally - update it from FETCH_HEAD if fetch succeeded.
-		// Best-effort: if this fails, the code below falls back to ls-remote.
-		if fetchErr == nil {
-			_, _ = g.run("update-ref", remoteRef, "FETCH_HEAD")
+// unionTrailers returns "Key: value" trailers for keys collected from
+// commits (given newest first, as from Log), oldest commit first, with
+// duplicate values collapsed.
+func unionTrailers(commits []Commit, keys []string) []string {
+	var trailers []string
+	seen := make(map[string]bool)
+	for i := len(commits) - 1; i >= 0; i-- {
+		for _, key := range keys {
+			for k, values := range commits[i].Trailers {
+				if !strings.EqualFold(k, key) {
+					continue
+				}
+				for _, v := range values {
+					id := strings.ToLower(key) + "\x00" + v
+					if v != "" && !seen[id] {
+						seen[id] = true
+						trailers = append(trailers, key+": "+v)
+					}
+				}
+			}
 		}
 	}
+	return trailers
+}
 
-	// Check if local is ahead
-	count, err := g.run("rev-list", "--count", remoteBranch+"..HEAD")
-	if err != nil {
-		// Fallback: If we can't use the tracking ref (possibly missing remote.origin.fetch),
-		// get the remote commit SHA directly via ls-remote and compare.
-		// See: gt-0eh3r (gt done fails in worktree with missing remote.origin.fetch config)
-		remoteSHA, lsErr := g.run("ls-remote", remote, "refs/heads/"+localBranch)
-		if lsErr != nil {
-			return false, 0, fmt.Errorf("counting unpushed commits: %w (fallback also failed: %v)", err, lsErr)
-		}
-		// Parse SHA from ls-remote output (format: "<sha>\trefs/heads/<branch>")
-		remoteSHA = strings.TrimSpace(remoteSHA)
-		if remoteSHA == "" {
-			return false, 0, fmt.Errorf("counting unpushed commits: %w (remote branch not found)", err)
-		}
-		parts := strings.Fields(remoteSHA)
-		if len(parts) == 0 {
-			return false, 0, fmt.Errorf("counting unpushed commits: %w (invalid ls-remote output)", err)
+// stripTrailers removes lines for keys from the last paragraph of message,
+// so they can be re-added without duplication.
+func stripTrailers(message string, keys []string) string {
+	message = strings.TrimRight(message, "\n")
+	cut := strings.LastIndex(message, "\n\n")
+	if cut < 0 {
+		return message // A subject alone has no trailer block
+	}
+	var kept []string
+	for _, line := range strings.Split(message[cut+2:], "\n") {
+		key, _, _ := strings.Cut(line, ":")
+		drop := false
+		for _, k := range keys {
+			drop = drop || strings.EqualFold(strings.TrimSpace(key), k)
 		}
-		remoteSHA = parts[0]
-
-		// Count commits from remote SHA to HEAD
-		count, err = g.run("rev-list", "--count", remoteSHA+"..HEAD")
-		if err != nil {
-			return false, 0, fmt.Errorf("counting unpushed commits (fallback): %w", err)
+		if !drop {
+			kept = append(kept, line)
 		}
 	}
-
-	var n int
-	_, err = fmt.Sscanf(count, "%d", &n)
-	if err != nil {
-		return false, 0, fmt.Errorf("parsing unpushed count: %w", err)
+	if len(kept) == 0 {
+		return message[:cut]
 	}
-
-	return n == 0, n, nil
+	return message[:cut+2] + strings.Join(kept, "\n")
 }
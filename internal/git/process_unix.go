@@ -0,0 +1,18 @@
+//go:build !windows
+
+package git
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroupOnCancel runs cmd in its own process group and makes
+// context cancellation kill the whole group, so helpers git spawned (ssh,
+// remote helpers) die with it instead of holding its output open.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
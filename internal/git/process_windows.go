@@ -0,0 +1,9 @@
+//go:build windows
+
+package git
+
+import "os/exec"
+
+// killProcessGroupOnCancel is a no-op on Windows: cancellation kills only
+// the git process, and commandWaitDelay bounds the wait for its helpers.
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,232 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RecordedCommand is one git invocation captured by a Recorder.
+type RecordedCommand struct {
+	Args     []string // argv after "git", with credentials scrubbed
+	Stdin    []byte   // Input fed to the command (e.g. commit -F -); nil if none
+	Env      []string // Extra environment (e.g. GIT_INDEX_FILE, identity, dates); nil if none
+	Mutating bool     // false for commands known to only read the repo
+}
+
+// Recorder captures every git command run through the Git instances it is
+// attached to, for reproducing agent-induced repo states in bug reports.
+// Credentials in URL arguments are scrubbed before recording. A Recorder is
+// safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	commands []RecordedCommand
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// SetRecorder attaches r to g; pass nil to stop recording.
+func (g *Git) SetRecorder(r *Recorder) {
+	g.recorder = r
+}
+
+// record appends args and the command's stdin and extra environment (nil
+// if none) to the recorder. A nil recorder records nothing.
+func (r *Recorder) record(args []string, stdin []byte, env []string) {
+	if r == nil {
+		return
+	}
+	scrubbed := make([]string, len(args))
+	for i, arg := range args {
+		scrubbed[i] = scrubCredentials(arg)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, RecordedCommand{Args: scrubbed, Stdin: stdin, Env: env, Mutating: isMutating(scrubbed)})
+}
+
+// Commands returns a copy of the recorded commands in execution order.
+func (r *Recorder) Commands() []RecordedCommand {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedCommand(nil), r.commands...)
+}
+
+// RecordedCommands returns the argv of every recorded command in execution order.
+func (r *Recorder) RecordedCommands() [][]string {
+	commands := r.Commands()
+	argvs := make([][]string, len(commands))
+	for i, c := range commands {
+		argvs[i] = c.Args
+	}
+	return argvs
+}
+
+// Replay re-executes the mutating commands recorded by r against target, in
+// order, stopping at the first failure. Read-only commands are skipped.
+// Commands whose URLs had credentials scrubbed will fail to authenticate.
+// Each command gets its recorded environment, except that temporary
+// indexes (GIT_INDEX_FILE) are recreated outside the target, so they
+// can't touch its real index.
+func Replay(r *Recorder, target *Git) error {
+	tmpDir, err := os.MkdirTemp("", "gt-replay-")
+	if err != nil {
+		return fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	indexes := make(map[string]string)
+
+	for i, c := range r.Commands() {
+		if !c.Mutating {
+			continue
+		}
+		var stdin io.Reader
+		if c.Stdin != nil {
+			stdin = bytes.NewReader(c.Stdin)
+		}
+		env := make([]string, len(c.Env))
+		for j, e := range c.Env {
+			if index, ok := strings.CutPrefix(e, "GIT_INDEX_FILE="); ok {
+				if indexes[index] == "" {
+					indexes[index] = filepath.Join(tmpDir, fmt.Sprintf("index-%d", len(indexes)))
+				}
+				e = "GIT_INDEX_FILE=" + indexes[index]
+			}
+			env[j] = e
+		}
+		if _, err := target.runCmd(env, stdin, c.Args...); err != nil {
+			return fmt.Errorf("replaying command %d (git %s): %w", i, strings.Join(c.Args, " "), err)
+		}
+	}
+	return nil
+}
+
+// pinCommitDates returns env with the dates a commit or commit-tree in args
+// would take from the clock set explicitly, so a replay creates the same
+// commit. Dates already set in env or git's environment, or reused by an
+// amend or -C, are left alone. Other commands get env unchanged.
+func pinCommitDates(env, args []string, now time.Time) []string {
+	sub := commandName(args)
+	if sub != "commit" && sub != "commit-tree" {
+		return env
+	}
+	date := fmt.Sprintf("%d %s", now.Unix(), now.Format("-0700"))
+	isSet := func(key string) bool {
+		if _, ok := os.LookupEnv(key); ok {
+			return true
+		}
+		for _, e := range env {
+			if strings.HasPrefix(e, key+"=") {
+				return true
+			}
+		}
+		return false
+	}
+
+	pinned := append([]string(nil), env...)
+	if !isSet("GIT_COMMITTER_DATE") {
+		pinned = append(pinned, "GIT_COMMITTER_DATE="+date)
+	}
+	if !isSet("GIT_AUTHOR_DATE") && (sub == "commit-tree" || !reusesAuthorDate(args)) {
+		pinned = append(pinned, "GIT_AUTHOR_DATE="+date)
+	}
+	return pinned
+}
+
+// reusesAuthorDate reports whether git commit args take the author date
+// from an existing commit or an explicit --date, rather than the clock.
+func reusesAuthorDate(args []string) bool {
+	inCommit := false
+	for _, arg := range args {
+		if !inCommit {
+			inCommit = arg == "commit"
+			continue
+		}
+		if arg == "--" {
+			break
+		}
+		name, _, _ := strings.Cut(arg, "=")
+		switch {
+		case name == "--amend", name == "--reuse-message", name == "--reedit-message", name == "--date":
+			return true
+		case strings.HasPrefix(arg, "-C"), strings.HasPrefix(arg, "-c"):
+			return true
+		}
+	}
+	return false
+}
+
+// readOnlyCommands are git subcommands that never modify the repository.
+var readOnlyCommands = map[string]bool{
+	"blame": true, "cat-file": true, "check-attr": true, "check-ignore": true,
+	"count-objects": true, "describe": true, "diff": true, "diff-tree": true,
+	"for-each-ref": true, "grep": true, "log": true, "ls-files": true,
+	"ls-remote": true, "ls-tree": true, "merge-base": true, "rev-list": true,
+	"rev-parse": true, "show": true, "show-ref": true, "status": true,
+	"symbolic-ref": true, "verify-commit": true, "verify-tag": true, "version": true,
+}
+
+// isMutating reports whether args may modify the repository. Commands that
+// both read and write (branch, config, ...) count as read-only only when
+// given a listing or query flag.
+func isMutating(args []string) bool {
+	sub := commandName(args)
+	if readOnlyCommands[sub] {
+		return false
+	}
+	for _, arg := range args {
+		switch sub {
+		case "branch", "tag":
+			switch arg {
+			case "--list", "-l", "--show-current", "--contains", "--points-at", "--merged", "--no-merged":
+				return false
+			}
+		case "config":
+			switch arg {
+			case "--get", "--get-all", "--get-regexp", "--list", "-l":
+				return false
+			}
+		case "remote":
+			switch arg {
+			case "get-url", "show", "-v":
+				return false
+			}
+		case "submodule":
+			if arg == "status" {
+				return false
+			}
+		case "worktree", "stash":
+			if arg == "list" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scrubCredentials redacts the password, or a bare token used as the
+// username, from URL arguments. Other arguments are returned unchanged.
+func scrubCredentials(arg string) string {
+	if !strings.Contains(arg, "://") || !strings.Contains(arg, "@") {
+		return arg
+	}
+	u, err := url.Parse(arg)
+	if err != nil || u.User == nil {
+		return arg
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), "REDACTED")
+	} else if u.Scheme == "http" || u.Scheme == "https" {
+		u.User = url.User("REDACTED")
+	}
+	return u.String()
+}
@@ -0,0 +1,131 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MinGitVersion is the oldest git that gt supports. Older versions lack
+// features the wrapper relies on, such as `git sparse-checkout` and
+// %(trailers:...) formatting options.
+const MinGitVersion = "2.25.0"
+
+// ErrGitNotFound is returned when the git executable is not on PATH.
+var ErrGitNotFound = errors.New("git not found on PATH")
+
+// EnvGitBinary is the environment variable naming the git executable to
+// run when SetGitBinary hasn't set one, for hosts where git isn't on PATH
+// or several versions are installed.
+const EnvGitBinary = "GT_GIT_BINARY"
+
+// gitBinary is the executable set by SetGitBinary.
+var gitBinary string
+
+// SetGitBinary sets the git executable that every command runs: a path,
+// or a name looked up on PATH. An empty path restores the default,
+// $GT_GIT_BINARY or else "git". Call it at startup, before running any
+// commands; it also resets CheckGit's cached result.
+func SetGitBinary(path string) {
+	gitBinary = path
+	gitCheckOnce = sync.Once{}
+}
+
+// Binary returns the git executable commands run, as set by SetGitBinary
+// or GT_GIT_BINARY, else "git".
+func Binary() string {
+	if gitBinary != "" {
+		return gitBinary
+	}
+	if env := os.Getenv(EnvGitBinary); env != "" {
+		return env
+	}
+	return "git"
+}
+
+// IsGitInstalled returns true if the git executable (see Binary) exists.
+func IsGitInstalled() bool {
+	_, err := exec.LookPath(Binary())
+	return err == nil
+}
+
+// Pre-compiled regex for git version parsing
+var gitVersionRe = regexp.MustCompile(`git version (\d+\.\d+(?:\.\d+)?)`)
+
+// Version returns the installed git version, e.g. "2.39.5". Vendor suffixes
+// such as ".windows.1" or " (Apple Git-143)" are dropped.
+func Version() (string, error) {
+	if !IsGitInstalled() {
+		return "", ErrGitNotFound
+	}
+	out, err := exec.Command(Binary(), "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("running git version: %w", err)
+	}
+	return parseGitVersion(string(out))
+}
+
+// parseGitVersion extracts the version number from `git version` output.
+func parseGitVersion(output string) (string, error) {
+	matches := gitVersionRe.FindStringSubmatch(output)
+	if len(matches) < 2 {
+		return "", fmt.Errorf("could not parse git version from: %s", strings.TrimSpace(output))
+	}
+	return matches[1], nil
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0 or 1.
+// Missing components count as zero ("2.25" == "2.25.0").
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+var (
+	cachedGitCheckResult error
+	gitCheckOnce         sync.Once
+)
+
+// CheckGit verifies that git is installed and at least MinGitVersion,
+// returning an actionable error if not. The check is performed only once
+// per process execution.
+func CheckGit() error {
+	gitCheckOnce.Do(func() {
+		cachedGitCheckResult = checkGitInternal()
+	})
+	return cachedGitCheckResult
+}
+
+func checkGitInternal() error {
+	installed, err := Version()
+	if errors.Is(err, ErrGitNotFound) {
+		return fmt.Errorf("%w\n\nPlease install git %s or newer", err, MinGitVersion)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot verify git version: %w", err)
+	}
+	if compareVersions(installed, MinGitVersion) < 0 {
+		return fmt.Errorf("git version %s is required, but %s is installed\n\nPlease upgrade git", MinGitVersion, installed)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseGitVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"git version 2.39.5\n", "2.39.5"},
+		{"git version 2.39.3 (Apple Git-146)\n", "2.39.3"},
+		{"git version 2.45.1.windows.1\n", "2.45.1"},
+		{"git version 2.30\n", "2.30"},
+	}
+	for _, tt := range tests {
+		got, err := parseGitVersion(tt.output)
+		if err != nil || got != tt.want {
+			t.Errorf("parseGitVersion(%q) = %q, %v; want %q", tt.output, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseGitVersion("not git"); err == nil {
+		t.Error("expected error for unparseable output")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.25.0", "2.25.0", 0},
+		{"2.25", "2.25.0", 0},
+		{"2.24.9", "2.25.0", -1},
+		{"2.39.5", "2.25.0", 1},
+		{"3.0", "2.99.99", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckGit(t *testing.T) {
+	if !IsGitInstalled() {
+		t.Skip("git not installed")
+	}
+	if err := CheckGit(); err != nil {
+		t.Errorf("CheckGit: %v", err)
+	}
+}
+
+func TestGitBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("wrapper is a shell script")
+	}
+	realGit, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not installed")
+	}
+	src := initTestRepo(t)
+
+	// A wrapper that logs each invocation's args before running git
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	wrapper := filepath.Join(dir, "git-wrapper")
+	script := "#!/bin/sh\necho \"$*\" >> '" + calls + "'\nexec '" + realGit + "' \"$@\"\n"
+	if err := os.WriteFile(wrapper, []byte(script), 0755); err != nil {
+		t.Fatalf("write wrapper: %v", err)
+	}
+	defer SetGitBinary("")
+
+	t.Setenv(EnvGitBinary, wrapper)
+	if got := Binary(); got != wrapper {
+		t.Errorf("Binary() with %s = %q, want %q", EnvGitBinary, got, wrapper)
+	}
+	SetGitBinary(filepath.Join(dir, "missing"))
+	if IsGitInstalled() {
+		t.Error("IsGitInstalled() = true for a missing binary")
+	}
+	if _, err := Version(); err == nil {
+		t.Error("Version() with a missing binary: expected error")
+	}
+
+	SetGitBinary(wrapper)
+	dest := filepath.Join(dir, "clone.git")
+	if err := NewGit(dir).CloneWithOptions(src, dest, CloneOptions{Bare: true}); err != nil {
+		t.Fatalf("CloneWithOptions: %v", err)
+	}
+	if _, err := NewGit(src).CurrentBranch(); err != nil {
+		t.Fatalf("CurrentBranch: %v", err)
+	}
+
+	out, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("read calls: %v", err)
+	}
+	// The clone itself, its direct config and fetch calls, and run()
+	for _, want := range []string{"clone --bare", "config remote.origin.fetch", "fetch origin", "rev-parse --abbrev-ref HEAD"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("wrapper calls missing %q:\n%s", want, out)
+		}
+	}
+}
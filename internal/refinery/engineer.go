@@ -326,8 +326,8 @@ func (e *Engineer) doMerge(ctx context.Context, branch, target, sourceIssue stri
 	_, _ = fmt.Fprintf(e.output, "[Engineer] Merging with message: %s\n", mergeMsg)
 	if err := e.git.MergeNoFF(branch, mergeMsg); err != nil {
 		// ZFC: Use git's porcelain output to detect conflicts instead of parsing stderr.
-		// GetConflictingFiles() uses `git diff --diff-filter=U` which is proper.
-		conflicts, conflictErr := e.git.GetConflictingFiles()
+		// ConflictedPaths() uses `git diff --diff-filter=U` which is proper.
+		conflicts, conflictErr := e.git.ConflictedPaths()
 		if conflictErr == nil && len(conflicts) > 0 {
 			_ = e.git.AbortMerge()
 			return ProcessResult{
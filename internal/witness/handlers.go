@@ -795,7 +795,7 @@ func verifyCommitOnMain(workDir, rigName, polecatName string) (bool, error) {
 
 	// Try each remote/<defaultBranch> until we find one where commit is an ancestor
 	for _, remote := range remotes {
-		remoteBranch := remote + "/" + defaultBranch
+		remoteBranch := remote.Name + "/" + defaultBranch
 		isOnRemote, err := g.IsAncestor(commitSHA, remoteBranch)
 		if err == nil && isOnRemote {
 			return true, nil